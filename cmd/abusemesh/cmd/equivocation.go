@@ -0,0 +1,56 @@
+package cmd
+
+//This file contains all commands related to nodes which signed conflicting events
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go/pkg/adminapi"
+	"github.com/abuse-mesh/abuse-mesh-go/pkg/adminapiclient"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	// ./abusemesh get equivocations
+	getCmd.AddCommand(getEquivocationsCommand)
+}
+
+//Show the nodes which signed conflicting events
+var getEquivocationsCommand = &cobra.Command{
+	Use:   "equivocations",
+	Short: "Get the proofs of nodes which signed conflicting events",
+	Long:  "Lists every pair of conflicting events, both events are shown in full with their signatures when the output is json or yaml",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		client := adminapiclient.NewAbuseMeshAdminClient()
+
+		response, err := client.ListEquivocations(&adminapi.ListEquivocationsRequest{})
+		if err != nil {
+			exitWithGrpcError(err)
+		}
+
+		printToStdout(response, func(object interface{}) string {
+			buf := &bytes.Buffer{}
+			tabWriter := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+
+			fmt.Fprintln(tabWriter, "AUTHOR\tENTITY\tTIMESTAMP\tFIRST EVENT\tSECOND EVENT\tDETECTED AT")
+
+			for _, equivocation := range response.GetEquivocations() {
+				fmt.Fprintf(tabWriter, "%s\t%s\t%s\t%s\t%s\t%s\n",
+					formatUUID(equivocation.GetAuthorId(), "-"),
+					equivocation.GetEntity(),
+					time.Unix(equivocation.GetSecondEvent().GetTimestamp(), 0).Format(time.RFC3339),
+					formatUUID(equivocation.GetFirstEvent().GetEventId(), "-"),
+					formatUUID(equivocation.GetSecondEvent().GetEventId(), "-"),
+					formatUnixNano(equivocation.GetDetectedAt()))
+			}
+
+			tabWriter.Flush()
+
+			return buf.String()
+		})
+	},
+}
@@ -0,0 +1,169 @@
+package cmd
+
+//This file contains all commands related to the events the node refused because they are invalid
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/abuse-mesh/abuse-mesh-go/pkg/adminapi"
+	"github.com/abuse-mesh/abuse-mesh-go/pkg/adminapiclient"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+//The value of the 'all' flag of the purge command
+var purgeAllFlag bool
+
+func init() {
+	// ./abusemesh get quarantine [event-id]
+	getCmd.AddCommand(getQuarantineCommand)
+
+	// ./abusemesh quarantine revalidate|purge
+	rootCmd.AddCommand(quarantineCmd)
+	quarantineCmd.AddCommand(revalidateQuarantineCommand, purgeQuarantineCommand)
+
+	purgeQuarantineCommand.Flags().BoolVar(&purgeAllFlag, "all", false, "Remove all events from the quarantine")
+}
+
+//formatUnixNano formats a unix timestamp in nanoseconds, a zero timestamp is shown as '-'
+func formatUnixNano(timestamp int64) string {
+	if timestamp == 0 {
+		return "-"
+	}
+
+	return time.Unix(0, timestamp).Format(time.RFC3339)
+}
+
+//formatUUID formats a optional uuid, a missing uuid is shown as the fallback
+func formatUUID(id *abusemesh.UUID, fallback string) string {
+	if id.GetUuid() == "" {
+		return fallback
+	}
+
+	return id.GetUuid()
+}
+
+//Show the events which were refused by the node
+var getQuarantineCommand = &cobra.Command{
+	Use:   "quarantine [event-id]",
+	Short: "Get the events which were refused because they are invalid",
+	Long:  "Lists all quarantined events, or shows the details of a single event if a event id is given",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client := adminapiclient.NewAbuseMeshAdminClient()
+
+		if len(args) == 1 {
+			quarantined, err := client.GetQuarantinedEvent(&adminapi.GetQuarantinedEventRequest{
+				EventId: &abusemesh.UUID{Uuid: args[0]},
+			})
+			if err != nil {
+				exitWithGrpcError(err)
+			}
+
+			printToStdout(quarantined, func(object interface{}) string {
+				buf := &bytes.Buffer{}
+				tabWriter := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+
+				fmt.Fprintf(tabWriter, "Event ID:\t%s\n", formatUUID(quarantined.GetEventId(), "-"))
+				fmt.Fprintf(tabWriter, "Update type:\t%s\n", quarantined.GetEvent().GetUpdateType())
+				fmt.Fprintf(tabWriter, "Timestamp:\t%s\n", time.Unix(quarantined.GetEvent().GetTimestamp(), 0).Format(time.RFC3339))
+				fmt.Fprintf(tabWriter, "Source:\t%s\n", formatUUID(quarantined.GetSourceId(), "local"))
+				fmt.Fprintf(tabWriter, "Received at:\t%s\n", formatUnixNano(quarantined.GetReceivedAt()))
+				fmt.Fprintf(tabWriter, "Quarantined at:\t%s\n", formatUnixNano(quarantined.GetQuarantinedAt()))
+				fmt.Fprintf(tabWriter, "Rejections:\t%d\n", quarantined.GetRejections())
+				fmt.Fprintf(tabWriter, "Reason:\t%s\n", quarantined.GetReason())
+
+				tabWriter.Flush()
+
+				return buf.String()
+			})
+
+			return
+		}
+
+		response, err := client.ListQuarantine(&adminapi.ListQuarantineRequest{})
+		if err != nil {
+			exitWithGrpcError(err)
+		}
+
+		printToStdout(response, func(object interface{}) string {
+			buf := &bytes.Buffer{}
+			tabWriter := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+
+			fmt.Fprintln(tabWriter, "EVENT ID\tSOURCE\tQUARANTINED AT\tREJECTIONS\tREASON")
+
+			for _, quarantined := range response.GetEvents() {
+				fmt.Fprintf(tabWriter, "%s\t%s\t%s\t%d\t%s\n",
+					formatUUID(quarantined.GetEventId(), "-"),
+					formatUUID(quarantined.GetSourceId(), "local"),
+					formatUnixNano(quarantined.GetQuarantinedAt()),
+					quarantined.GetRejections(),
+					quarantined.GetReason())
+			}
+
+			tabWriter.Flush()
+
+			return buf.String()
+		})
+	},
+}
+
+//Quarantine subcommand which has other children
+// so we have semantic commands like: 'abusemesh quarantine revalidate' and 'abusemesh quarantine purge'
+var quarantineCmd = &cobra.Command{
+	Use:   "quarantine",
+	Short: "Manage the events which were refused because they are invalid",
+}
+
+//Validate a quarantined event again
+var revalidateQuarantineCommand = &cobra.Command{
+	Use:   "revalidate <event-id>",
+	Short: "Validate a quarantined event again",
+	Long:  "Validates a quarantined event again, for example after the key of its signer has arrived. A event which is valid now is accepted into the event stream",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client := adminapiclient.NewAbuseMeshAdminClient()
+
+		response, err := client.RevalidateQuarantinedEvent(&adminapi.RevalidateQuarantinedEventRequest{
+			EventId: &abusemesh.UUID{Uuid: args[0]},
+		})
+		if err != nil {
+			exitWithGrpcError(err)
+		}
+
+		if !response.GetAccepted() {
+			exitWithError(errors.Errorf("Event is still invalid: %s", response.GetReason()))
+		}
+
+		fmt.Println("Event is valid and has been written to the event stream")
+	},
+}
+
+//Remove events from the quarantine
+var purgeQuarantineCommand = &cobra.Command{
+	Use:   "purge [event-id...]",
+	Short: "Remove events from the quarantine",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 && !purgeAllFlag {
+			exitWithError(errors.New("Specify the events to remove or use --all to remove all events"))
+		}
+
+		request := &adminapi.PurgeQuarantineRequest{}
+		for _, eventID := range args {
+			request.EventIds = append(request.EventIds, &abusemesh.UUID{Uuid: eventID})
+		}
+
+		client := adminapiclient.NewAbuseMeshAdminClient()
+
+		response, err := client.PurgeQuarantine(request)
+		if err != nil {
+			exitWithGrpcError(err)
+		}
+
+		fmt.Printf("Removed %d events from the quarantine\n", response.GetPurged())
+	},
+}
@@ -0,0 +1,127 @@
+package cmd
+
+//This file contains all commands related to the reports known to the node
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go/pkg/adminapi"
+	"github.com/abuse-mesh/abuse-mesh-go/pkg/adminapiclient"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+//The value of the 'match' flag of the get reports command
+var prefixMatchFlag string
+
+//The values of the flags of the create report command
+var (
+	reportCategoryFlag    string
+	reportDescriptionFlag string
+	reportTTLFlag         time.Duration
+)
+
+//prefixMatchFlags maps the values of the 'match' flag to the prefix match of the admin API
+var prefixMatchFlags = map[string]adminapi.PrefixMatch{
+	"exact":      adminapi.PrefixMatch_PrefixMatchExact,
+	"covering":   adminapi.PrefixMatch_PrefixMatchCovering,
+	"covered-by": adminapi.PrefixMatch_PrefixMatchCoveredBy,
+}
+
+func init() {
+	// ./abusemesh get reports <prefix>
+	getCmd.AddCommand(getReportsCommand)
+
+	getReportsCommand.Flags().StringVar(&prefixMatchFlag, "match", "exact",
+		"Which reports to return, one of: exact, covering (less specific prefixes), covered-by (more specific prefixes)")
+
+	// ./abusemesh report create <prefix>
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(createReportCommand)
+
+	createReportCommand.Flags().StringVar(&reportCategoryFlag, "category", "", "The category of the abuse, for example: spam")
+	createReportCommand.Flags().StringVar(&reportDescriptionFlag, "description", "", "A description of the abuse")
+	createReportCommand.Flags().DurationVar(&reportTTLFlag, "ttl", 0, "How long the report stays listed, 0 leaves it to the ttl policy of every node")
+}
+
+//Show the reports about a IP address or network
+var getReportsCommand = &cobra.Command{
+	Use:   "reports <ip-address|network>",
+	Short: "Get the reports about a IP address or network",
+	Long: "Lists the reports about a IP address or a network in CIDR notation. " +
+		"With --match covering the reports about networks containing it are listed as well, with --match covered-by the reports about addresses and networks within it",
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		match, found := prefixMatchFlags[prefixMatchFlag]
+		if !found {
+			exitWithError(errors.Errorf("Unknown match '%s', expected one of: exact, covering, covered-by", prefixMatchFlag))
+		}
+
+		client := adminapiclient.NewAbuseMeshAdminClient()
+
+		response, err := client.GetReportsByPrefix(&adminapi.GetReportsByPrefixRequest{
+			Prefix: args[0],
+			Match:  match,
+		})
+		if err != nil {
+			exitWithGrpcError(err)
+		}
+
+		printToStdout(response, func(object interface{}) string {
+			buf := &bytes.Buffer{}
+			tabWriter := tabwriter.NewWriter(buf, 0, 0, 3, ' ', 0)
+
+			fmt.Fprintln(tabWriter, "REPORT ID\tREPORTER\tPREFIX\tCATEGORY\tDESCRIPTION")
+
+			for _, report := range response.GetReports() {
+				fmt.Fprintf(tabWriter, "%s\t%s\t%s\t%s\t%s\n",
+					formatUUID(report.GetUuid(), "-"),
+					formatUUID(report.GetReporter(), "-"),
+					report.GetIpAddress().GetAddress(),
+					report.GetCategory(),
+					report.GetDescription())
+			}
+
+			tabWriter.Flush()
+
+			return buf.String()
+		})
+	},
+}
+
+//Report subcommand which has other children
+// so we have semantic commands like: 'abusemesh report create'
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Manage the reports of this node",
+}
+
+//Report a IP address or network
+var createReportCommand = &cobra.Command{
+	Use:   "create <ip-address|network>",
+	Short: "Report a IP address or network",
+	Long:  "Signs a new report with the key of the node and submits it to the event stream, from where it is sent to all neighbors",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if reportTTLFlag < 0 {
+			exitWithError(errors.New("The ttl can't be negative"))
+		}
+
+		client := adminapiclient.NewAbuseMeshAdminClient()
+
+		response, err := client.CreateReport(&adminapi.CreateReportRequest{
+			Prefix:      args[0],
+			Category:    reportCategoryFlag,
+			Description: reportDescriptionFlag,
+			Ttl:         int64(reportTTLFlag / time.Second),
+		})
+		if err != nil {
+			exitWithGrpcError(err)
+		}
+
+		fmt.Printf("Report '%s' submitted in event '%s'\n", response.GetReport().GetUuid().GetUuid(), response.GetEventId().GetUuid())
+	},
+}
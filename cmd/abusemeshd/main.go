@@ -6,17 +6,22 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/abuse-mesh/abuse-mesh-go/internal/config"
 	"github.com/abuse-mesh/abuse-mesh-go/internal/entities"
 	"github.com/abuse-mesh/abuse-mesh-go/internal/pgp"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/storage/local"
 	"github.com/abuse-mesh/abuse-mesh-go/pkg/adminapiserver"
 	"github.com/abuse-mesh/abuse-mesh-go/pkg/server"
+	"github.com/google/uuid"
 	"github.com/jessevdk/go-flags"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"golang.org/x/crypto/ssh/terminal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	validator "gopkg.in/go-playground/validator.v9"
 )
 
@@ -104,16 +109,152 @@ func main() {
 		log.Fatalf("Unknown PGP provider '%s'", config.Node.PGPProvider)
 	}
 
-	tableSet := &entities.TableSet{
-		Channel: make(chan entities.TableRequest, 100), //TODO make buffer configurable
+	//Announced nodes are dialed with the same transport security as we use ourselves
+	var verifierDialOption grpc.DialOption
+	if config.Node.Insecure {
+		verifierDialOption = grpc.WithInsecure()
+	} else {
+		//Nodes use self signed certificates which can't be verified, the identity of a node is confirmed
+		//by comparing the PGP fingerprint it reports with the one in the announcement
+		verifierDialOption = grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+			InsecureSkipVerify: true,
+		}))
+	}
+
+	tableSet := entities.NewTableSet(100) //TODO make buffer configurable
+
+	tableSet.NodeVerifier = entities.NewCallbackNodeVerifier(entities.NodeVerifierPolicy{
+		Port:      config.NodeVerification.Port,
+		Timeout:   config.NodeVerification.Timeout,
+		CacheTTL:  config.NodeVerification.CacheTTL,
+		CacheSize: config.NodeVerification.CacheSize,
+	}, verifierDialOption)
+
+	reportTTL := entities.ReportTTLPolicy{
+		Default:    config.Reports.DefaultTTL,
+		Categories: config.Reports.CategoryTTL,
+	}
+	tableSet.ReportTTL = reportTTL
+
+	writeBufferSize := config.EventStream.WriteBufferSize
+	if writeBufferSize == 0 {
+		writeBufferSize = 1000
 	}
 
-	//TODO make event stream type configurable
-	eventStream := entities.NewInMemoryEventStream(tableSet, 1000) //TODO make event stream buffer configurable
+	compaction := entities.CompactionPolicy{
+		Interval:      config.EventStream.Compaction.Interval,
+		Retain:        config.EventStream.Compaction.Retain,
+		RetainExpired: config.EventStream.Compaction.RetainExpired,
+		ReportTTL:     reportTTL,
+	}
+
+	subscriberPolicy := entities.SubscriberPolicy{
+		QueueSize:         config.EventStream.SubscriberQueueSize,
+		Overflow:          entities.OverflowResync,
+		ValidationTimeout: config.EventStream.ValidationTimeout,
+	}
+	if config.EventStream.OverflowPolicy == "disconnect" {
+		subscriberPolicy.Overflow = entities.OverflowDisconnect
+	}
+
+	dedupPolicy := entities.DedupPolicy{
+		ExpectedEvents:    config.EventStream.Dedup.ExpectedEvents,
+		FalsePositiveRate: config.EventStream.Dedup.FalsePositiveRate,
+		MemoryIndexSize:   config.EventStream.Dedup.MemoryIndexSize,
+	}
+
+	quarantine := entities.NewQuarantine(config.EventStream.QuarantineSize)
+
+	equivocationHorizon := config.EventStream.EquivocationHorizon
+	if equivocationHorizon == 0 {
+		equivocationHorizon = 24 * time.Hour
+	}
+
+	var eventStream entities.EventStream
+
+	switch config.EventStream.Type {
+	case "", "memory":
+		//Without persisted events the ids of known events are kept in memory as well
+		deduplicator, err := entities.NewEventDeduplicator(dedupPolicy, nil)
+		if err != nil {
+			log.WithError(err).Fatal("Error while creating event deduplicator")
+		}
+
+		equivocations, err := entities.NewEquivocationDetector(equivocationHorizon, nil)
+		if err != nil {
+			log.WithError(err).Fatal("Error while creating equivocation detector")
+		}
+
+		eventStream = entities.NewInMemoryEventStream(
+			tableSet,
+			writeBufferSize,
+			compaction,
+			subscriberPolicy,
+			deduplicator,
+			entities.NewProvenanceTracker(nil),
+			quarantine,
+			equivocations,
+		)
+	case "log":
+		if config.EventStream.Log.Dir == "" {
+			log.Fatal("The 'log' event stream requires a data directory")
+		}
+
+		//The events, the index of known event ids, the provenance of the events and the equivocation proofs
+		//are stored in the same data directory, so the index never outlives the events it belongs to
+		eventStorage, err := local.NewStorageBackend(config.EventStream.Log.Dir, config.EventStream.Log.SyncWrites)
+		if err != nil {
+			log.WithError(err).Fatal("Error while opening event log")
+		}
+
+		//Overwritten and deleted values are reclaimed in the background
+		storageCompactionInterval := config.EventStream.Log.StorageCompactionInterval
+		if storageCompactionInterval == 0 {
+			storageCompactionInterval = 10 * time.Minute
+		}
+
+		storageCompactionGarbage := config.EventStream.Log.StorageCompactionGarbage
+		if storageCompactionGarbage == 0 {
+			storageCompactionGarbage = 16 * 1024 * 1024
+		}
+
+		go eventStorage.RunCompaction(context.Background(), storageCompactionInterval, storageCompactionGarbage)
+
+		deduplicator, err := entities.NewEventDeduplicator(dedupPolicy, eventStorage)
+		if err != nil {
+			log.WithError(err).Fatal("Error while creating event deduplicator")
+		}
+
+		//The authors of stored equivocation proofs stay flagged after a restart
+		equivocations, err := entities.NewEquivocationDetector(equivocationHorizon, eventStorage)
+		if err != nil {
+			log.WithError(err).Fatal("Error while loading equivocation proofs")
+		}
+
+		eventStream, err = entities.NewLogEventStream(
+			tableSet,
+			writeBufferSize,
+			compaction,
+			subscriberPolicy,
+			deduplicator,
+			entities.NewProvenanceTracker(eventStorage),
+			quarantine,
+			equivocations,
+			eventStorage,
+		)
+		if err != nil {
+			log.WithError(err).Fatal("Error while opening event log")
+		}
+	default:
+		log.Fatalf("Unknown event stream type '%s'", config.EventStream.Type)
+	}
 
 	//Attach the tableSet as observer to the event stream
 	eventStream.Attach(tableSet)
 
+	//Events made on this node, like reports created with the admin API, are signed with our key and written to the event stream
+	author := entities.NewEventAuthor(pgpProvider, eventStream)
+
 	errChan := make(chan error)
 
 	go func() {
@@ -121,6 +262,8 @@ func main() {
 		errChan <- tableSet.Run(context.Background())
 	}()
 
+	setNodeTrust(tableSet, config.Reports.NodeTrust)
+
 	go func() {
 		log.Info("Starting EventStream.Run()")
 		errChan <- eventStream.Run(context.Background())
@@ -146,7 +289,7 @@ func main() {
 	}()
 
 	go func() {
-		abuseMeshAdminAPI := adminapiserver.NewAbuseMeshAdminAPI(config, pgpProvider, tableSet, eventStream)
+		abuseMeshAdminAPI := adminapiserver.NewAbuseMeshAdminAPI(config, pgpProvider, tableSet, eventStream, author)
 
 		adminAPIAddr := fmt.Sprintf("%s:%d", config.AdminInterface.ListenIP, config.AdminInterface.ListenPort)
 		adminAPIListener, err := net.Listen("tcp", adminAPIAddr)
@@ -170,6 +313,28 @@ func main() {
 	log.WithError(err).Error("AbuseMesh protocol server has stopped")
 }
 
+//setNodeTrust sets the configured trust in other nodes, which is used to score the credibility of reports
+func setNodeTrust(tableSet *entities.TableSet, nodeTrust map[string]float64) {
+	for node, trust := range nodeTrust {
+		nodeID, err := uuid.Parse(node)
+		if err != nil {
+			log.WithError(err).Fatalf("Invalid node UUID '%s' in node trust", node)
+		}
+
+		errChan := make(chan error, 1)
+		tableSet.Channel <- &entities.SetNodeTrustRequest{
+			NodeID:    nodeID,
+			Trust:     trust,
+			ErrorChan: errChan,
+		}
+
+		err = <-errChan
+		if err != nil {
+			log.WithError(err).Fatalf("Error while setting trust in node '%s'", node)
+		}
+	}
+}
+
 func wrapListener(innerListener net.Listener, config *config.AbuseMeshConfig) (net.Listener, error) {
 	var listener net.Listener
 
@@ -1,13 +1,18 @@
 package config
 
 import (
+	"time"
+
 	"github.com/spf13/viper"
 	"gopkg.in/go-playground/validator.v9"
 )
 
 type AbuseMeshConfig struct {
-	Node           NodeConfig           `mapstructure:"node" json:"node"`
-	AdminInterface AdminInterfaceConfig `mapstructure:"admin-interface" json:"admin-interface"`
+	Node             NodeConfig             `mapstructure:"node" json:"node"`
+	AdminInterface   AdminInterfaceConfig   `mapstructure:"admin-interface" json:"admin-interface"`
+	EventStream      EventStreamConfig      `mapstructure:"event-stream" json:"event-stream"`
+	Reports          ReportsConfig          `mapstructure:"reports" json:"reports"`
+	NodeVerification NodeVerificationConfig `mapstructure:"node-verification" json:"node-verification"`
 }
 
 func GetConfig(v *viper.Viper) (*AbuseMeshConfig, error) {
@@ -36,3 +41,99 @@ type AdminInterfaceConfig struct {
 	ListenIP   string `mapstructure:"listen-ip" json:"listen-ip" yaml:"listen-ip" validate:"required,ip"`
 	ListenPort int    `mapstructure:"listen-port" json:"listen-port,omitempty" yaml:"listen-port,omitempty" validate:"min=1,max=65535"`
 }
+
+//EventStreamConfig is the configuration of the event stream which holds all events known to the node
+type EventStreamConfig struct {
+	//Type is the event stream implementation, 'memory' keeps events in memory only, 'log' also persists them to disk
+	Type string `mapstructure:"type" json:"type" validate:"omitempty,oneof=memory log"`
+
+	//WriteBufferSize is the amount of events which can be queued before they are processed
+	WriteBufferSize int `mapstructure:"write-buffer-size" json:"write-buffer-size" validate:"min=0"`
+
+	//SubscriberQueueSize is the amount of events which can be queued for a single subscriber of the event stream
+	SubscriberQueueSize int `mapstructure:"subscriber-queue-size" json:"subscriber-queue-size" validate:"min=0"`
+
+	//OverflowPolicy determines what happens to a subscriber which can't keep up, 'resync' or 'disconnect'
+	OverflowPolicy string `mapstructure:"overflow-policy" json:"overflow-policy" validate:"omitempty,oneof=resync disconnect"`
+
+	//ValidationTimeout is how long a event waits for the tables to receive the events before it, before it is validated
+	ValidationTimeout time.Duration `mapstructure:"validation-timeout" json:"validation-timeout" validate:"min=0"`
+
+	//QuarantineSize is the amount of refused events which are kept for inspection
+	QuarantineSize int `mapstructure:"quarantine-size" json:"quarantine-size" validate:"min=0"`
+
+	//EquivocationHorizon is how long events are kept to detect a conflicting copy signed by the same author
+	EquivocationHorizon time.Duration `mapstructure:"equivocation-horizon" json:"equivocation-horizon" validate:"min=0"`
+
+	Log LogEventStreamConfig `mapstructure:"log" json:"log"`
+
+	Compaction CompactionConfig `mapstructure:"compaction" json:"compaction"`
+
+	Dedup DedupConfig `mapstructure:"dedup" json:"dedup"`
+}
+
+//DedupConfig is the configuration of the filters used to detect duplicate events
+type DedupConfig struct {
+	//ExpectedEvents is the amount of events the first filter is sized for, every next filter is sized for twice as many
+	ExpectedEvents uint `mapstructure:"expected-events" json:"expected-events"`
+
+	//FalsePositiveRate is the rate at which the filters together wrongly report a new event as known
+	FalsePositiveRate float64 `mapstructure:"false-positive-rate" json:"false-positive-rate" validate:"min=0,max=1"`
+
+	//MemoryIndexSize is the maximum amount of ids in the exact index of the 'memory' event stream
+	MemoryIndexSize int `mapstructure:"memory-index-size" json:"memory-index-size" validate:"min=0"`
+}
+
+//CompactionConfig is the configuration of the snapshots and compaction of the event stream
+type CompactionConfig struct {
+	//Interval is the time between snapshots, 0 disables compaction
+	Interval time.Duration `mapstructure:"interval" json:"interval" validate:"min=0"`
+
+	//Retain is the amount of most recent events which are never compacted
+	Retain uint64 `mapstructure:"retain" json:"retain"`
+
+	//RetainExpired is how long the events of expired and delisted reports are kept, 0 keeps them forever
+	RetainExpired time.Duration `mapstructure:"retain-expired" json:"retain-expired" validate:"min=0"`
+}
+
+//LogEventStreamConfig is the configuration of the 'log' event stream
+type LogEventStreamConfig struct {
+	//Dir is the data directory in which the events and the index of known event ids are stored
+	Dir string `mapstructure:"dir" json:"dir"`
+
+	//SyncWrites forces every event to be synced to disk before it is processed
+	SyncWrites bool `mapstructure:"sync-writes" json:"sync-writes"`
+
+	//StorageCompactionInterval is the time between checks whether the data directory should be compacted
+	StorageCompactionInterval time.Duration `mapstructure:"storage-compaction-interval" json:"storage-compaction-interval" validate:"min=0"`
+
+	//StorageCompactionGarbage is the amount of bytes of overwritten and deleted values after which the data directory is compacted
+	StorageCompactionGarbage int64 `mapstructure:"storage-compaction-garbage" json:"storage-compaction-garbage" validate:"min=0"`
+}
+
+//ReportsConfig is the configuration of the reports known to the node
+type ReportsConfig struct {
+	//DefaultTTL is the time reports stay listed if neither the reporter nor their category sets a ttl, 0 means forever
+	DefaultTTL time.Duration `mapstructure:"default-ttl" json:"default-ttl" validate:"min=0"`
+
+	//CategoryTTL is the time reports of a category stay listed if the reporter doesn't set a ttl
+	CategoryTTL map[string]time.Duration `mapstructure:"category-ttl" json:"category-ttl" validate:"dive,min=0"`
+
+	//NodeTrust is the trust, between 0 and 1, in the reports and confirmations of specific nodes by UUID
+	NodeTrust map[string]float64 `mapstructure:"node-trust" json:"node-trust" validate:"dive,min=0,max=1"`
+}
+
+//NodeVerificationConfig is the configuration of how the claims of announced nodes are confirmed
+type NodeVerificationConfig struct {
+	//Port is the TCP port on which announced nodes are contacted
+	Port int `mapstructure:"port" json:"port" validate:"min=0,max=65535"`
+
+	//Timeout is how long contacting a announced node may take
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout" validate:"min=0"`
+
+	//CacheTTL is how long the result of a verification is reused for announcements with the same claims
+	CacheTTL time.Duration `mapstructure:"cache-ttl" json:"cache-ttl" validate:"min=0"`
+
+	//CacheSize is the maximum amount of verification results which are kept
+	CacheSize int `mapstructure:"cache-size" json:"cache-size" validate:"min=0"`
+}
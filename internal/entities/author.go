@@ -0,0 +1,131 @@
+package entities
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/pgp"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+//ErrNoPrivateKey signals that the PGP entity of this node has no private key which can be used to sign events
+var ErrNoPrivateKey = errors.New("No decrypted private key available to sign the event")
+
+//A EventAuthor creates events on behalf of this node
+//Every event gets a new event id and the current time as timestamp and is signed with the key of this node
+type EventAuthor struct {
+	pgpProvider pgp.PGPProvider
+	eventStream EventStream
+
+	//The timestamp of the last event, two events of this node never get the same timestamp
+	//since other nodes would see them as conflicting versions of the entity
+	lastTimestamp int64
+	lock          sync.Mutex
+}
+
+//NewEventAuthor creates a author which signs events with the key of the PGP provider and submits them to the event stream
+func NewEventAuthor(pgpProvider pgp.PGPProvider, eventStream EventStream) *EventAuthor {
+	return &EventAuthor{
+		pgpProvider: pgpProvider,
+		eventStream: eventStream,
+	}
+}
+
+//timestamp returns the current time as unix timestamp, or the timestamp after the last event if that is later
+func (author *EventAuthor) timestamp() int64 {
+	author.lock.Lock()
+	defer author.lock.Unlock()
+
+	timestamp := time.Now().Unix()
+	if timestamp <= author.lastTimestamp {
+		timestamp = author.lastTimestamp + 1
+	}
+	author.lastTimestamp = timestamp
+
+	return timestamp
+}
+
+//newEvent creates a unsigned event without entity
+func (author *EventAuthor) newEvent(updateType abusemesh.TableEventType) *GenericEvent {
+	return &GenericEvent{
+		TableEvent: abusemesh.TableEvent{
+			EventId: &abusemesh.UUID{
+				Uuid: uuid.New().String(),
+			},
+			UpdateType: updateType,
+			Timestamp:  author.timestamp(),
+		},
+	}
+}
+
+//signed signs the event, the entity of the event must be set before it is signed
+func (author *EventAuthor) signed(event *GenericEvent) (*GenericEvent, error) {
+	err := event.sign(author.pgpProvider.GetEntity())
+	if err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+//NodeEvent creates a signed event about a node
+func (author *EventAuthor) NodeEvent(updateType abusemesh.TableEventType, node *abusemesh.Node) (*GenericEvent, error) {
+	event := author.newEvent(updateType)
+	event.TableEntity = &abusemesh.TableEvent_Node{Node: node}
+
+	return author.signed(event)
+}
+
+//ReportEvent creates a signed event about a report
+func (author *EventAuthor) ReportEvent(updateType abusemesh.TableEventType, report *abusemesh.Report) (*GenericEvent, error) {
+	event := author.newEvent(updateType)
+	event.TableEntity = &abusemesh.TableEvent_Report{Report: report}
+
+	return author.signed(event)
+}
+
+//ReportConfirmationEvent creates a signed event about a report confirmation
+func (author *EventAuthor) ReportConfirmationEvent(updateType abusemesh.TableEventType, confirmation *abusemesh.ReportConfirmation) (*GenericEvent, error) {
+	event := author.newEvent(updateType)
+	event.TableEntity = &abusemesh.TableEvent_ReportConfirmation{ReportConfirmation: confirmation}
+
+	return author.signed(event)
+}
+
+//DelistRequestEvent creates a signed event about a delist request
+func (author *EventAuthor) DelistRequestEvent(updateType abusemesh.TableEventType, request *abusemesh.DelistRequest) (*GenericEvent, error) {
+	event := author.newEvent(updateType)
+	event.TableEntity = &abusemesh.TableEvent_DelistRequests{DelistRequests: request}
+
+	return author.signed(event)
+}
+
+//DelistAcceptanceEvent creates a signed event about a delist acceptance
+func (author *EventAuthor) DelistAcceptanceEvent(updateType abusemesh.TableEventType, acceptance *abusemesh.DelistAcceptance) (*GenericEvent, error) {
+	event := author.newEvent(updateType)
+	event.TableEntity = &abusemesh.TableEvent_DelistAcceptance{DelistAcceptance: acceptance}
+
+	return author.signed(event)
+}
+
+//NeighborEvent creates a signed event about a neighborship
+func (author *EventAuthor) NeighborEvent(updateType abusemesh.TableEventType, neighbor *abusemesh.Neighbor) (*GenericEvent, error) {
+	event := author.newEvent(updateType)
+	event.TableEntity = &abusemesh.TableEvent_Neighbor{Neighbor: neighbor}
+
+	return author.signed(event)
+}
+
+//Submit writes the event to the event stream, which validates it like any other event
+//Blocks until the event is queued or the context is canceled
+func (author *EventAuthor) Submit(ctx context.Context, event Event) error {
+	select {
+	case author.eventStream.GetWriteChannel() <- event:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "Event not submitted")
+	}
+}
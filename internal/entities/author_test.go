@@ -0,0 +1,150 @@
+package entities
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/pgp"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+//newKeyringPGPProvider writes the private key of the test node to a keyring and opens it with the file PGP provider
+func newKeyringPGPProvider(t *testing.T, dir string, name string) pgp.PGPProvider {
+	key := testKey(t, name)
+
+	keyringFile := filepath.Join(dir, name+".pgp")
+	keyring, err := os.Create(keyringFile)
+	if err != nil {
+		t.Fatalf("Error while creating keyring: %s", err)
+	}
+	defer keyring.Close()
+
+	err = key.SerializePrivate(keyring, nil)
+	if err != nil {
+		t.Fatalf("Error while writing keyring: %s", err)
+	}
+
+	provider, err := pgp.NewFilePGPProvider(keyringFile, fmt.Sprintf("%016X", key.PrimaryKey.KeyId), nil)
+	if err != nil {
+		t.Fatalf("Error while opening keyring: %s", err)
+	}
+
+	return provider
+}
+
+//A event signed by the author with the key from a keyring is verified against the key of the announced node
+func Test_EventAuthor_RoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyring")
+	if err != nil {
+		t.Fatalf("Error while creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	node := newTestNode(t, "author")
+	node.author = NewEventAuthor(newKeyringPGPProvider(t, dir, "author"), nil)
+
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	err = applyEvent(tableSet, node.announce(t))
+	if err != nil {
+		t.Fatalf("Error while announcing node: %s", err)
+	}
+
+	event := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, uuid.New(), "198.51.100.1")
+
+	err = event.verifySignedBy(tableSet, event.GetReport().GetReporter())
+	if err != nil {
+		t.Fatalf("Expected the signature to be valid, got: %s", err)
+	}
+
+	err = applyEvent(tableSet, event)
+	if err != nil {
+		t.Fatalf("Expected the event to be valid, got: %s", err)
+	}
+
+	//A change after signing is detected
+	tampered := &GenericEvent{TableEvent: *proto.Clone(&event.TableEvent).(*abusemesh.TableEvent)}
+	tampered.GetReport().Description = "changed after signing"
+
+	err = tampered.verifySignedBy(tableSet, tampered.GetReport().GetReporter())
+	if errors.Cause(err) != ErrPayloadTampered {
+		t.Errorf("Expected %s, got: %v", ErrPayloadTampered, err)
+	}
+
+	//A event about the node signed by a other key is refused
+	other := newTestNode(t, "other")
+	err = applyEvent(tableSet, other.announce(t))
+	if err != nil {
+		t.Fatalf("Error while announcing node: %s", err)
+	}
+
+	forged, err := other.author.ReportEvent(abusemesh.TableEventType_TABLE_UPDATE_NEW, event.GetReport())
+	if err != nil {
+		t.Fatalf("Error while signing report event: %s", err)
+	}
+
+	err = forged.verifySignedBy(tableSet, forged.GetReport().GetReporter())
+	if errors.Cause(err) != ErrSignatureInvalid {
+		t.Errorf("Expected %s, got: %v", ErrSignatureInvalid, err)
+	}
+}
+
+//Submitted events are validated and accepted by the event stream like events from neighbors
+func Test_EventAuthor_Submit(t *testing.T) {
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	stream := newTestEventStream(t, tableSet)
+	stream.Attach(tableSet)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go stream.Run(ctx)
+
+	node := newTestNode(t, "node-a")
+	author := NewEventAuthor(testPGPProvider{node.key}, stream)
+
+	announce, err := author.NodeEvent(abusemesh.TableEventType_TABLE_UPDATE_NEW, node.message(t))
+	if err != nil {
+		t.Fatalf("Error while signing node event: %s", err)
+	}
+
+	reportID := uuid.New()
+	report, err := author.ReportEvent(abusemesh.TableEventType_TABLE_UPDATE_NEW, &abusemesh.Report{
+		Uuid:      &abusemesh.UUID{Uuid: reportID.String()},
+		Reporter:  &abusemesh.UUID{Uuid: node.id.String()},
+		IpAddress: &abusemesh.IPAddress{Address: "198.51.100.1"},
+		Category:  "spam",
+	})
+	if err != nil {
+		t.Fatalf("Error while signing report event: %s", err)
+	}
+
+	for _, event := range []Event{announce, report} {
+		err := author.Submit(ctx, event)
+		if err != nil {
+			t.Fatalf("Error while submitting event: %s", err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for tableSet.getReport(reportID) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("Report was not applied, %d events are quarantined", len(stream.GetQuarantinedEvents()))
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if announce.GetTimestamp() >= report.GetTimestamp() {
+		t.Errorf("Expected every event to get a later timestamp, got %d and %d", announce.GetTimestamp(), report.GetTimestamp())
+	}
+}
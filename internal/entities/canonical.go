@@ -0,0 +1,401 @@
+package entities
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+//CanonicalEncode returns the canonical encoding of a protobuf message, which is used to sign and verify events
+//The default protobuf encoding doesn't guarantee a field or map order, the canonical encoding is a valid protobuf
+//wire encoding which every implementation of the protocol can reproduce byte for byte.
+//Fields are written in ascending order of their field number, a set oneof field is written like any other field.
+//Scalar fields with the default value are omitted, except when they are the set field of a oneof.
+//Message fields are written if they are set, a empty message is written with length 0.
+//Repeated fields are written in their order, repeated numeric fields are packed if the schema declares them packed.
+//Map entries are written in ascending order of their key, every entry contains both the key and the value.
+//Unknown fields are never written. Varints use the shortest encoding, negative int32 and enum values are sign extended to 64 bits
+func CanonicalEncode(message proto.Message) ([]byte, error) {
+	value := reflect.ValueOf(message)
+	if value.Kind() != reflect.Ptr || value.Type().Elem().Kind() != reflect.Struct {
+		return nil, errors.Errorf("Can't encode '%T', not a protobuf message", message)
+	}
+
+	if value.IsNil() {
+		return nil, nil
+	}
+
+	return appendCanonicalMessage(nil, value.Elem())
+}
+
+//The wire types of the protobuf encoding
+const (
+	wireTypeVarint  = 0
+	wireTypeFixed64 = 1
+	wireTypeBytes   = 2
+	wireTypeFixed32 = 5
+)
+
+//protobufTag holds the parts of the struct tag of a generated field which determine its encoding
+type protobufTag struct {
+	encoding string
+	number   int
+	packed   bool
+}
+
+func parseProtobufTag(tag string) (protobufTag, error) {
+	parts := strings.Split(tag, ",")
+	if len(parts) < 2 {
+		return protobufTag{}, errors.Errorf("Malformed protobuf tag '%s'", tag)
+	}
+
+	number, err := strconv.Atoi(parts[1])
+	if err != nil || number <= 0 {
+		return protobufTag{}, errors.Errorf("Malformed field number in protobuf tag '%s'", tag)
+	}
+
+	parsed := protobufTag{
+		encoding: parts[0],
+		number:   number,
+	}
+
+	for _, part := range parts[2:] {
+		if part == "packed" {
+			parsed.packed = true
+		}
+	}
+
+	return parsed, nil
+}
+
+//wireType returns the wire type of the encoding of the field
+func (tag protobufTag) wireType() (int, error) {
+	switch tag.encoding {
+	case "varint", "zigzag32", "zigzag64":
+		return wireTypeVarint, nil
+	case "fixed64":
+		return wireTypeFixed64, nil
+	case "bytes":
+		return wireTypeBytes, nil
+	case "fixed32":
+		return wireTypeFixed32, nil
+	default:
+		return 0, errors.Errorf("Unsupported protobuf encoding '%s'", tag.encoding)
+	}
+}
+
+//A canonicalField is a field of a message which is written in the canonical encoding
+type canonicalField struct {
+	tag         protobufTag
+	structField reflect.StructField
+	value       reflect.Value
+
+	//True if the field is written even if it has the default value
+	always bool
+}
+
+//canonicalFields returns the fields of the message in the order in which they are written
+func canonicalFields(message reflect.Value) ([]canonicalField, error) {
+	messageType := message.Type()
+
+	var fields []canonicalField
+	for i := 0; i < messageType.NumField(); i++ {
+		structField := messageType.Field(i)
+
+		//Unknown fields and other bookkeeping of the generated code are not part of the encoding
+		if strings.HasPrefix(structField.Name, "XXX_") {
+			continue
+		}
+
+		if _, isOneof := structField.Tag.Lookup("protobuf_oneof"); isOneof {
+			oneof := message.Field(i)
+			if oneof.IsNil() {
+				continue
+			}
+
+			//The oneof holds a pointer to a wrapper struct with the set field as its only field
+			wrapper := oneof.Elem().Elem()
+			wrapperField := wrapper.Type().Field(0)
+
+			tag, err := parseProtobufTag(wrapperField.Tag.Get("protobuf"))
+			if err != nil {
+				return nil, err
+			}
+
+			fields = append(fields, canonicalField{
+				tag:         tag,
+				structField: wrapperField,
+				value:       wrapper.Field(0),
+				always:      true,
+			})
+
+			continue
+		}
+
+		protobufTagValue, found := structField.Tag.Lookup("protobuf")
+		if !found {
+			continue
+		}
+
+		tag, err := parseProtobufTag(protobufTagValue)
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, canonicalField{
+			tag:         tag,
+			structField: structField,
+			value:       message.Field(i),
+		})
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].tag.number < fields[j].tag.number
+	})
+
+	return fields, nil
+}
+
+func appendCanonicalMessage(buf []byte, message reflect.Value) ([]byte, error) {
+	fields, err := canonicalFields(message)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields {
+		value := field.value
+
+		switch {
+		case value.Kind() == reflect.Map:
+			buf, err = appendCanonicalMap(buf, field, value)
+
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.Uint8:
+			buf, err = appendCanonicalRepeated(buf, field.tag, value)
+
+		default:
+			if !field.always && isDefaultValue(value) {
+				continue
+			}
+
+			buf, err = appendCanonicalField(buf, field.tag, value)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buf, nil
+}
+
+//isDefaultValue returns true if the scalar or message value is not set
+func isDefaultValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Ptr:
+		return value.IsNil()
+	case reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint32, reflect.Uint64:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		//Negative zero is not the default value, it has a different encoding
+		return math.Float64bits(value.Float()) == 0
+	default:
+		return false
+	}
+}
+
+func appendCanonicalRepeated(buf []byte, tag protobufTag, values reflect.Value) ([]byte, error) {
+	var err error
+
+	if !tag.packed {
+		for i := 0; i < values.Len(); i++ {
+			buf, err = appendCanonicalField(buf, tag, values.Index(i))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return buf, nil
+	}
+
+	//A packed field is a single length delimited field which contains the values without field keys
+	if values.Len() == 0 {
+		return buf, nil
+	}
+
+	var packed []byte
+	for i := 0; i < values.Len(); i++ {
+		packed, err = appendCanonicalValue(packed, tag, values.Index(i))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	buf = appendFieldKey(buf, tag.number, wireTypeBytes)
+	buf = appendVarint(buf, uint64(len(packed)))
+
+	return append(buf, packed...), nil
+}
+
+func appendCanonicalMap(buf []byte, field canonicalField, entries reflect.Value) ([]byte, error) {
+	keyTag, err := parseProtobufTag(field.structField.Tag.Get("protobuf_key"))
+	if err != nil {
+		return nil, err
+	}
+
+	valueTag, err := parseProtobufTag(field.structField.Tag.Get("protobuf_val"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := entries.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return lessMapKey(keys[i], keys[j])
+	})
+
+	//Every entry is encoded as a message with the key as field 1 and the value as field 2
+	for _, key := range keys {
+		entry, err := appendCanonicalField(nil, keyTag, key)
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err = appendCanonicalField(entry, valueTag, entries.MapIndex(key))
+		if err != nil {
+			return nil, err
+		}
+
+		buf = appendFieldKey(buf, field.tag.number, wireTypeBytes)
+		buf = appendVarint(buf, uint64(len(entry)))
+		buf = append(buf, entry...)
+	}
+
+	return buf, nil
+}
+
+//lessMapKey orders map keys, strings by their bytes and numbers by their value
+func lessMapKey(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	case reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	default:
+		return a.Uint() < b.Uint()
+	}
+}
+
+func appendFieldKey(buf []byte, number int, wireType int) []byte {
+	return appendVarint(buf, uint64(number)<<3|uint64(wireType))
+}
+
+func appendVarint(buf []byte, value uint64) []byte {
+	var varint [binary.MaxVarintLen64]byte
+	return append(buf, varint[:binary.PutUvarint(varint[:], value)]...)
+}
+
+//appendCanonicalField appends the field key followed by the value
+func appendCanonicalField(buf []byte, tag protobufTag, value reflect.Value) ([]byte, error) {
+	wireType, err := tag.wireType()
+	if err != nil {
+		return nil, err
+	}
+
+	buf = appendFieldKey(buf, tag.number, wireType)
+
+	return appendCanonicalValue(buf, tag, value)
+}
+
+//appendCanonicalValue appends the value without field key, values with the bytes wire type are prefixed with their length
+func appendCanonicalValue(buf []byte, tag protobufTag, value reflect.Value) ([]byte, error) {
+	kind := value.Kind()
+
+	switch {
+	case tag.encoding == "varint" && kind == reflect.Bool:
+		if value.Bool() {
+			return appendVarint(buf, 1), nil
+		}
+		return appendVarint(buf, 0), nil
+
+	case tag.encoding == "varint" && (kind == reflect.Int32 || kind == reflect.Int64):
+		return appendVarint(buf, uint64(value.Int())), nil
+
+	case tag.encoding == "varint" && (kind == reflect.Uint32 || kind == reflect.Uint64):
+		return appendVarint(buf, value.Uint()), nil
+
+	case tag.encoding == "zigzag32" && kind == reflect.Int32:
+		n := int32(value.Int())
+		return appendVarint(buf, uint64(uint32(n<<1)^uint32(n>>31))), nil
+
+	case tag.encoding == "zigzag64" && kind == reflect.Int64:
+		n := value.Int()
+		return appendVarint(buf, uint64(n<<1)^uint64(n>>63)), nil
+
+	case tag.encoding == "fixed32" && kind == reflect.Float32:
+		return appendFixed32(buf, math.Float32bits(float32(value.Float()))), nil
+
+	case tag.encoding == "fixed32" && kind == reflect.Int32:
+		return appendFixed32(buf, uint32(value.Int())), nil
+
+	case tag.encoding == "fixed32" && kind == reflect.Uint32:
+		return appendFixed32(buf, uint32(value.Uint())), nil
+
+	case tag.encoding == "fixed64" && kind == reflect.Float64:
+		return appendFixed64(buf, math.Float64bits(value.Float())), nil
+
+	case tag.encoding == "fixed64" && kind == reflect.Int64:
+		return appendFixed64(buf, uint64(value.Int())), nil
+
+	case tag.encoding == "fixed64" && kind == reflect.Uint64:
+		return appendFixed64(buf, value.Uint()), nil
+
+	case tag.encoding == "bytes" && kind == reflect.String:
+		buf = appendVarint(buf, uint64(value.Len()))
+		return append(buf, value.String()...), nil
+
+	case tag.encoding == "bytes" && kind == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8:
+		buf = appendVarint(buf, uint64(value.Len()))
+		return append(buf, value.Bytes()...), nil
+
+	case tag.encoding == "bytes" && kind == reflect.Ptr && value.Type().Elem().Kind() == reflect.Struct:
+		var nested []byte
+		if !value.IsNil() {
+			var err error
+			nested, err = appendCanonicalMessage(nil, value.Elem())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		buf = appendVarint(buf, uint64(len(nested)))
+		return append(buf, nested...), nil
+
+	default:
+		return nil, errors.Errorf("Unsupported type '%s' for protobuf encoding '%s'", value.Type(), tag.encoding)
+	}
+}
+
+func appendFixed32(buf []byte, value uint32) []byte {
+	var fixed [4]byte
+	binary.LittleEndian.PutUint32(fixed[:], value)
+	return append(buf, fixed[:]...)
+}
+
+func appendFixed64(buf []byte, value uint64) []byte {
+	var fixed [8]byte
+	binary.LittleEndian.PutUint64(fixed[:], value)
+	return append(buf, fixed[:]...)
+}
@@ -0,0 +1,122 @@
+package entities
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/golang/protobuf/proto"
+)
+
+//A canonicalVector is a golden test vector of the canonical encoding
+//Other implementations of the protocol can use the same vectors to check that their signatures will match
+type canonicalVector struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	//The protobuf encoding of the event as it could arrive from a neighbor, in hex
+	Event string `json:"event"`
+
+	//The canonical encoding of the event, in hex
+	Canonical string `json:"canonical"`
+
+	//The canonical encoding of the event without signature, the bytes covered by the signature, in hex
+	SignedPayload string `json:"signed_payload"`
+}
+
+func readCanonicalVectors(t *testing.T) []canonicalVector {
+	file, err := ioutil.ReadFile("testdata/canonical_vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var vectors []canonicalVector
+	err = json.Unmarshal(file, &vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return vectors
+}
+
+func decodeHex(t *testing.T, value string) []byte {
+	decoded, err := hex.DecodeString(value)
+	if err != nil {
+		t.Fatalf("Malformed hex '%s': %s", value, err)
+	}
+
+	return decoded
+}
+
+func Test_CanonicalEncode_Vectors(t *testing.T) {
+	for _, vector := range readCanonicalVectors(t) {
+		var event GenericEvent
+		err := proto.Unmarshal(decodeHex(t, vector.Event), &event.TableEvent)
+		if err != nil {
+			t.Fatalf("%s: error while decoding event: %s", vector.Name, err)
+		}
+
+		canonical, err := CanonicalEncode(&event.TableEvent)
+		if err != nil {
+			t.Fatalf("%s: error while encoding event: %s", vector.Name, err)
+		}
+
+		if !bytes.Equal(canonical, decodeHex(t, vector.Canonical)) {
+			t.Errorf("%s: unexpected canonical encoding '%x'", vector.Name, canonical)
+		}
+
+		payload, err := event.signedPayload()
+		if err != nil {
+			t.Fatalf("%s: error while encoding payload: %s", vector.Name, err)
+		}
+
+		if !bytes.Equal(payload, decodeHex(t, vector.SignedPayload)) {
+			t.Errorf("%s: unexpected signed payload '%x'", vector.Name, payload)
+		}
+
+		//The canonical encoding of a canonical encoding is the same
+		var decoded abusemesh.TableEvent
+		err = proto.Unmarshal(canonical, &decoded)
+		if err != nil {
+			t.Fatalf("%s: error while decoding canonical encoding: %s", vector.Name, err)
+		}
+
+		recoded, err := CanonicalEncode(&decoded)
+		if err != nil {
+			t.Fatalf("%s: error while encoding event: %s", vector.Name, err)
+		}
+
+		if !bytes.Equal(recoded, canonical) {
+			t.Errorf("%s: canonical encoding changed after decoding '%x'", vector.Name, recoded)
+		}
+	}
+}
+
+func Test_CanonicalEncode_MapOrder(t *testing.T) {
+	type mapMessage struct {
+		Labels map[string]int64 `protobuf:"bytes,1,rep,name=labels" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+		Count  int64            `protobuf:"varint,2,opt,name=count"`
+	}
+
+	message := &mapMessage{
+		Labels: map[string]int64{"b": 2, "a": 0, "c": 3},
+	}
+
+	//The entries are sorted on their key and always contain the key and the value, the default count is omitted
+	expected := "0a050a01611000" + "0a050a01621002" + "0a050a01631003"
+
+	for i := 0; i < 10; i++ {
+		canonical, err := appendCanonicalMessage(nil, reflect.ValueOf(message).Elem())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hex.EncodeToString(canonical) != expected {
+			t.Fatalf("Unexpected encoding of map '%x'", canonical)
+		}
+	}
+}
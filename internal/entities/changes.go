@@ -0,0 +1,288 @@
+package entities
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+//ErrSlowSubscriber signals that a table subscription ended because the subscriber couldn't keep up with the changes
+var ErrSlowSubscriber = errors.New("The subscriber can't keep up with the table changes")
+
+//TableName identifies one of the tables in the TableSet
+type TableName string
+
+//The names of the tables, they are used to filter changes
+const (
+	TableNodes          TableName = "nodes"
+	TableReports        TableName = "reports"
+	TableConfirmations  TableName = "confirmations"
+	TableDelistRequests TableName = "delist-requests"
+	TableNeighbors      TableName = "neighbors"
+)
+
+//ChangeType describes how a entity changed
+type ChangeType int
+
+const (
+	//ChangeCreated means the entity didn't exist before
+	ChangeCreated ChangeType = iota
+
+	//ChangeUpdated means the entity was replaced, both the old and new version are part of the change
+	ChangeUpdated
+
+	//ChangeDeleted means the entity no longer exists
+	ChangeDeleted
+)
+
+func (changeType ChangeType) String() string {
+	switch changeType {
+	case ChangeCreated:
+		return "created"
+	case ChangeUpdated:
+		return "updated"
+	case ChangeDeleted:
+		return "deleted"
+	default:
+		return fmt.Sprintf("ChangeType(%d)", int(changeType))
+	}
+}
+
+//A EntityChange describes the change of a single entity in the derived table state
+//Before and After hold the entity type of the table (Node, Report, ReportConfirmation, DelistRequest or Neighbor),
+//Before is nil for created entities and After is nil for deleted entities.
+type EntityChange struct {
+	Table TableName
+
+	//The key of the entity within its table, the UUID of the entity or for neighbors the UUIDs of both nodes separated by a '/'
+	Key string
+
+	Type   ChangeType
+	Before interface{}
+	After  interface{}
+}
+
+//A ChangeFilter selects the changes a subscriber receives, a empty list matches everything
+type ChangeFilter struct {
+	Tables []TableName
+	Keys   []string
+}
+
+func (filter ChangeFilter) matches(change EntityChange) bool {
+	if len(filter.Tables) > 0 && !containsTable(filter.Tables, change.Table) {
+		return false
+	}
+
+	if len(filter.Keys) > 0 && !containsKey(filter.Keys, change.Key) {
+		return false
+	}
+
+	return true
+}
+
+func containsTable(tables []TableName, table TableName) bool {
+	for _, t := range tables {
+		if t == table {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+//A changeSet collects the changes made while processing a single request
+//Multiple changes to the same entity are merged, so a remove followed by a add becomes a single update
+type changeSet struct {
+	changes []EntityChange
+	index   map[string]int
+}
+
+//record records that a entity changed from before to after, nil meaning the entity doesn't exist
+func (set *changeSet) record(table TableName, key string, before, after interface{}) {
+	if set == nil {
+		return
+	}
+
+	if set.index == nil {
+		set.index = make(map[string]int)
+	}
+
+	id := string(table) + "/" + key
+	if i, found := set.index[id]; found {
+		set.changes[i].After = after
+		return
+	}
+
+	set.index[id] = len(set.changes)
+	set.changes = append(set.changes, EntityChange{
+		Table:  table,
+		Key:    key,
+		Before: before,
+		After:  after,
+	})
+}
+
+//flush returns the recorded changes with their final type and resets the set
+func (set *changeSet) flush() []EntityChange {
+	changes := make([]EntityChange, 0, len(set.changes))
+	for _, change := range set.changes {
+		switch {
+		case change.Before == nil && change.After == nil:
+			//The entity was created and removed again
+			continue
+		case change.Before == nil:
+			change.Type = ChangeCreated
+		case change.After == nil:
+			change.Type = ChangeDeleted
+		default:
+			change.Type = ChangeUpdated
+		}
+
+		changes = append(changes, change)
+	}
+
+	set.changes = nil
+	set.index = nil
+
+	return changes
+}
+
+//DefaultChangeQueueSize is the queue size of a table subscription if the request doesn't specify one
+const DefaultChangeQueueSize = 1000
+
+//A TableSubscription receives the changes to the tables which match its filter
+type TableSubscription struct {
+	//Changes receives the changes in the order in which they were applied,
+	//the channel is closed when the subscription ends
+	Changes <-chan EntityChange
+
+	//Snapshot is the state of the tables at the moment the subscription started,
+	//applying the changes to the snapshot gives the current state
+	Snapshot *TableSnapshot
+
+	changes chan EntityChange
+	filter  ChangeFilter
+	err     error
+}
+
+//Err returns the reason the subscription ended, only valid after the Changes channel is closed
+//Returns ErrSlowSubscriber if the subscriber didn't keep up, in which case it has to subscribe again to resync
+func (sub *TableSubscription) Err() error {
+	return sub.err
+}
+
+//publish sends the changes of the last request to the subscribers, must be called from the goroutine of the TableSet
+//Subscribers whose queue is full are disconnected, waiting for them would block all table updates
+func (set *TableSet) publish() {
+	if len(set.changes.changes) == 0 {
+		return
+	}
+
+	changes := set.changes.flush()
+
+	for sub := range set.subscriptions {
+		for _, change := range changes {
+			if !sub.filter.matches(change) {
+				continue
+			}
+
+			select {
+			case sub.changes <- change:
+				continue
+			default:
+			}
+
+			logrus.WithField("queue-size", cap(sub.changes)).Warn("Table subscriber can't keep up with the changes, disconnecting")
+
+			sub.err = ErrSlowSubscriber
+			set.unsubscribe(sub)
+
+			break
+		}
+	}
+}
+
+func (set *TableSet) unsubscribe(sub *TableSubscription) {
+	if _, found := set.subscriptions[sub]; !found {
+		return
+	}
+
+	delete(set.subscriptions, sub)
+	close(sub.changes)
+}
+
+//SubscribeRequest can be used to subscribe to the changes of the tables
+type SubscribeRequest struct {
+	ResponseChan chan<- *TableSubscription
+	Filter       ChangeFilter
+
+	//The amount of changes which can be queued for the subscriber
+	QueueSize int
+}
+
+//Process processes the request and sends the new subscription on the ResponseChan
+func (req *SubscribeRequest) Process(tables *TableSet) error {
+	queueSize := req.QueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultChangeQueueSize
+	}
+
+	changes := make(chan EntityChange, queueSize)
+	sub := &TableSubscription{
+		Changes: changes,
+		changes: changes,
+		filter:  req.Filter,
+	}
+
+	sub.Snapshot = tables.takeSnapshot()
+
+	tables.subscriptions[sub] = struct{}{}
+
+	req.ResponseChan <- sub
+
+	return nil
+}
+
+//UnsubscribeRequest ends a subscription, the Changes channel of the subscription is closed
+type UnsubscribeRequest struct {
+	Subscription *TableSubscription
+}
+
+//Process processes the request and ends the subscription
+func (req *UnsubscribeRequest) Process(tables *TableSet) error {
+	tables.unsubscribe(req.Subscription)
+
+	return nil
+}
+
+//Subscribe subscribes to the changes of the tables which match the filter
+//NOTE: must not be called from the goroutine of the TableSet since it waits for the request to be processed
+func (set *TableSet) Subscribe(filter ChangeFilter, queueSize int) *TableSubscription {
+	responseChan := make(chan *TableSubscription, 1)
+
+	set.Channel <- &SubscribeRequest{
+		ResponseChan: responseChan,
+		Filter:       filter,
+		QueueSize:    queueSize,
+	}
+
+	return <-responseChan
+}
+
+//Unsubscribe ends the subscription, changes which are already queued can still be read from the Changes channel
+func (set *TableSet) Unsubscribe(sub *TableSubscription) {
+	set.Channel <- &UnsubscribeRequest{
+		Subscription: sub,
+	}
+}
@@ -0,0 +1,263 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/google/uuid"
+)
+
+//nextChange returns the next change of the subscription, the test fails if none arrives in time
+func nextChange(t *testing.T, sub *TableSubscription) EntityChange {
+	select {
+	case change, ok := <-sub.Changes:
+		if !ok {
+			t.Fatalf("Subscription ended: %v", sub.Err())
+		}
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatal("No change received")
+	}
+
+	return EntityChange{}
+}
+
+//assertNoChange fails the test if the subscription has a queued change
+//The TableSet publishes changes before it answers the next request, so a round trip makes sure nothing is pending
+func assertNoChange(t *testing.T, tableSet *TableSet, sub *TableSubscription) {
+	tableSet.Snapshot()
+
+	select {
+	case change := <-sub.Changes:
+		t.Errorf("Expected no change, got %s of %s/%s", change.Type, change.Table, change.Key)
+	default:
+	}
+}
+
+//reportAddress returns the address of the report before or after a change, empty if the entity is not a report
+func reportAddress(entity interface{}) string {
+	if report, ok := entity.(Report); ok {
+		return report.Prefix.IP.String()
+	}
+
+	return ""
+}
+
+//A expectedChange is a change a subscriber should receive, before and after are the addresses of a changed report
+type expectedChange struct {
+	table      TableName
+	key        string
+	changeType ChangeType
+	before     string
+	after      string
+}
+
+//Subscribers receive the created, updated and deleted entities which match their filter
+func Test_TableSubscription_Changes(t *testing.T) {
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	node := newTestNode(t, "node-a")
+	watched, other := uuid.New(), uuid.New()
+
+	all := tableSet.Subscribe(ChangeFilter{}, 0)
+	defer tableSet.Unsubscribe(all)
+
+	filtered := tableSet.Subscribe(ChangeFilter{
+		Tables: []TableName{TableReports},
+		Keys:   []string{watched.String()},
+	}, 0)
+	defer tableSet.Unsubscribe(filtered)
+
+	created := expectedChange{table: TableReports, key: watched.String(), changeType: ChangeCreated, after: "198.51.100.2"}
+	updated := expectedChange{table: TableReports, key: watched.String(), changeType: ChangeUpdated, before: "198.51.100.2", after: "198.51.100.3"}
+	deleted := expectedChange{table: TableReports, key: watched.String(), changeType: ChangeDeleted, before: "198.51.100.3"}
+
+	//Every step applies its event and checks the changes both subscribers receive
+	tests := []struct {
+		name     string
+		event    *GenericEvent
+		all      []expectedChange
+		filtered []expectedChange
+	}{
+		{
+			name:  "node",
+			event: node.announce(t),
+			all:   []expectedChange{{table: TableNodes, key: node.id.String(), changeType: ChangeCreated}},
+		},
+		{
+			name:  "other report",
+			event: node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, other, "198.51.100.1"),
+			all:   []expectedChange{{table: TableReports, key: other.String(), changeType: ChangeCreated, after: "198.51.100.1"}},
+		},
+		{
+			name:     "new",
+			event:    node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, watched, "198.51.100.2"),
+			all:      []expectedChange{created},
+			filtered: []expectedChange{created},
+		},
+		{
+			name:     "edit",
+			event:    node.report(t, abusemesh.TableEventType_TABLE_UPDATE_EDIT, watched, "198.51.100.3"),
+			all:      []expectedChange{updated},
+			filtered: []expectedChange{updated},
+		},
+		{
+			name:     "delete",
+			event:    node.report(t, abusemesh.TableEventType_TABLE_UPDATE_DELETE, watched, "198.51.100.3"),
+			all:      []expectedChange{deleted},
+			filtered: []expectedChange{deleted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := applyEvent(tableSet, tt.event)
+			if err != nil {
+				t.Fatalf("Error while applying event: %s", err)
+			}
+
+			for _, subscriber := range []struct {
+				name     string
+				sub      *TableSubscription
+				expected []expectedChange
+			}{
+				{name: "all", sub: all, expected: tt.all},
+				{name: "filtered", sub: filtered, expected: tt.filtered},
+			} {
+				for _, expected := range subscriber.expected {
+					change := nextChange(t, subscriber.sub)
+					if change.Table != expected.table || change.Key != expected.key || change.Type != expected.changeType {
+						t.Errorf("%s: expected %s of %s/%s, got %s of %s/%s", subscriber.name,
+							expected.changeType, expected.table, expected.key, change.Type, change.Table, change.Key)
+					}
+					if reportAddress(change.Before) != expected.before || reportAddress(change.After) != expected.after {
+						t.Errorf("%s: expected the report to change from '%s' to '%s', got '%s' to '%s'", subscriber.name,
+							expected.before, expected.after, reportAddress(change.Before), reportAddress(change.After))
+					}
+				}
+
+				assertNoChange(t, tableSet, subscriber.sub)
+			}
+		})
+	}
+}
+
+//Changes to the same entity while processing one request are merged into a single change
+func Test_ChangeSet_Merge(t *testing.T) {
+	report := Report{UUID: uuid.New(), Category: "spam"}
+	edited := report
+	edited.Category = "phishing"
+
+	type record struct {
+		table         TableName
+		before, after interface{}
+	}
+
+	tests := []struct {
+		name    string
+		records []record
+		expect  []EntityChange
+	}{
+		{
+			name:    "remove then add",
+			records: []record{{TableReports, report, nil}, {TableReports, nil, edited}},
+			expect:  []EntityChange{{Table: TableReports, Key: "key", Type: ChangeUpdated, Before: report, After: edited}},
+		},
+		{
+			//A entity which was created and removed again didn't change
+			name:    "add then remove",
+			records: []record{{TableReports, nil, report}, {TableReports, report, nil}},
+			expect:  []EntityChange{},
+		},
+		{
+			//The same key in a other table is a different entity
+			name:    "other table",
+			records: []record{{TableReports, nil, report}, {TableNodes, nil, Node{}}},
+			expect: []EntityChange{
+				{Table: TableReports, Key: "key", Type: ChangeCreated, After: report},
+				{Table: TableNodes, Key: "key", Type: ChangeCreated, After: Node{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var set changeSet
+			for _, record := range tt.records {
+				set.record(record.table, "key", record.before, record.after)
+			}
+
+			changes := set.flush()
+			if !reflect.DeepEqual(changes, tt.expect) {
+				t.Errorf("Expected changes %v, got %v", tt.expect, changes)
+			}
+
+			if len(set.flush()) != 0 {
+				t.Error("Expected the change set to be empty after a flush")
+			}
+		})
+	}
+}
+
+//A subscriber whose queue is full is disconnected instead of blocking the TableSet
+func Test_TableSubscription_SlowSubscriber(t *testing.T) {
+	tests := []struct {
+		name         string
+		queueSize    int
+		disconnected bool
+	}{
+		{name: "full queue", queueSize: 1, disconnected: true},
+		{name: "enough room", queueSize: 2, disconnected: false},
+	}
+
+	node := newTestNode(t, "node-a")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tableSet, stopTableSet := runTestTableSet()
+			defer stopTableSet()
+
+			//Unsubscribing a ended subscription is harmless
+			sub := tableSet.Subscribe(ChangeFilter{}, tt.queueSize)
+			defer tableSet.Unsubscribe(sub)
+
+			for _, event := range []*GenericEvent{
+				node.announce(t),
+				node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, uuid.New(), "198.51.100.1"),
+			} {
+				err := applyEvent(tableSet, event)
+				if err != nil {
+					t.Fatalf("Error while applying event: %s", err)
+				}
+			}
+
+			if change := nextChange(t, sub); change.Table != TableNodes {
+				t.Errorf("Expected the first change to be about the node, got %s", change.Table)
+			}
+
+			if !tt.disconnected {
+				if change := nextChange(t, sub); change.Table != TableReports {
+					t.Errorf("Expected the second change to be about the report, got %s", change.Table)
+				}
+				assertNoChange(t, tableSet, sub)
+				return
+			}
+
+			select {
+			case change, ok := <-sub.Changes:
+				if ok {
+					t.Fatalf("Expected the subscription to end, got %s of %s/%s", change.Type, change.Table, change.Key)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Subscription didn't end")
+			}
+
+			if sub.Err() != ErrSlowSubscriber {
+				t.Errorf("Expected ErrSlowSubscriber, got %v", sub.Err())
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package entities
+
+import (
+	"context"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/utils/conv"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var (
+	//ErrSelfConfirmation signals that a node tried to confirm its own report
+	ErrSelfConfirmation = errors.New("A reporter can't confirm its own report")
+
+	//ErrNotConfirmer signals that a event changes a report confirmation but is not made by the node which confirmed the report
+	ErrNotConfirmer = errors.New("Only the confirmer of a report confirmation can edit or withdraw it")
+)
+
+//DefaultNodeTrust is the trust given to nodes for which no explicit trust has been set
+const DefaultNodeTrust = 0.5
+
+//A ReportConfirmation is the confirmation of a report by a node other than the reporter
+type ReportConfirmation struct {
+	UUID uuid.UUID
+	//The UUID of the confirmed report
+	Report uuid.UUID
+	//The UUID of the node which confirmed the report
+	Confirmer uuid.UUID
+	//The signed timestamp of the event which made the confirmation
+	ConfirmedAt time.Time
+}
+
+//ReportConfirmationFromProtobuf creates a report confirmation object from the protobuf stub
+//ConfirmedAt is not part of the message, it is the time at which the event was signed
+func ReportConfirmationFromProtobuf(protobufConfirmation *abusemesh.ReportConfirmation) (ReportConfirmation, error) {
+	confirmationID, err := conv.AuuidToGuuid(protobufConfirmation.GetUuid())
+	if err != nil {
+		return ReportConfirmation{}, errors.Wrap(err, "Report confirmation ID invalid")
+	}
+
+	reportID, err := conv.AuuidToGuuid(protobufConfirmation.GetReport())
+	if err != nil {
+		return ReportConfirmation{}, errors.Wrap(err, "Report ID invalid")
+	}
+
+	confirmerID, err := conv.AuuidToGuuid(protobufConfirmation.GetConfirmer())
+	if err != nil {
+		return ReportConfirmation{}, errors.Wrap(err, "Confirmer ID invalid")
+	}
+
+	return ReportConfirmation{
+		UUID:      confirmationID,
+		Report:    reportID,
+		Confirmer: confirmerID,
+	}, nil
+}
+
+//validateReportConfirmation checks that the confirmed report is known, that the confirmer isn't the reporter
+//and that the confirmation is signed by the confirmer
+func validateReportConfirmation(event *GenericEvent, tableSet *TableSet, confirmation *abusemesh.ReportConfirmation) error {
+	reportID, err := conv.AuuidToGuuid(confirmation.GetReport())
+	if err != nil {
+		return errors.Wrap(err, "Report ID invalid")
+	}
+
+	report := tableSet.getReport(reportID)
+	if report == nil {
+		return ErrUnknownReport
+	}
+
+	confirmerID, err := conv.AuuidToGuuid(confirmation.GetConfirmer())
+	if err != nil {
+		return errors.Wrap(err, "Confirmer ID invalid")
+	}
+
+	if confirmerID == report.Reporter {
+		return ErrSelfConfirmation
+	}
+
+	return event.verifySignedBy(tableSet, confirmation.GetConfirmer())
+}
+
+//A ConfirmationTable holds all report confirmations known to the current node
+type ConfirmationTable struct {
+	Entities map[uuid.UUID]ReportConfirmation
+
+	//byReport indexes the confirmations on the report they confirm
+	byReport map[uuid.UUID]map[uuid.UUID]struct{}
+
+	//trust holds the trust, between 0 and 1, the operator has in specific nodes
+	trust map[uuid.UUID]float64
+
+	//shared is true if Entities is part of a snapshot and has to be copied before it is written
+	shared bool
+
+	//changes records the changes to Entities
+	changes *changeSet
+}
+
+func (table *ConfirmationTable) handleTableEvent(
+	eventType abusemesh.TableEventType,
+	entity *abusemesh.TableEvent_ReportConfirmation,
+	signedAt time.Time,
+) error {
+	table.own()
+
+	switch eventType {
+	case abusemesh.TableEventType_TABLE_UPDATE_NEW, abusemesh.TableEventType_TABLE_UPDATE_EDIT:
+		confirmation, err := ReportConfirmationFromProtobuf(entity.ReportConfirmation)
+		if err != nil {
+			return err
+		}
+
+		confirmation.ConfirmedAt = signedAt
+
+		//A edit may move the confirmation to a other report
+		table.remove(confirmation.UUID)
+
+		table.changes.record(TableConfirmations, confirmation.UUID.String(), nil, confirmation)
+		table.Entities[confirmation.UUID] = confirmation
+
+		confirmations, found := table.byReport[confirmation.Report]
+		if !found {
+			confirmations = make(map[uuid.UUID]struct{})
+			table.byReport[confirmation.Report] = confirmations
+		}
+		confirmations[confirmation.UUID] = struct{}{}
+
+	case abusemesh.TableEventType_TABLE_UPDATE_DELETE:
+		confirmationID, err := conv.AuuidToGuuid(entity.ReportConfirmation.GetUuid())
+		if err != nil {
+			return err
+		}
+
+		table.remove(confirmationID)
+
+	default:
+		return errors.Errorf("Unknown abusemesh.TableEventType type '%T'", eventType)
+	}
+
+	return nil
+}
+
+//remove deletes a confirmation from the table and the report index
+func (table *ConfirmationTable) remove(confirmationID uuid.UUID) {
+	confirmation, found := table.Entities[confirmationID]
+	if !found {
+		return
+	}
+
+	table.changes.record(TableConfirmations, confirmationID.String(), confirmation, nil)
+	delete(table.Entities, confirmationID)
+	delete(table.byReport[confirmation.Report], confirmationID)
+
+	if len(table.byReport[confirmation.Report]) == 0 {
+		delete(table.byReport, confirmation.Report)
+	}
+}
+
+//nodeTrust returns the trust in the given node
+func (table *ConfirmationTable) nodeTrust(nodeID uuid.UUID) float64 {
+	if trust, found := table.trust[nodeID]; found {
+		return trust
+	}
+
+	return DefaultNodeTrust
+}
+
+//ReportCredibility is the aggregated confirmation state of a report
+type ReportCredibility struct {
+	Report Report
+
+	//Confirmations is the amount of distinct nodes which confirmed the report
+	Confirmations int
+
+	//ConfirmingASNs is the amount of distinct ASNs, other than the ASN of the reporter, which confirmed the report
+	ConfirmingASNs int
+
+	//Score is the chance, between 0 and 1, that the report is correct based on the trust in the reporter and the confirmers
+	Score float64
+}
+
+//credibility aggregates the confirmations of the report
+func (set *TableSet) credibility(report Report) ReportCredibility {
+	table := &set.confirmationTable
+
+	//The score is the chance that not all of the reporter and confirmers are wrong
+	doubt := 1 - table.nodeTrust(report.Reporter)
+
+	var reporterASN int32
+	if reporter, found := set.nodeTable.Entities[report.Reporter]; found {
+		reporterASN = reporter.ASN
+	}
+
+	confirmers := make(map[uuid.UUID]struct{})
+	asns := make(map[int32]struct{})
+
+	for confirmationID := range table.byReport[report.UUID] {
+		confirmerID := table.Entities[confirmationID].Confirmer
+
+		//Multiple confirmations of the same node only count once
+		if _, found := confirmers[confirmerID]; found {
+			continue
+		}
+		confirmers[confirmerID] = struct{}{}
+
+		doubt *= 1 - table.nodeTrust(confirmerID)
+
+		if confirmer, found := set.nodeTable.Entities[confirmerID]; found && confirmer.ASN != reporterASN {
+			asns[confirmer.ASN] = struct{}{}
+		}
+	}
+
+	return ReportCredibility{
+		Report:         report,
+		Confirmations:  len(confirmers),
+		ConfirmingASNs: len(asns),
+		Score:          1 - doubt,
+	}
+}
+
+//SetNodeTrustRequest can be used to set the trust the operator has in a node
+type SetNodeTrustRequest struct {
+	NodeID uuid.UUID
+	//Trust is a value between 0 (no trust) and 1 (full trust)
+	Trust float64
+
+	//ErrorChan receives nil once the trust is set or the error which prevented it, it is optional.
+	//The channel must be buffered, the TableSet doesn't wait for the caller to receive the outcome
+	ErrorChan chan<- error
+}
+
+func (req *SetNodeTrustRequest) reportResult(err error) {
+	sendResult(req.ErrorChan, err)
+}
+
+//Process processes the request and updates the trust of the node
+func (req *SetNodeTrustRequest) Process(tables *TableSet) error {
+	if req.Trust < 0 || req.Trust > 1 {
+		return errors.Errorf("Trust '%f' is not between 0 and 1", req.Trust)
+	}
+
+	tables.confirmationTable.trust[req.NodeID] = req.Trust
+
+	return nil
+}
+
+//GetReportCredibilityRequest can be used to request the credibility of a specific report
+type GetReportCredibilityRequest struct {
+	ResponseChan chan<- *ReportCredibility
+	ReportID     uuid.UUID
+}
+
+//Process processes the request and sends a pointer to the credibility on the ResponseChan or nil if the report was not found
+func (req *GetReportCredibilityRequest) Process(tables *TableSet) error {
+	report, found := tables.reportTable.Entities[req.ReportID]
+	if !found {
+		req.ResponseChan <- nil
+		return nil
+	}
+
+	credibility := tables.credibility(report)
+	req.ResponseChan <- &credibility
+
+	return nil
+}
+
+//GetConfirmedReportsRequest can be used to request all reports which are confirmed by a minimum amount of independent ASNs
+type GetConfirmedReportsRequest struct {
+	//ResponseChan is the channel over which multiple reports will be sent
+	ResponseChan chan<- ReportCredibility
+
+	//Context can be used to cancel the sending of reports
+	Context context.Context
+
+	//MinASNs is the minimum amount of distinct ASNs, other than the ASN of the reporter, which must have confirmed the report
+	MinASNs int
+}
+
+//Process processes the request and sends all confirmed reports on the ResponseChan, the channel is closed when all are sent
+//The credibility is calculated up front and sent from a separate goroutine, so a slow requester doesn't block the TableSet
+func (req *GetConfirmedReportsRequest) Process(tables *TableSet) error {
+	var confirmed []ReportCredibility
+
+	for _, report := range tables.reportTable.Entities {
+		//Delisted reports should no longer be acted upon
+		if report.Delisted {
+			continue
+		}
+
+		credibility := tables.credibility(report)
+		if credibility.ConfirmingASNs >= req.MinASNs {
+			confirmed = append(confirmed, credibility)
+		}
+	}
+
+	go func() {
+		defer close(req.ResponseChan)
+
+		for _, credibility := range confirmed {
+			select {
+			case req.ResponseChan <- credibility:
+			//If the requester no longer wishes to receive reports we return
+			case <-req.Context.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
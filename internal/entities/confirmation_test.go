@@ -0,0 +1,120 @@
+package entities
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func Test_TableSet_Credibility(t *testing.T) {
+	reporter, confirmerA, confirmerB, confirmerC := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name string
+		//The confirmers of the report, a node may confirm more than once
+		confirmers []uuid.UUID
+		trust      map[uuid.UUID]float64
+
+		confirmations  int
+		confirmingASNs int
+		score          float64
+	}{
+		{
+			name:  "unconfirmed",
+			score: DefaultNodeTrust,
+		},
+		{
+			name:  "trusted reporter",
+			trust: map[uuid.UUID]float64{reporter: 0.9},
+			score: 0.9,
+		},
+		{
+			name:           "confirmed",
+			confirmers:     []uuid.UUID{confirmerA, confirmerB},
+			confirmations:  2,
+			confirmingASNs: 1,
+			score:          1 - 0.5*0.5*0.5,
+		},
+		{
+			name:           "confirmed twice by the same node",
+			confirmers:     []uuid.UUID{confirmerA, confirmerA},
+			confirmations:  1,
+			confirmingASNs: 1,
+			score:          1 - 0.5*0.5,
+		},
+		{
+			name:           "confirmed by the ASN of the reporter",
+			confirmers:     []uuid.UUID{confirmerC},
+			confirmations:  1,
+			confirmingASNs: 0,
+			score:          1 - 0.5*0.5,
+		},
+		{
+			name:           "untrusted confirmer",
+			confirmers:     []uuid.UUID{confirmerA, confirmerB},
+			trust:          map[uuid.UUID]float64{reporter: 0.8, confirmerA: 0, confirmerB: 1},
+			confirmations:  2,
+			confirmingASNs: 1,
+			score:          1,
+		},
+	}
+
+	for _, test := range tests {
+		tableSet := NewTableSet(0)
+
+		//confirmerA and confirmerB share a ASN, confirmerC is in the ASN of the reporter
+		for nodeID, asn := range map[uuid.UUID]int32{reporter: 64496, confirmerA: 64497, confirmerB: 64497, confirmerC: 64496} {
+			tableSet.nodeTable.Entities[nodeID] = Node{UUID: nodeID, ASN: asn}
+		}
+
+		for nodeID, trust := range test.trust {
+			err := (&SetNodeTrustRequest{NodeID: nodeID, Trust: trust}).Process(tableSet)
+			if err != nil {
+				t.Fatalf("%s: error while setting trust: %s", test.name, err)
+			}
+		}
+
+		report := Report{UUID: uuid.New(), Reporter: reporter}
+		for _, confirmer := range test.confirmers {
+			confirmationID := uuid.New()
+			tableSet.confirmationTable.Entities[confirmationID] = ReportConfirmation{UUID: confirmationID, Report: report.UUID, Confirmer: confirmer}
+			if tableSet.confirmationTable.byReport[report.UUID] == nil {
+				tableSet.confirmationTable.byReport[report.UUID] = make(map[uuid.UUID]struct{})
+			}
+			tableSet.confirmationTable.byReport[report.UUID][confirmationID] = struct{}{}
+		}
+
+		credibility := tableSet.credibility(report)
+
+		if credibility.Confirmations != test.confirmations {
+			t.Errorf("%s: expected %d confirmations, got %d", test.name, test.confirmations, credibility.Confirmations)
+		}
+		if credibility.ConfirmingASNs != test.confirmingASNs {
+			t.Errorf("%s: expected %d confirming ASNs, got %d", test.name, test.confirmingASNs, credibility.ConfirmingASNs)
+		}
+		if math.Abs(credibility.Score-test.score) > 1e-9 {
+			t.Errorf("%s: expected score %f, got %f", test.name, test.score, credibility.Score)
+		}
+	}
+}
+
+func Test_SetNodeTrustRequest_Range(t *testing.T) {
+	tableSet := NewTableSet(0)
+
+	for _, trust := range []float64{-0.1, 1.1} {
+		err := (&SetNodeTrustRequest{NodeID: uuid.New(), Trust: trust}).Process(tableSet)
+		if err == nil {
+			t.Errorf("Expected trust %f to be refused", trust)
+		}
+	}
+
+	if len(tableSet.confirmationTable.trust) != 0 {
+		t.Errorf("Expected no trust to be set, got %v", tableSet.confirmationTable.trust)
+	}
+
+	err := (&SetNodeTrustRequest{NodeID: uuid.New(), Trust: 1}).Process(tableSet)
+	if err != nil {
+		t.Errorf("Expected trust 1 to be accepted, got %s", err)
+	}
+}
@@ -0,0 +1,407 @@
+package entities
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/abuse-mesh/abuse-mesh-go/internal/storage"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+//dedupKeyPrefix is the prefix of the keys of the exact event id index in the storage backend
+//The ids are stored in buckets on the first two bytes of the id, so the storage backend only has to keep a limited amount of keys
+const dedupKeyPrefix = "dedup/bucket/"
+
+//DedupPolicy determines the size of the filters used to detect duplicate events
+type DedupPolicy struct {
+	//The amount of events the first filter is sized for, every next filter is sized for twice as many events
+	ExpectedEvents uint
+
+	//The false positive rate of all filters together
+	FalsePositiveRate float64
+
+	//The maximum amount of ids in the exact index if it is kept in memory, older ids are only kept in the filters
+	MemoryIndexSize int
+}
+
+//DefaultDedupPolicy is used for the fields of the policy which are not set
+var DefaultDedupPolicy = DedupPolicy{
+	ExpectedEvents:    100000,
+	FalsePositiveRate: 0.001,
+	MemoryIndexSize:   1000000,
+}
+
+//DedupStats describes the effectiveness and memory usage of the deduplicator
+type DedupStats struct {
+	//The amount of times a event id was checked
+	Lookups uint64
+
+	//The amount of lookups which had to consult the exact index
+	IndexLookups uint64
+
+	//The amount of new event ids which the filters claimed to contain
+	FalsePositives uint64
+
+	//The measured rate of false positives among the lookups of new event ids
+	FalsePositiveRate float64
+
+	//The false positive rate the filters are expected to have given the amount of ids they contain
+	EstimatedFalsePositiveRate float64
+
+	//The amount of filters and the memory used by them in bytes
+	Filters      int
+	FilterMemory int
+
+	//The amount of event ids in the exact index and whether the index is kept in memory or stored
+	IndexSize     int
+	IndexInMemory bool
+
+	//The amount of ids which were evicted from the memory index, duplicates of them are detected by the filters alone
+	IndexEvicted uint64
+}
+
+//A bloomFilter is a probabilistic set, it can claim to contain ids which were never added but never misses a id which was added
+type bloomFilter struct {
+	bits     []uint64
+	hashes   uint64
+	count    uint
+	capacity uint
+}
+
+//newBloomFilter creates a filter which has the given false positive rate once it contains n items
+func newBloomFilter(n uint, falsePositiveRate float64) *bloomFilter {
+	if n == 0 {
+		n = 1
+	}
+
+	bits := math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	hashes := math.Max(1, math.Round(bits/float64(n)*math.Ln2))
+
+	return &bloomFilter{
+		bits:     make([]uint64, int(bits)/64+1),
+		hashes:   uint64(hashes),
+		capacity: n,
+	}
+}
+
+//positions calls the callback with the bit positions of the id
+func (filter *bloomFilter) positions(id uuid.UUID, callback func(word int, mask uint64) bool) {
+	//Event ids are chosen by the author, so they are hashed instead of trusting them to be random
+	hash := fnv.New128a()
+	hash.Write(id[:])
+	sum := hash.Sum(nil)
+
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16]) | 1
+	size := uint64(len(filter.bits) * 64)
+
+	for i := uint64(0); i < filter.hashes; i++ {
+		position := (h1 + i*h2) % size
+		if !callback(int(position/64), 1<<(position%64)) {
+			return
+		}
+	}
+}
+
+func (filter *bloomFilter) add(id uuid.UUID) {
+	filter.positions(id, func(word int, mask uint64) bool {
+		filter.bits[word] |= mask
+		return true
+	})
+	filter.count++
+}
+
+func (filter *bloomFilter) mayContain(id uuid.UUID) bool {
+	found := true
+	filter.positions(id, func(word int, mask uint64) bool {
+		found = filter.bits[word]&mask != 0
+		return found
+	})
+
+	return found
+}
+
+//estimatedFalsePositiveRate returns the false positive rate given the amount of ids in the filter
+func (filter *bloomFilter) estimatedFalsePositiveRate() float64 {
+	bits := float64(len(filter.bits) * 64)
+	return math.Pow(1-math.Exp(-float64(filter.hashes)*float64(filter.count)/bits), float64(filter.hashes))
+}
+
+//A EventDeduplicator keeps track of the ids of all accepted events without keeping the events in memory
+//Ids are kept in a exact index, which is stored in a storage backend. A scalable bloom filter in front of the index
+//contains all ids, so for most new events the index doesn't have to be consulted. Once a filter is full a new filter is
+//started which is sized for twice as many ids with half the false positive rate, so together they stay within the policy.
+//The signed timestamp of a event is not used, the author of a event could sign it again with a other timestamp.
+//
+//The filters use about 2 bytes per id at the default false positive rate. Without a storage backend the exact index
+//is kept in memory and holds the MemoryIndexSize most recent ids. A id which is found in a filter of which ids
+//have been evicted from the memory index is considered a duplicate, so a new event is refused at the false positive rate
+//of that filter instead of a old event being accepted twice
+type EventDeduplicator struct {
+	policy DedupPolicy
+
+	//The filters in the order they were started, ids are added to the last filter
+	filters []*bloomFilter
+
+	//The exact index, if nil the memoryIndex is used
+	index       storage.StorageBackend
+	memoryIndex map[uuid.UUID]struct{}
+	indexSize   int
+
+	//The ids in the memory index in the order they were added, the oldest first
+	memoryOrder *list.List
+
+	//The amount of ids which were evicted from the memory index, they are the first ids which were added
+	evicted uint64
+
+	lookups         uint64
+	indexLookups    uint64
+	filterNegatives uint64
+	falsePositives  uint64
+
+	lock sync.Mutex
+}
+
+//NewEventDeduplicator creates a deduplicator which stores the exact index in the storage backend
+//If index is nil the exact index is kept in memory
+func NewEventDeduplicator(policy DedupPolicy, index storage.StorageBackend) (*EventDeduplicator, error) {
+	if policy.ExpectedEvents == 0 {
+		policy.ExpectedEvents = DefaultDedupPolicy.ExpectedEvents
+	}
+	if policy.FalsePositiveRate <= 0 || policy.FalsePositiveRate >= 1 {
+		policy.FalsePositiveRate = DefaultDedupPolicy.FalsePositiveRate
+	}
+	if policy.MemoryIndexSize <= 0 {
+		policy.MemoryIndexSize = DefaultDedupPolicy.MemoryIndexSize
+	}
+
+	deduplicator := &EventDeduplicator{
+		policy: policy,
+		index:  index,
+	}
+
+	if index == nil {
+		deduplicator.memoryIndex = make(map[uuid.UUID]struct{})
+		deduplicator.memoryOrder = list.New()
+		return deduplicator, nil
+	}
+
+	err := deduplicator.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return deduplicator, nil
+}
+
+//bucketKey returns the key of the bucket of the id in the storage backend
+func bucketKey(id uuid.UUID) []byte {
+	return append([]byte(dedupKeyPrefix), id[0:2]...)
+}
+
+//searchBucket returns the position of the id in the sorted bucket and true if the bucket contains it
+//If the bucket doesn't contain the id the position is where it should be inserted
+func searchBucket(bucket []byte, id uuid.UUID) (int, bool) {
+	entries := len(bucket) / len(id)
+
+	position := sort.Search(entries, func(i int) bool {
+		return bytes.Compare(bucket[i*len(id):(i+1)*len(id)], id[:]) >= 0
+	})
+
+	found := position < entries && bytes.Equal(bucket[position*len(id):(position+1)*len(id)], id[:])
+
+	return position * len(id), found
+}
+
+//readBucket returns the bucket of the id, which is empty if no id in the bucket has been added
+func (deduplicator *EventDeduplicator) readBucket(id uuid.UUID) ([]byte, error) {
+	bucket, err := deduplicator.index.Get(bucketKey(id))
+	if err == storage.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while reading event id index")
+	}
+
+	return bucket, nil
+}
+
+//indexContains checks the exact index, the lock must be held by the caller
+func (deduplicator *EventDeduplicator) indexContains(id uuid.UUID) (bool, error) {
+	deduplicator.indexLookups++
+
+	if deduplicator.index == nil {
+		_, found := deduplicator.memoryIndex[id]
+		return found, nil
+	}
+
+	bucket, err := deduplicator.readBucket(id)
+	if err != nil {
+		return false, err
+	}
+
+	_, found := searchBucket(bucket, id)
+
+	return found, nil
+}
+
+//Contains returns true if a event with the id has been added
+func (deduplicator *EventDeduplicator) Contains(id uuid.UUID) (bool, error) {
+	deduplicator.lock.Lock()
+	defer deduplicator.lock.Unlock()
+
+	deduplicator.lookups++
+
+	mayContain := false
+	var start uint64
+	for _, filter := range deduplicator.filters {
+		if filter.mayContain(id) {
+			//The memory index no longer holds all ids of this filter, so it has to be trusted
+			if start < deduplicator.evicted {
+				return true, nil
+			}
+
+			mayContain = true
+			break
+		}
+
+		//Filters are started once the previous one is full, so the ids of a filter directly follow those of the previous one
+		start += uint64(filter.capacity)
+	}
+
+	if !mayContain {
+		deduplicator.filterNegatives++
+		return false, nil
+	}
+
+	found, err := deduplicator.indexContains(id)
+	if err == nil && !found {
+		deduplicator.falsePositives++
+	}
+
+	return found, err
+}
+
+//Add adds the id of a accepted event
+func (deduplicator *EventDeduplicator) Add(id uuid.UUID) error {
+	deduplicator.lock.Lock()
+	defer deduplicator.lock.Unlock()
+
+	if deduplicator.index == nil {
+		if _, found := deduplicator.memoryIndex[id]; found {
+			return nil
+		}
+
+		deduplicator.memoryIndex[id] = struct{}{}
+		deduplicator.memoryOrder.PushBack(id)
+
+		//The oldest ids are evicted, a duplicate of them is still detected by their filter
+		if deduplicator.memoryOrder.Len() > deduplicator.policy.MemoryIndexSize {
+			oldest := deduplicator.memoryOrder.Remove(deduplicator.memoryOrder.Front()).(uuid.UUID)
+			delete(deduplicator.memoryIndex, oldest)
+
+			deduplicator.evicted++
+			deduplicator.indexSize--
+		}
+	} else {
+		bucket, err := deduplicator.readBucket(id)
+		if err != nil {
+			return err
+		}
+
+		position, found := searchBucket(bucket, id)
+		if found {
+			return nil
+		}
+
+		updated := make([]byte, 0, len(bucket)+len(id))
+		updated = append(updated, bucket[:position]...)
+		updated = append(updated, id[:]...)
+		updated = append(updated, bucket[position:]...)
+
+		err = deduplicator.index.Put(bucketKey(id), updated)
+		if err != nil {
+			return errors.Wrap(err, "Error while writing event id index")
+		}
+	}
+
+	deduplicator.indexSize++
+	deduplicator.addToFilter(id)
+
+	return nil
+}
+
+//addToFilter adds the id to the last filter and starts a new filter once it is full, the lock must be held by the caller
+func (deduplicator *EventDeduplicator) addToFilter(id uuid.UUID) {
+	last := len(deduplicator.filters) - 1
+	if last < 0 || deduplicator.filters[last].count >= deduplicator.filters[last].capacity {
+		//The false positive rates of the filters are a geometric series which adds up to the rate of the policy
+		stage := uint(len(deduplicator.filters))
+		deduplicator.filters = append(deduplicator.filters, newBloomFilter(
+			deduplicator.policy.ExpectedEvents<<stage,
+			deduplicator.policy.FalsePositiveRate/float64(uint64(2)<<stage),
+		))
+		last++
+	}
+
+	deduplicator.filters[last].add(id)
+}
+
+//load fills the filters with the ids in the exact index
+//This includes ids of events which are no longer in the event stream because they were compacted
+func (deduplicator *EventDeduplicator) load() error {
+	deduplicator.lock.Lock()
+	defer deduplicator.lock.Unlock()
+
+	return deduplicator.index.Iterate([]byte(dedupKeyPrefix), func(key, bucket []byte) error {
+		var id uuid.UUID
+		if len(key) != len(dedupKeyPrefix)+2 || len(bucket)%len(id) != 0 {
+			return errors.New("Event id index is corrupt")
+		}
+
+		for offset := 0; offset < len(bucket); offset += len(id) {
+			copy(id[:], bucket[offset:])
+
+			deduplicator.indexSize++
+			deduplicator.addToFilter(id)
+		}
+
+		return nil
+	})
+}
+
+//Stats returns the effectiveness and memory usage of the deduplicator
+func (deduplicator *EventDeduplicator) Stats() DedupStats {
+	deduplicator.lock.Lock()
+	defer deduplicator.lock.Unlock()
+
+	stats := DedupStats{
+		Lookups:        deduplicator.lookups,
+		IndexLookups:   deduplicator.indexLookups,
+		FalsePositives: deduplicator.falsePositives,
+		Filters:        len(deduplicator.filters),
+		IndexSize:      deduplicator.indexSize,
+		IndexInMemory:  deduplicator.index == nil,
+		IndexEvicted:   deduplicator.evicted,
+	}
+
+	//The filters were consulted for these new ids, they either correctly rejected them or gave a false positive
+	newLookups := deduplicator.filterNegatives + deduplicator.falsePositives
+	if newLookups > 0 {
+		stats.FalsePositiveRate = float64(deduplicator.falsePositives) / float64(newLookups)
+	}
+
+	//A new id is a false positive if any of the filters claims to contain it
+	for _, filter := range deduplicator.filters {
+		stats.FilterMemory += len(filter.bits) * 8
+		stats.EstimatedFalsePositiveRate += filter.estimatedFalsePositiveRate()
+	}
+
+	return stats
+}
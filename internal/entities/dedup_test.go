@@ -0,0 +1,157 @@
+package entities
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/abuse-mesh/abuse-mesh-go/internal/storage/local"
+	"github.com/google/uuid"
+)
+
+func assertContains(t *testing.T, deduplicator *EventDeduplicator, id uuid.UUID, expected bool) {
+	found, err := deduplicator.Contains(id)
+	if err != nil {
+		t.Fatalf("Error while checking event id: %s", err)
+	}
+	if found != expected {
+		t.Errorf("Expected contains %s to be %t", id, expected)
+	}
+}
+
+//New filters are started once a filter is full, all of them are checked for every id
+func Test_EventDeduplicator_Filters(t *testing.T) {
+	deduplicator, err := NewEventDeduplicator(DedupPolicy{ExpectedEvents: 10, FalsePositiveRate: 0.01}, nil)
+	if err != nil {
+		t.Fatalf("Error while creating deduplicator: %s", err)
+	}
+
+	ids := make([]uuid.UUID, 100)
+	for i := range ids {
+		ids[i] = uuid.New()
+
+		err := deduplicator.Add(ids[i])
+		if err != nil {
+			t.Fatalf("Error while adding event id: %s", err)
+		}
+	}
+
+	for _, id := range ids {
+		assertContains(t, deduplicator, id, true)
+	}
+
+	for i := 0; i < 1000; i++ {
+		assertContains(t, deduplicator, uuid.New(), false)
+	}
+
+	//10 + 20 + 40 ids fit in 3 filters, the fourth holds the rest
+	stats := deduplicator.Stats()
+	if stats.Filters != 4 {
+		t.Errorf("Expected 4 filters, got %d", stats.Filters)
+	}
+	if stats.IndexSize != len(ids) || !stats.IndexInMemory {
+		t.Errorf("Expected %d ids in the memory index, got %d in memory %t", len(ids), stats.IndexSize, stats.IndexInMemory)
+	}
+	if stats.EstimatedFalsePositiveRate > 0.01 {
+		t.Errorf("Expected a false positive rate of at most 0.01, estimated %f", stats.EstimatedFalsePositiveRate)
+	}
+	if stats.Lookups != uint64(len(ids)+1000) {
+		t.Errorf("Expected %d lookups, got %d", len(ids)+1000, stats.Lookups)
+	}
+}
+
+//The memory index keeps the most recent ids, duplicates of the evicted ids are still detected by the filters
+func Test_EventDeduplicator_MemoryIndexSize(t *testing.T) {
+	deduplicator, err := NewEventDeduplicator(DedupPolicy{ExpectedEvents: 10, FalsePositiveRate: 0.01, MemoryIndexSize: 15}, nil)
+	if err != nil {
+		t.Fatalf("Error while creating deduplicator: %s", err)
+	}
+
+	ids := make([]uuid.UUID, 100)
+	for i := range ids {
+		ids[i] = uuid.New()
+
+		err := deduplicator.Add(ids[i])
+		if err != nil {
+			t.Fatalf("Error while adding event id: %s", err)
+		}
+	}
+
+	for _, id := range ids {
+		assertContains(t, deduplicator, id, true)
+	}
+
+	stats := deduplicator.Stats()
+	if stats.IndexSize != 15 || stats.IndexEvicted != 85 {
+		t.Errorf("Expected 15 ids in the memory index and 85 evicted, got %d and %d", stats.IndexSize, stats.IndexEvicted)
+	}
+
+	//Without the exact index new ids are refused at the false positive rate of the filters
+	refused := 0
+	for i := 0; i < 1000; i++ {
+		found, err := deduplicator.Contains(uuid.New())
+		if err != nil {
+			t.Fatalf("Error while checking event id: %s", err)
+		}
+		if found {
+			refused++
+		}
+	}
+	if refused > 50 {
+		t.Errorf("Expected few new ids to be refused, got %d of 1000", refused)
+	}
+}
+
+//The ids in the storage backend are loaded into the filters when the deduplicator is created again
+func Test_EventDeduplicator_StoredIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dedup")
+	if err != nil {
+		t.Fatalf("Error while creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := local.NewStorageBackend(dir, false)
+	if err != nil {
+		t.Fatalf("Error while opening storage backend: %s", err)
+	}
+	defer store.Close()
+
+	deduplicator, err := NewEventDeduplicator(DefaultDedupPolicy, store)
+	if err != nil {
+		t.Fatalf("Error while creating deduplicator: %s", err)
+	}
+
+	//Ids which share the first two bytes are stored in the same bucket
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	ids[1][0], ids[1][1] = ids[0][0], ids[0][1]
+	ids[2][0], ids[2][1] = ids[0][0], ids[0][1]
+
+	for _, id := range append(ids, ids[0]) {
+		err := deduplicator.Add(id)
+		if err != nil {
+			t.Fatalf("Error while adding event id: %s", err)
+		}
+	}
+
+	bucket, err := store.Get(bucketKey(ids[0]))
+	if err != nil {
+		t.Fatalf("Error while reading bucket: %s", err)
+	}
+	if len(bucket) != 3*16 {
+		t.Errorf("Expected 3 ids in the bucket, got %d bytes", len(bucket))
+	}
+
+	reloaded, err := NewEventDeduplicator(DefaultDedupPolicy, store)
+	if err != nil {
+		t.Fatalf("Error while loading deduplicator: %s", err)
+	}
+
+	for _, id := range ids {
+		assertContains(t, reloaded, id, true)
+	}
+	assertContains(t, reloaded, uuid.New(), false)
+
+	if stats := reloaded.Stats(); stats.IndexSize != len(ids) || stats.IndexInMemory {
+		t.Errorf("Expected %d ids in the stored index, got %d in memory %t", len(ids), stats.IndexSize, stats.IndexInMemory)
+	}
+}
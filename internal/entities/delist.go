@@ -0,0 +1,431 @@
+package entities
+
+import (
+	"context"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/utils/conv"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var (
+	//ErrUnknownReport signals that the event refers to a report which is not in the report table
+	ErrUnknownReport = errors.New("The referenced report is not known")
+
+	//ErrUnknownDelistRequest signals that the event refers to a delist request which is not in the delist table
+	ErrUnknownDelistRequest = errors.New("The referenced delist request is not known")
+
+	//ErrNotReporter signals that a delist decision was not made by the node which made the report
+	ErrNotReporter = errors.New("Only the reporter of a report can decide on its delist requests")
+
+	//ErrNotRequester signals that a event changes a delist request but is not made by the node which requested the delisting
+	ErrNotRequester = errors.New("Only the requester of a delist request can edit it")
+
+	//ErrDelistRequestExpired signals that a delist request was accepted after it expired
+	ErrDelistRequestExpired = errors.New("The delist request expired before it was accepted")
+)
+
+//DelistState is the state of a delist request in the delisting workflow
+type DelistState int
+
+const (
+	//DelistStateRequested is the initial state, the listed party has asked the reporter to delist the report
+	DelistStateRequested DelistState = iota
+
+	//DelistStateAccepted means the reporter has accepted the request and the report is delisted
+	DelistStateAccepted
+
+	//DelistStateRejected means the reporter has rejected the request, the report stays listed
+	DelistStateRejected
+
+	//DelistStateExpired means the reporter didn't respond to the request in time
+	DelistStateExpired
+)
+
+func (state DelistState) String() string {
+	switch state {
+	case DelistStateRequested:
+		return "requested"
+	case DelistStateAccepted:
+		return "accepted"
+	case DelistStateRejected:
+		return "rejected"
+	case DelistStateExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+//A DelistAcceptance is the confirmation of a reporter that a report may be delisted
+type DelistAcceptance struct {
+	UUID          uuid.UUID
+	DelistRequest uuid.UUID
+	//The UUID of the node which accepted the request, always the reporter of the report
+	Acceptor uuid.UUID
+	//The signed timestamp of the acceptance event
+	AcceptedAt time.Time
+}
+
+//DelistAcceptanceFromProtobuf creates a delist acceptance object from the protobuf stub
+//AcceptedAt is not part of the message, it is the time at which the event was signed
+func DelistAcceptanceFromProtobuf(protobufAcceptance *abusemesh.DelistAcceptance) (DelistAcceptance, error) {
+	acceptanceID, err := conv.AuuidToGuuid(protobufAcceptance.GetUuid())
+	if err != nil {
+		return DelistAcceptance{}, errors.Wrap(err, "Delist acceptance ID invalid")
+	}
+
+	requestID, err := conv.AuuidToGuuid(protobufAcceptance.GetDelistRequest())
+	if err != nil {
+		return DelistAcceptance{}, errors.Wrap(err, "Delist request ID invalid")
+	}
+
+	acceptorID, err := conv.AuuidToGuuid(protobufAcceptance.GetAcceptor())
+	if err != nil {
+		return DelistAcceptance{}, errors.Wrap(err, "Acceptor ID invalid")
+	}
+
+	return DelistAcceptance{
+		UUID:          acceptanceID,
+		DelistRequest: requestID,
+		Acceptor:      acceptorID,
+	}, nil
+}
+
+//A DelistRequest is a request of a listed party to remove a report
+type DelistRequest struct {
+	UUID uuid.UUID
+	//The UUID of the report which should be delisted
+	Report uuid.UUID
+	//The UUID of the node which requested the delisting
+	Requester uuid.UUID
+	Reason    string
+	State     DelistState
+	//The signed timestamp of the event which created the request
+	RequestedAt time.Time
+	//Acceptance is set when the request has been accepted by the reporter
+	Acceptance *DelistAcceptance
+}
+
+//DelistRequestFromProtobuf creates a delist request object from the protobuf stub
+//RequestedAt is not part of the message, it is the time at which the event was signed
+func DelistRequestFromProtobuf(protobufRequest *abusemesh.DelistRequest) (DelistRequest, error) {
+	requestID, err := conv.AuuidToGuuid(protobufRequest.GetUuid())
+	if err != nil {
+		return DelistRequest{}, errors.Wrap(err, "Delist request ID invalid")
+	}
+
+	reportID, err := conv.AuuidToGuuid(protobufRequest.GetReport())
+	if err != nil {
+		return DelistRequest{}, errors.Wrap(err, "Report ID invalid")
+	}
+
+	requesterID, err := conv.AuuidToGuuid(protobufRequest.GetRequester())
+	if err != nil {
+		return DelistRequest{}, errors.Wrap(err, "Requester ID invalid")
+	}
+
+	return DelistRequest{
+		UUID:      requestID,
+		Report:    reportID,
+		Requester: requesterID,
+		Reason:    protobufRequest.GetReason(),
+		State:     DelistStateRequested,
+	}, nil
+}
+
+//validateDelistRequest checks that a delist request refers to a known report and is signed by the right node
+//New requests and edits are signed by the requester, a deletion is the rejection of the request by the reporter
+func validateDelistRequest(event *GenericEvent, tableSet *TableSet, request *abusemesh.DelistRequest) error {
+	reportID, err := conv.AuuidToGuuid(request.GetReport())
+	if err != nil {
+		return errors.Wrap(err, "Report ID invalid")
+	}
+
+	report := tableSet.getReport(reportID)
+	if report == nil {
+		return ErrUnknownReport
+	}
+
+	if event.UpdateType == abusemesh.TableEventType_TABLE_UPDATE_DELETE {
+		return event.verifySignedBy(tableSet, &abusemesh.UUID{Uuid: report.Reporter.String()})
+	}
+
+	return event.verifySignedBy(tableSet, request.GetRequester())
+}
+
+//validateDelistAcceptance checks that a delist acceptance refers to a known delist request
+//and that it is made and signed by the reporter of the report which is to be delisted
+func validateDelistAcceptance(event *GenericEvent, tableSet *TableSet, acceptance *abusemesh.DelistAcceptance) error {
+	requestID, err := conv.AuuidToGuuid(acceptance.GetDelistRequest())
+	if err != nil {
+		return errors.Wrap(err, "Delist request ID invalid")
+	}
+
+	request := tableSet.getDelistRequest(requestID)
+	if request == nil {
+		return ErrUnknownDelistRequest
+	}
+
+	report := tableSet.getReport(request.Report)
+	if report == nil {
+		return ErrUnknownReport
+	}
+
+	acceptorID, err := conv.AuuidToGuuid(acceptance.GetAcceptor())
+	if err != nil {
+		return errors.Wrap(err, "Acceptor ID invalid")
+	}
+
+	if acceptorID != report.Reporter {
+		return ErrNotReporter
+	}
+
+	reporter := tableSet.getNode(report.Reporter)
+	if reporter == nil {
+		return ErrUnknownSigner
+	}
+
+	return event.verifyOwnedBy(reporter, ErrNotReporter)
+}
+
+//A DelistTable holds the current state of all delist requests and their acceptances known to the current node
+type DelistTable struct {
+	Entities map[uuid.UUID]DelistRequest
+
+	//Requests which are not decided on within this duration expire, the duration counts from the signed timestamp
+	//of the request so all nodes agree on whether a acceptance was made in time
+	ExpireAfter time.Duration
+
+	//shared is true if Entities is part of a snapshot and has to be copied before it is written
+	shared bool
+
+	//changes records the changes to Entities
+	changes *changeSet
+}
+
+//expired returns true if a decision made at the given time is too late for the request
+func (table *DelistTable) expired(request DelistRequest, decidedAt time.Time) bool {
+	return table.ExpireAfter > 0 && decidedAt.Sub(request.RequestedAt) > table.ExpireAfter
+}
+
+//expire moves all undecided requests which are older than ExpireAfter to the expired state
+//It is called periodically by the TableSet, a acceptance which was made in time is still applied afterwards
+func (table *DelistTable) expire(now time.Time) {
+	if table.ExpireAfter <= 0 {
+		return
+	}
+
+	var expired []DelistRequest
+	for _, request := range table.Entities {
+		if request.State == DelistStateRequested && table.expired(request, now) {
+			request.State = DelistStateExpired
+			expired = append(expired, request)
+		}
+	}
+
+	if len(expired) == 0 {
+		return
+	}
+
+	for _, request := range expired {
+		table.put(request)
+	}
+}
+
+//put adds or replaces the delist request and records the change
+func (table *DelistTable) put(request DelistRequest) {
+	table.own()
+
+	if existing, found := table.Entities[request.UUID]; found {
+		table.changes.record(TableDelistRequests, request.UUID.String(), existing, request)
+	} else {
+		table.changes.record(TableDelistRequests, request.UUID.String(), nil, request)
+	}
+
+	table.Entities[request.UUID] = request
+}
+
+func (table *DelistTable) handleDelistRequestEvent(
+	eventType abusemesh.TableEventType,
+	entity *abusemesh.TableEvent_DelistRequests,
+	signedAt time.Time,
+) error {
+	switch eventType {
+	case abusemesh.TableEventType_TABLE_UPDATE_NEW:
+		request, err := DelistRequestFromProtobuf(entity.DelistRequests)
+		if err != nil {
+			return err
+		}
+
+		request.RequestedAt = signedAt
+		table.put(request)
+
+	case abusemesh.TableEventType_TABLE_UPDATE_EDIT:
+		request, err := DelistRequestFromProtobuf(entity.DelistRequests)
+		if err != nil {
+			return err
+		}
+
+		request.RequestedAt = signedAt
+
+		//The requester may only change the details of the request, not the report or the state of the workflow
+		if existing, found := table.Entities[request.UUID]; found {
+			request.Report = existing.Report
+			request.State = existing.State
+			request.RequestedAt = existing.RequestedAt
+			request.Acceptance = existing.Acceptance
+		}
+
+		table.put(request)
+
+	case abusemesh.TableEventType_TABLE_UPDATE_DELETE:
+		//A deletion is signed by the reporter and rejects the request, the request is kept for reference
+		requestID, err := conv.AuuidToGuuid(entity.DelistRequests.GetUuid())
+		if err != nil {
+			return err
+		}
+
+		request, found := table.Entities[requestID]
+		if !found {
+			return nil
+		}
+
+		//The deletion was validated against the report it names, which has to be the report of the request
+		reportID, err := conv.AuuidToGuuid(entity.DelistRequests.GetReport())
+		if err != nil {
+			return err
+		}
+
+		if reportID != request.Report {
+			return errors.Wrapf(ErrNotReporter, "Delist request '%s' is about report '%s'", requestID, request.Report)
+		}
+
+		if request.State != DelistStateAccepted {
+			request.State = DelistStateRejected
+			table.put(request)
+		}
+
+	default:
+		return errors.Errorf("Unknown abusemesh.TableEventType type '%T'", eventType)
+	}
+
+	return nil
+}
+
+func (table *DelistTable) handleDelistAcceptanceEvent(
+	eventType abusemesh.TableEventType,
+	entity *abusemesh.TableEvent_DelistAcceptance,
+	signedAt time.Time,
+	reportTable *ReportTable,
+) error {
+	acceptance, err := DelistAcceptanceFromProtobuf(entity.DelistAcceptance)
+	if err != nil {
+		return err
+	}
+
+	acceptance.AcceptedAt = signedAt
+
+	request, found := table.Entities[acceptance.DelistRequest]
+	if !found {
+		return ErrUnknownDelistRequest
+	}
+
+	report, found := reportTable.Entities[request.Report]
+	if !found {
+		return ErrUnknownReport
+	}
+
+	if acceptance.Acceptor != report.Reporter {
+		return ErrNotReporter
+	}
+
+	switch eventType {
+	case abusemesh.TableEventType_TABLE_UPDATE_NEW, abusemesh.TableEventType_TABLE_UPDATE_EDIT:
+		//The request may already be marked as expired if the acceptance arrived late, the signed times decide
+		if table.expired(request, signedAt) {
+			return errors.Wrapf(ErrDelistRequestExpired, "Delist request '%s' was accepted at %s", request.UUID, signedAt.UTC().Format(time.RFC3339))
+		}
+
+		request.State = DelistStateAccepted
+		request.Acceptance = &acceptance
+		report.Delisted = true
+
+	case abusemesh.TableEventType_TABLE_UPDATE_DELETE:
+		//The reporter withdrew the acceptance, the report is listed again
+		request.State = DelistStateRejected
+		request.Acceptance = nil
+		report.Delisted = false
+
+	default:
+		return errors.Errorf("Unknown abusemesh.TableEventType type '%T'", eventType)
+	}
+
+	table.put(request)
+	reportTable.put(report)
+
+	return nil
+}
+
+//GetDelistRequestRequest can be used to request a specific delist request from the delist table
+type GetDelistRequestRequest struct {
+	ResponseChan    chan<- *DelistRequest
+	DelistRequestID uuid.UUID
+}
+
+//Process processes the request and sends a pointer to the delist request on the ResponseChan or nil if it was not found
+func (req *GetDelistRequestRequest) Process(tables *TableSet) error {
+	request, found := tables.delistTable.Entities[req.DelistRequestID]
+	if found {
+		req.ResponseChan <- &request
+	} else {
+		req.ResponseChan <- nil
+	}
+
+	return nil
+}
+
+//getDelistRequest queries the delist table for the delist request with the given id, nil is returned if it is unknown
+//NOTE: must not be called from the goroutine of the TableSet since it waits for the request to be processed
+func (set *TableSet) getDelistRequest(requestID uuid.UUID) *DelistRequest {
+	responseChan := make(chan *DelistRequest, 1)
+
+	set.Channel <- &GetDelistRequestRequest{
+		ResponseChan:    responseChan,
+		DelistRequestID: requestID,
+	}
+
+	return <-responseChan
+}
+
+//GetAllDelistRequestsRequest can be used to request all delist requests from the delist table
+type GetAllDelistRequestsRequest struct {
+	//ResponseChan is the channel over which multiple delist requests will be sent
+	ResponseChan chan<- DelistRequest
+
+	//Context can be used to cancel the sending of delist requests
+	Context context.Context
+}
+
+//Process processes the request and sends all delist requests on the ResponseChan, the channel is closed when all are sent
+//The delist requests are sent from a snapshot in a separate goroutine, so a slow requester doesn't block the TableSet
+func (req *GetAllDelistRequestsRequest) Process(tables *TableSet) error {
+	snapshot := tables.takeSnapshot()
+
+	go func() {
+		defer close(req.ResponseChan)
+
+		for _, request := range snapshot.DelistRequests {
+			select {
+			case req.ResponseChan <- request:
+			//If the requester no longer wishes to receive delist requests we return
+			case <-req.Context.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package entities
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+//newDelistTestTables returns tables with a report and a delist request for it which was signed at requestedAt
+func newDelistTestTables(t *testing.T, requestedAt time.Time) (*TableSet, Report, uuid.UUID) {
+	tableSet := NewTableSet(0)
+	tableSet.delistTable.ExpireAfter = time.Hour
+
+	report := Report{
+		UUID:     uuid.New(),
+		Reporter: uuid.New(),
+		Prefix:   net.IPNet{IP: net.ParseIP("198.51.100.1").To4(), Mask: net.CIDRMask(32, 32)},
+	}
+	tableSet.reportTable.put(report)
+
+	requestID := uuid.New()
+	err := tableSet.delistTable.handleDelistRequestEvent(abusemesh.TableEventType_TABLE_UPDATE_NEW, &abusemesh.TableEvent_DelistRequests{
+		DelistRequests: &abusemesh.DelistRequest{
+			Uuid:      &abusemesh.UUID{Uuid: requestID.String()},
+			Report:    &abusemesh.UUID{Uuid: report.UUID.String()},
+			Requester: &abusemesh.UUID{Uuid: uuid.New().String()},
+		},
+	}, requestedAt)
+	if err != nil {
+		t.Fatalf("Error while adding delist request: %s", err)
+	}
+
+	return tableSet, report, requestID
+}
+
+func acceptDelistRequest(tableSet *TableSet, requestID uuid.UUID, acceptor uuid.UUID, acceptedAt time.Time) error {
+	return tableSet.delistTable.handleDelistAcceptanceEvent(abusemesh.TableEventType_TABLE_UPDATE_NEW, &abusemesh.TableEvent_DelistAcceptance{
+		DelistAcceptance: &abusemesh.DelistAcceptance{
+			Uuid:          &abusemesh.UUID{Uuid: uuid.New().String()},
+			DelistRequest: &abusemesh.UUID{Uuid: requestID.String()},
+			Acceptor:      &abusemesh.UUID{Uuid: acceptor.String()},
+		},
+	}, acceptedAt, &tableSet.reportTable)
+}
+
+//The expiry of a delist request counts from its signed timestamp, not from the time it was received
+func Test_DelistTable_ExpiresOnSignedTime(t *testing.T) {
+	requestedAt := time.Now().Add(-2 * time.Hour)
+	tableSet, _, requestID := newDelistTestTables(t, requestedAt)
+
+	request := tableSet.delistTable.Entities[requestID]
+	if !request.RequestedAt.Equal(requestedAt) {
+		t.Errorf("Expected request to be requested at %s, got %s", requestedAt, request.RequestedAt)
+	}
+
+	//Taking a snapshot doesn't change the tables
+	tableSet.takeSnapshot()
+	if state := tableSet.delistTable.Entities[requestID].State; state != DelistStateRequested {
+		t.Errorf("Expected request to stay requested until the maintenance, got %s", state)
+	}
+
+	tableSet.maintain(time.Now())
+	if state := tableSet.delistTable.Entities[requestID].State; state != DelistStateExpired {
+		t.Errorf("Expected request to be expired, got %s", state)
+	}
+}
+
+func Test_DelistTable_Acceptance(t *testing.T) {
+	requestedAt := time.Now().Add(-2 * time.Hour)
+
+	tests := []struct {
+		name       string
+		acceptedAt time.Time
+		otherNode  bool
+		err        error
+		state      DelistState
+	}{
+		{
+			name:       "in time",
+			acceptedAt: requestedAt.Add(time.Minute),
+			state:      DelistStateAccepted,
+		},
+		{
+			name:       "after expiry",
+			acceptedAt: requestedAt.Add(time.Hour + time.Minute),
+			err:        ErrDelistRequestExpired,
+			state:      DelistStateExpired,
+		},
+		{
+			name:       "not by the reporter",
+			acceptedAt: requestedAt.Add(time.Minute),
+			otherNode:  true,
+			err:        ErrNotReporter,
+			state:      DelistStateExpired,
+		},
+	}
+
+	for _, test := range tests {
+		tableSet, report, requestID := newDelistTestTables(t, requestedAt)
+
+		//The request already expired locally, a acceptance which was made in time still arrives late
+		tableSet.maintain(time.Now())
+
+		acceptor := report.Reporter
+		if test.otherNode {
+			acceptor = uuid.New()
+		}
+
+		err := acceptDelistRequest(tableSet, requestID, acceptor, test.acceptedAt)
+		if errors.Cause(err) != test.err {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.err, err)
+		}
+
+		request := tableSet.delistTable.Entities[requestID]
+		if request.State != test.state {
+			t.Errorf("%s: expected state %s, got %s", test.name, test.state, request.State)
+		}
+
+		delisted := tableSet.reportTable.Entities[report.UUID].Delisted
+		if delisted != (test.state == DelistStateAccepted) {
+			t.Errorf("%s: expected report delisted to be %t", test.name, !delisted)
+		}
+
+		if test.state == DelistStateAccepted && !request.Acceptance.AcceptedAt.Equal(test.acceptedAt) {
+			t.Errorf("%s: expected acceptance at %s, got %s", test.name, test.acceptedAt, request.Acceptance.AcceptedAt)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package entities
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/storage"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/utils/conv"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+//equivocationKeyPrefix is the prefix of the keys of the proofs in the storage backend
+const equivocationKeyPrefix = "equivocation/"
+
+//A EquivocationProof proves that a node signed two conflicting events
+//Both events are kept with their signatures, so anyone who has the key of the author can verify the proof
+type EquivocationProof struct {
+	//The node which signed both events
+	Author uuid.UUID
+
+	//The key of the entity the second event is about
+	Entity string
+
+	//The event which was seen first and the event which conflicts with it
+	First  *GenericEvent
+	Second *GenericEvent
+
+	DetectedAt time.Time
+}
+
+//A seenEvent is a event which later copies with the same id are compared with
+type seenEvent struct {
+	event   *GenericEvent
+	author  uuid.UUID
+	payload [sha256.Size]byte
+}
+
+//A EquivocationDetector detects nodes which sign conflicting events
+//Events are ordered on their version, the signed timestamp and the event id, so two events with the same id are
+//the same version of a entity. Two events conflict if they have the same id and are signed by the same author but
+//have a different content: the author has sent different versions of the entity to different parts of the mesh.
+//A author which signs several events about a entity within the same second gives every event its own id, which is not a conflict.
+//
+//The events are kept for the horizon, counted from their signed timestamp, so the memory used is proportional
+//to the amount of events within the horizon. A conflicting copy which arrives after the horizon is not detected.
+//The proofs are kept in the storage backend, if any, so a author stays flagged after a restart
+type EquivocationDetector struct {
+	horizon time.Duration
+	seen    map[uuid.UUID]seenEvent
+
+	//The time at which events older than the horizon are removed next
+	nextPrune time.Time
+
+	//The storage of the proofs, if nil the proofs are only kept in memory
+	store storage.StorageBackend
+
+	//The proofs in the order they were detected and their fingerprints to ignore repeated conflicts
+	proofs       []EquivocationProof
+	fingerprints map[[sha256.Size]byte]struct{}
+
+	lock sync.Mutex
+}
+
+//NewEquivocationDetector creates a detector which compares the events within the horizon
+//The proofs are stored in the store and the proofs stored earlier are loaded, if store is nil they are kept in memory
+func NewEquivocationDetector(horizon time.Duration, store storage.StorageBackend) (*EquivocationDetector, error) {
+	detector := &EquivocationDetector{
+		horizon:      horizon,
+		seen:         make(map[uuid.UUID]seenEvent),
+		store:        store,
+		fingerprints: make(map[[sha256.Size]byte]struct{}),
+	}
+
+	if store == nil {
+		return detector, nil
+	}
+
+	err := store.Iterate([]byte(equivocationKeyPrefix), func(key, value []byte) error {
+		proof, err := decodeEquivocationProof(value)
+		if err != nil {
+			return errors.Wrapf(err, "Error while decoding equivocation proof '%s'", key)
+		}
+
+		var fingerprint [sha256.Size]byte
+		copy(fingerprint[:], key[len(equivocationKeyPrefix):])
+
+		detector.fingerprints[fingerprint] = struct{}{}
+		detector.proofs = append(detector.proofs, proof)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	//The proofs are stored by fingerprint, they are listed in the order they were detected
+	sort.SliceStable(detector.proofs, func(i, j int) bool {
+		return detector.proofs[i].DetectedAt.Before(detector.proofs[j].DetectedAt)
+	})
+
+	return detector, nil
+}
+
+//Check compares a validated event with the earlier event with the same id
+//Returns a proof and true if the event conflicts with the earlier event and the conflict was not detected before
+func (detector *EquivocationDetector) Check(event *GenericEvent) (EquivocationProof, bool) {
+	author, found := eventAuthor(event)
+	if !found {
+		return EquivocationProof{}, false
+	}
+
+	now := time.Now()
+	if now.Sub(time.Unix(event.GetTimestamp(), 0)) > detector.horizon {
+		return EquivocationProof{}, false
+	}
+
+	payload, err := event.signedPayload()
+	if err != nil {
+		logrus.WithError(err).Error("Error while encoding event to detect equivocation")
+		return EquivocationProof{}, false
+	}
+
+	current := seenEvent{
+		event:   event,
+		author:  author,
+		payload: sha256.Sum256(payload),
+	}
+
+	detector.lock.Lock()
+	defer detector.lock.Unlock()
+
+	detector.prune(now)
+
+	previous, found := detector.seen[event.GetID()]
+	if !found {
+		detector.seen[event.GetID()] = current
+		return EquivocationProof{}, false
+	}
+
+	//A copy of the same event is not a conflict, even if it was signed again.
+	//A event of a other author which reuses the id is refused by the deduplication, but it doesn't prove anything about the first author
+	if previous.payload == current.payload || previous.author != current.author {
+		return EquivocationProof{}, false
+	}
+
+	fingerprint := conflictFingerprint(previous.payload, current.payload)
+	if _, found := detector.fingerprints[fingerprint]; found {
+		return EquivocationProof{}, false
+	}
+
+	proof := EquivocationProof{
+		Author:     author,
+		Entity:     eventEntityKey(event),
+		First:      previous.event,
+		Second:     event,
+		DetectedAt: now,
+	}
+
+	if detector.store != nil {
+		value, err := encodeEquivocationProof(proof)
+		if err == nil {
+			err = detector.store.Put(append([]byte(equivocationKeyPrefix), fingerprint[:]...), value)
+		}
+		if err != nil {
+			logrus.WithError(err).Error("Error while storing equivocation proof")
+		}
+	}
+
+	detector.fingerprints[fingerprint] = struct{}{}
+	detector.proofs = append(detector.proofs, proof)
+
+	return proof, true
+}
+
+//prune removes the events which are older than the horizon, at most once per tenth of the horizon
+//The lock must be held by the caller
+func (detector *EquivocationDetector) prune(now time.Time) {
+	if now.Before(detector.nextPrune) {
+		return
+	}
+	detector.nextPrune = now.Add(detector.horizon / 10)
+
+	for eventID, seen := range detector.seen {
+		if now.Sub(time.Unix(seen.event.GetTimestamp(), 0)) > detector.horizon {
+			delete(detector.seen, eventID)
+		}
+	}
+}
+
+//conflictFingerprint returns the same fingerprint for two payload hashes regardless of their order
+func conflictFingerprint(hashA, hashB [sha256.Size]byte) [sha256.Size]byte {
+	if bytes.Compare(hashA[:], hashB[:]) > 0 {
+		hashA, hashB = hashB, hashA
+	}
+
+	return sha256.Sum256(append(hashA[:], hashB[:]...))
+}
+
+//Proofs returns all detected equivocations, the most recently detected last
+func (detector *EquivocationDetector) Proofs() []EquivocationProof {
+	detector.lock.Lock()
+	defer detector.lock.Unlock()
+
+	return append([]EquivocationProof(nil), detector.proofs...)
+}
+
+//encodeEquivocationProof encodes the proof as the detection time in unix nanoseconds,
+//the 4 byte length of the first event and both protobuf encoded events. The author and entity are derived from the events
+func encodeEquivocationProof(proof EquivocationProof) ([]byte, error) {
+	first, err := proto.Marshal(&proof.First.TableEvent)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while encoding event")
+	}
+
+	second, err := proto.Marshal(&proof.Second.TableEvent)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while encoding event")
+	}
+
+	value := make([]byte, 12, 12+len(first)+len(second))
+	binary.BigEndian.PutUint64(value[0:8], uint64(proof.DetectedAt.UnixNano()))
+	binary.BigEndian.PutUint32(value[8:12], uint32(len(first)))
+	value = append(value, first...)
+	value = append(value, second...)
+
+	return value, nil
+}
+
+func decodeEquivocationProof(value []byte) (EquivocationProof, error) {
+	if len(value) < 12 || uint64(len(value)-12) < uint64(binary.BigEndian.Uint32(value[8:12])) {
+		return EquivocationProof{}, errors.New("Equivocation proof is corrupt")
+	}
+
+	firstLength := binary.BigEndian.Uint32(value[8:12])
+
+	first, second := &GenericEvent{}, &GenericEvent{}
+
+	err := proto.Unmarshal(value[12:12+firstLength], &first.TableEvent)
+	if err != nil {
+		return EquivocationProof{}, errors.Wrap(err, "Error while decoding event")
+	}
+
+	err = proto.Unmarshal(value[12+firstLength:], &second.TableEvent)
+	if err != nil {
+		return EquivocationProof{}, errors.Wrap(err, "Error while decoding event")
+	}
+
+	author, found := eventAuthor(second)
+	if !found {
+		return EquivocationProof{}, errors.New("Equivocation proof has no author")
+	}
+
+	return EquivocationProof{
+		Author:     author,
+		Entity:     eventEntityKey(second),
+		First:      first,
+		Second:     second,
+		DetectedAt: time.Unix(0, int64(binary.BigEndian.Uint64(value[0:8]))),
+	}, nil
+}
+
+//eventAuthor returns the node which signed the event according to the validation rules of its entity
+//Returns false if the author can't be derived from the event itself
+func eventAuthor(event *GenericEvent) (uuid.UUID, bool) {
+	var authorID *abusemesh.UUID
+
+	switch e := event.GetTableEntity().(type) {
+	case *abusemesh.TableEvent_Node:
+		authorID = e.Node.GetUuid()
+	case *abusemesh.TableEvent_Report:
+		authorID = e.Report.GetReporter()
+	case *abusemesh.TableEvent_ReportConfirmation:
+		authorID = e.ReportConfirmation.GetConfirmer()
+	case *abusemesh.TableEvent_DelistRequests:
+		//The deletion of a delist request is signed by the reporter, which is not part of the event
+		if event.UpdateType == abusemesh.TableEventType_TABLE_UPDATE_DELETE {
+			return uuid.UUID{}, false
+		}
+		authorID = e.DelistRequests.GetRequester()
+	case *abusemesh.TableEvent_DelistAcceptance:
+		authorID = e.DelistAcceptance.GetAcceptor()
+	case *abusemesh.TableEvent_Neighbor:
+		authorID = e.Neighbor.GetNode()
+	default:
+		return uuid.UUID{}, false
+	}
+
+	author, err := conv.AuuidToGuuid(authorID)
+	if err != nil {
+		return uuid.UUID{}, false
+	}
+
+	return author, true
+}
+
+//detectEquivocation checks a validated event for conflicts with earlier events with the same id
+//A detected equivocation is logged and the author is flagged in the node table
+func (stream *inMemoryEventStream) detectEquivocation(event Event) {
+	genericEvent, ok := event.(*GenericEvent)
+	if !ok || stream.equivocations == nil {
+		return
+	}
+
+	proof, found := stream.equivocations.Check(genericEvent)
+	if !found {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"author":          proof.Author.String(),
+		"entity":          proof.Entity,
+		"first-event-id":  proof.First.GetID().String(),
+		"second-event-id": proof.Second.GetID().String(),
+		"timestamp":       proof.Second.GetTimestamp(),
+	}).Error("Node signed conflicting events with the same id")
+
+	if stream.tableSet != nil {
+		stream.tableSet.Channel <- &FlagEquivocationRequest{
+			Proof: proof,
+		}
+	}
+}
+
+//flagEquivocators flags the authors of the equivocations which were detected before the stream started
+func (stream *inMemoryEventStream) flagEquivocators() {
+	if stream.equivocations == nil || stream.tableSet == nil {
+		return
+	}
+
+	for _, proof := range stream.equivocations.Proofs() {
+		stream.tableSet.Channel <- &FlagEquivocationRequest{
+			Proof: proof,
+		}
+	}
+}
+
+func (stream *inMemoryEventStream) GetEquivocations() []EquivocationProof {
+	if stream.equivocations == nil {
+		return nil
+	}
+
+	return stream.equivocations.Proofs()
+}
+
+//FlagEquivocationRequest flags the author of a equivocation in the node table
+type FlagEquivocationRequest struct {
+	Proof EquivocationProof
+}
+
+//Process processes the request and flags the author, the author stays flagged when its node is changed or announced later
+func (req *FlagEquivocationRequest) Process(tables *TableSet) error {
+	tables.nodeTable.flagEquivocation(req.Proof.Author)
+
+	return nil
+}
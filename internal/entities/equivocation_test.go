@@ -0,0 +1,175 @@
+package entities
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/storage/local"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+)
+
+//resigned returns a copy of the event with the same id about a other address, signed again by the node
+func resigned(t *testing.T, node testNode, event *GenericEvent, address string) *GenericEvent {
+	copied := &GenericEvent{TableEvent: *proto.Clone(&event.TableEvent).(*abusemesh.TableEvent)}
+	copied.GetReport().IpAddress = &abusemesh.IPAddress{Address: address}
+
+	err := copied.sign(node.key)
+	if err != nil {
+		t.Fatalf("Error while signing event: %s", err)
+	}
+
+	return copied
+}
+
+func newTestEquivocationDetector(t *testing.T) *EquivocationDetector {
+	detector, err := NewEquivocationDetector(time.Hour, nil)
+	if err != nil {
+		t.Fatalf("Error while creating equivocation detector: %s", err)
+	}
+
+	return detector
+}
+
+//A honest node which creates and edits a report within the same second gives both events their own id
+func Test_EquivocationDetector_EditInSameSecond(t *testing.T) {
+	detector := newTestEquivocationDetector(t)
+	node := newTestNode(t, "node-a")
+	reportID := uuid.New()
+
+	for _, event := range []*GenericEvent{
+		node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, reportID, "198.51.100.1"),
+		node.report(t, abusemesh.TableEventType_TABLE_UPDATE_EDIT, reportID, "198.51.100.2"),
+	} {
+		if _, found := detector.Check(event); found {
+			t.Fatal("Expected no equivocation for a edit of the report")
+		}
+	}
+}
+
+func Test_EquivocationDetector_ResignedEvent(t *testing.T) {
+	detector := newTestEquivocationDetector(t)
+	node := newTestNode(t, "node-a")
+
+	first := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, uuid.New(), "198.51.100.1")
+	second := resigned(t, node, first, "198.51.100.2")
+
+	if _, found := detector.Check(first); found {
+		t.Fatal("Expected no equivocation for the first event")
+	}
+
+	//A identical copy is not a conflict
+	if _, found := detector.Check(first); found {
+		t.Fatal("Expected no equivocation for a copy of the event")
+	}
+
+	proof, found := detector.Check(second)
+	if !found {
+		t.Fatal("Expected equivocation for a event with the same id and a other content")
+	}
+	if proof.Author != node.id || proof.First != first || proof.Second != second {
+		t.Errorf("Expected proof of %s about both events, got %+v", node.id, proof)
+	}
+
+	//The same conflict is only reported once
+	if _, found := detector.Check(second); found {
+		t.Error("Expected the equivocation to be reported once")
+	}
+	if proofs := detector.Proofs(); len(proofs) != 1 {
+		t.Errorf("Expected 1 proof, got %d", len(proofs))
+	}
+}
+
+//A conflict about a older version of a entity is detected after a newer version has been seen
+func Test_EquivocationDetector_OlderConflict(t *testing.T) {
+	detector := newTestEquivocationDetector(t)
+	node := newTestNode(t, "node-a")
+	reportID := uuid.New()
+
+	older := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, reportID, "198.51.100.1")
+	newer := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_EDIT, reportID, "198.51.100.2")
+
+	detector.Check(older)
+	detector.Check(newer)
+
+	if _, found := detector.Check(resigned(t, node, older, "198.51.100.3")); !found {
+		t.Error("Expected equivocation for the older version of the report")
+	}
+}
+
+//Events older than the horizon are forgotten
+func Test_EquivocationDetector_Horizon(t *testing.T) {
+	detector := newTestEquivocationDetector(t)
+	node := newTestNode(t, "node-a")
+
+	event := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, uuid.New(), "198.51.100.1")
+	event.Timestamp = time.Now().Add(-2 * time.Hour).Unix()
+	err := event.sign(node.key)
+	if err != nil {
+		t.Fatalf("Error while signing event: %s", err)
+	}
+
+	detector.Check(event)
+	if _, found := detector.Check(resigned(t, node, event, "198.51.100.2")); found {
+		t.Error("Expected no equivocation for events older than the horizon")
+	}
+	if len(detector.seen) != 0 {
+		t.Errorf("Expected no events to be kept, got %d", len(detector.seen))
+	}
+}
+
+//The proofs are loaded from the storage backend after a restart
+func Test_EquivocationDetector_Persistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "equivocation")
+	if err != nil {
+		t.Fatalf("Error while creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := local.NewStorageBackend(dir, false)
+	if err != nil {
+		t.Fatalf("Error while opening storage backend: %s", err)
+	}
+	defer store.Close()
+
+	node := newTestNode(t, "node-a")
+	first := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, uuid.New(), "198.51.100.1")
+	second := resigned(t, node, first, "198.51.100.2")
+
+	detector, err := NewEquivocationDetector(time.Hour, store)
+	if err != nil {
+		t.Fatalf("Error while creating equivocation detector: %s", err)
+	}
+	detector.Check(first)
+	detected, found := detector.Check(second)
+	if !found {
+		t.Fatal("Expected equivocation for a event with the same id and a other content")
+	}
+
+	restarted, err := NewEquivocationDetector(time.Hour, store)
+	if err != nil {
+		t.Fatalf("Error while loading equivocation proofs: %s", err)
+	}
+
+	proofs := restarted.Proofs()
+	if len(proofs) != 1 {
+		t.Fatalf("Expected 1 proof after restart, got %d", len(proofs))
+	}
+
+	proof := proofs[0]
+	if proof.Author != node.id || proof.Entity != detected.Entity || !proof.DetectedAt.Equal(detected.DetectedAt) {
+		t.Errorf("Expected proof %+v, got %+v", detected, proof)
+	}
+	if !proto.Equal(&proof.First.TableEvent, &first.TableEvent) || !proto.Equal(&proof.Second.TableEvent, &second.TableEvent) {
+		t.Error("Expected the stored proof to contain both signed events")
+	}
+
+	//A conflict which was detected before the restart is not reported again
+	restarted.Check(first)
+	if _, found := restarted.Check(second); found {
+		t.Error("Expected the equivocation to be reported once")
+	}
+}
@@ -3,6 +3,7 @@ package entities
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
 	"github.com/abuse-mesh/abuse-mesh-go/internal/utils/conv"
@@ -14,6 +15,18 @@ import (
 var (
 	//ErrEventEntityEmpty signals that the GRPC message had no TableEntity
 	ErrEventEntityEmpty = errors.New("The event contains no entity data")
+
+	//ErrUnknownSigner signals that the node which should have signed the event is not in the node table
+	ErrUnknownSigner = errors.New("The signer of the event is not a known node")
+
+	//ErrSignatureInvalid signals that the signature is missing, malformed or not made with the key of the signer
+	ErrSignatureInvalid = errors.New("The signature of the event is invalid")
+
+	//ErrPayloadTampered signals that the signature was made by the signer but doesn't match the contents of the event
+	ErrPayloadTampered = errors.New("The event payload doesn't match the signature")
+
+	//ErrOffsetOutOfRange signals that a offset was requested which is beyond the end of the event stream
+	ErrOffsetOutOfRange = errors.New("The offset is beyond the end of the event stream")
 )
 
 //Event is a change of data in a table
@@ -42,30 +55,104 @@ func (event *GenericEvent) Validate(tableSet *TableSet) (bool, error) {
 		return false, errors.Wrap(err, "Event ID invalid")
 	}
 
+	err = checkClockSkew(event, time.Now())
+	if err != nil {
+		return false, err
+	}
+
 	switch e := entity.(type) {
 	case *abusemesh.TableEvent_Node:
+		if e.Node == nil {
+			return false, ErrEventEntityEmpty
+		}
+
+		//Only the node itself may announce, change or delete its node
+		err := validateNode(event, tableSet, e.Node)
+		if err != nil {
+			return false, err
+		}
+
+		//A deleted node can no longer be contacted, the signature of its known key is enough
+		if event.UpdateType == abusemesh.TableEventType_TABLE_UPDATE_DELETE {
+			return true, nil
+		}
+
 		//In case of a new node on the network we need to contact that node and confirm it's existence and claims
-		return false, errors.New("Not Yet Implemented")
+		if tableSet.NodeVerifier == nil {
+			return false, errors.New("No node verifier configured, unable to confirm node claims")
+		}
+
+		err = tableSet.NodeVerifier.VerifyNode(e.Node)
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
 
 	case *abusemesh.TableEvent_Report:
-		//In case of a report we need verify that the signature is correct
-		return false, errors.New("Not Yet Implemented")
+		//In case of a report we need verify that the signature is correct and made by the reporter
+		if e.Report == nil {
+			return false, ErrEventEntityEmpty
+		}
+
+		err := validateReport(event, tableSet, e.Report)
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
 
 	case *abusemesh.TableEvent_ReportConfirmation:
 		//In case of a report confirmation we need verify that the signature is correct
-		return false, errors.New("Not Yet Implemented")
+		if e.ReportConfirmation == nil {
+			return false, ErrEventEntityEmpty
+		}
+
+		err := validateReportConfirmation(event, tableSet, e.ReportConfirmation)
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
 
 	case *abusemesh.TableEvent_DelistAcceptance:
 		//In case of a delist acceptance we need verify that the signature is correct
-		return false, errors.New("Not Yet Implemented")
+		if e.DelistAcceptance == nil {
+			return false, ErrEventEntityEmpty
+		}
+
+		err := validateDelistAcceptance(event, tableSet, e.DelistAcceptance)
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
 
 	case *abusemesh.TableEvent_DelistRequests:
 		//In case of a delist request we need verify that the signature is correct
-		return false, errors.New("Not Yet Implemented")
+		if e.DelistRequests == nil {
+			return false, ErrEventEntityEmpty
+		}
+
+		err := validateDelistRequest(event, tableSet, e.DelistRequests)
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
 
 	case *abusemesh.TableEvent_Neighbor:
 		//In case of a neighbor we need verify that the signature is correct
-		return false, errors.New("Not Yet Implemented")
+		if e.Neighbor == nil {
+			return false, ErrEventEntityEmpty
+		}
+
+		err := validateNeighbor(event, tableSet, e.Neighbor)
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
 
 	case nil:
 		return false, ErrEventEntityEmpty
@@ -97,16 +184,53 @@ type EventStream interface {
 	//The EventStream is responsible for checking the validity and uniqueness of the event
 	GetWriteChannel() chan<- Event
 
-	//GetAllEvents returns all events currently in the event stream
+	//GetAllEvents returns the events of the latest snapshot followed by all events after it ordered by their offset
 	GetAllEvents() []Event
 
 	//Attach can be used by other components to subscribe to updates of the event stream
 	//The EventStream must only call the callback with validated and unique events.
+	//Every observer is called from its own goroutine so a slow observer doesn't block the stream or other observers,
+	//except for the TableSet which receives every event before the next event is validated
 	Attach(observerCallback EventObserver)
 
+	//AttachFrom returns all events starting at the given offset and subscribes the observer to all events after them
+	//Every event in the stream gets a offset, a monotonically increasing sequence number starting at 0,
+	//so a subscriber which has seen N events can resume from offset N without missing or duplicating events.
+	//If the offset has been compacted the backlog contains the events of the latest snapshot, followed by all events after it.
+	//ErrOffsetOutOfRange is returned if the offset is beyond the end of the stream
+	AttachFrom(offset uint64, observerCallback EventObserver) (EventBacklog, error)
+
 	//Detach removes a subscriber
 	Detach(observerCallback EventObserver)
 
+	//GetSubscribers returns the status of all attached observers
+	GetSubscribers() []SubscriberStatus
+
+	//GetDedupStats returns the effectiveness and memory usage of the duplicate detection
+	GetDedupStats() DedupStats
+
+	//GetProvenance returns from which neighbors and when the event was received
+	//Events which are written as ReceivedEvent are recorded with the neighbor they came from, other events as written locally
+	GetProvenance(eventID uuid.UUID) (EventProvenance, error)
+
+	//GetQuarantinedEvents returns the events which were refused because they are invalid, the most recently refused event last
+	GetQuarantinedEvents() []QuarantinedEvent
+
+	//GetQuarantinedEvent returns the quarantined event with the id, returns false if the event is not in the quarantine
+	GetQuarantinedEvent(eventID uuid.UUID) (QuarantinedEvent, bool)
+
+	//RevalidateQuarantinedEvent validates a quarantined event again, for example after the key of its signer has arrived
+	//A event which is valid now is removed from the quarantine and written to the stream, otherwise the reason is returned.
+	//ErrNotQuarantined is returned if the event is not in the quarantine
+	RevalidateQuarantinedEvent(eventID uuid.UUID) (bool, error)
+
+	//PurgeQuarantine removes the events with the given ids from the quarantine, or all events if no ids are given
+	//Returns the amount of events which were removed
+	PurgeQuarantine(eventIDs ...uuid.UUID) int
+
+	//GetEquivocations returns the proofs of nodes which signed conflicting events, the most recently detected last
+	GetEquivocations() []EquivocationProof
+
 	//Run runs the goroutine which handles changes and requests to the EventStream
 	Run(context.Context) error
 }
@@ -114,33 +238,66 @@ type EventStream interface {
 //The inMemoryEventStream implements EventStream and stores the events in memory.
 //In memory storage is fast but can also lead to excessive memory usage and long GC pauses
 type inMemoryEventStream struct {
-	//All events in the event stream
-	events map[uuid.UUID]Event
+	//The events in the event stream and the latest snapshot which contains the events needed to derive the state before them
+	store eventStore
 
-	//A mutex lock for the events
+	//A mutex lock for the store
 	eventsLock sync.RWMutex
 
-	//A map of observers interested in new events
-	observers []EventObserver
+	//Keeps track of the ids of all events in the stream, including compacted events
+	deduplicator *EventDeduplicator
 
-	//A mutex lock for the observers
+	//Records from which neighbors events were received, may be nil
+	provenance *ProvenanceTracker
+
+	//Keeps the most recently refused events, may be nil
+	quarantine *Quarantine
+
+	//Detects nodes which sign conflicting events, may be nil
+	equivocations *EquivocationDetector
+
+	//The subscribers which deliver events to the observers interested in new events
+	subscribers []*subscriber
+
+	//A mutex lock for the subscribers
 	observerLock sync.Mutex
 
+	//How events are delivered to the subscribers
+	subscriberPolicy SubscriberPolicy
+
 	//A channel which can be used to write new attempt
 	writeChan chan Event
 
 	//A table set which can be used to query the node table
 	tableSet *TableSet
+
+	//When and how far the stream is compacted
+	compaction CompactionPolicy
 }
 
 //NewInMemoryEventStream creates a new in memory event stream
-func NewInMemoryEventStream(tableSet *TableSet, writeChanBufferSize int) EventStream {
+func NewInMemoryEventStream(
+	tableSet *TableSet,
+	writeChanBufferSize int,
+	compaction CompactionPolicy,
+	subscriberPolicy SubscriberPolicy,
+	deduplicator *EventDeduplicator,
+	provenance *ProvenanceTracker,
+	quarantine *Quarantine,
+	equivocations *EquivocationDetector,
+) EventStream {
 	return &inMemoryEventStream{
-		events:       make(map[uuid.UUID]Event),
-		eventsLock:   sync.RWMutex{},
-		observerLock: sync.Mutex{},
-		writeChan:    make(chan Event, writeChanBufferSize),
-		tableSet:     tableSet,
+		store:            &memoryEventStore{},
+		deduplicator:     deduplicator,
+		provenance:       provenance,
+		quarantine:       quarantine,
+		equivocations:    equivocations,
+		eventsLock:       sync.RWMutex{},
+		observerLock:     sync.Mutex{},
+		subscriberPolicy: subscriberPolicy,
+		writeChan:        make(chan Event, writeChanBufferSize),
+		tableSet:         tableSet,
+		compaction:       compaction,
 	}
 }
 
@@ -149,59 +306,246 @@ func (stream *inMemoryEventStream) GetWriteChannel() chan<- Event {
 }
 
 func (stream *inMemoryEventStream) Run(ctx context.Context) error {
+	stream.flagEquivocators()
+
+	compactionTimer, stopCompactionTimer := stream.compaction.timer()
+	defer stopCompactionTimer()
+
+	//Announcements of nodes which were still being verified are validated again once the verification is done
+	var verifiedNodes <-chan string
+	if notifier, ok := stream.tableSet.NodeVerifier.(verificationNotifier); ok {
+		verifiedNodes = notifier.verified()
+	}
+
 	for {
 		select {
 		case event := <-stream.writeChan:
-			valid, reason := event.Validate(stream.tableSet)
-			if !valid {
-				logrus.WithError(reason).Warn("Event refused because it is invalid")
-				continue
-			}
-
-			eventID := event.GetID()
+			event, hop := receivedFrom(event)
 
-			//If the event doesn't already exist we add it to the stream and notify the observers
-			if _, found := stream.events[eventID]; !found {
-				stream.eventsLock.Lock()
-				stream.events[eventID] = event
-				stream.eventsLock.Unlock()
+			err := stream.process(event, hop)
+			if err != nil {
+				return err
+			}
 
-				stream.observerLock.Lock()
-				for _, observer := range stream.observers {
-					observer.EventUpdate(event)
-				}
-				stream.observerLock.Unlock()
+		case nodeID := <-verifiedNodes:
+			err := stream.revalidateNode(nodeID)
+			if err != nil {
+				return err
+			}
 
-			} else {
-				logrus.WithField("event-id", eventID.String()).Info("Received duplicate event")
+		case <-compactionTimer:
+			err := stream.compact(stream.compactionOffset())
+			if err != nil {
+				//The stored snapshot and events are still consistent, the stream is just not compacted
+				logrus.WithError(err).Error("Error while compacting event stream")
 			}
+
 		case <-ctx.Done():
 			return nil
 		}
 	}
 }
 
+//process validates a event which arrived through the hop and adds it to the stream if it is valid and new
+//Returns a error if the event could not be stored
+func (stream *inMemoryEventStream) process(event Event, hop EventHop) error {
+	//The validation queries the TableSet, which must have received the events before this one
+	stream.awaitValidationObservers()
+
+	valid, reason := event.Validate(stream.tableSet)
+	if !valid {
+		stream.refuse(event, hop, reason)
+		return nil
+	}
+
+	//A copy of a known event may still conflict with it, a different event with the same id is a equivocation
+	stream.detectEquivocation(event)
+
+	//If the event doesn't already exist we add it to the stream and notify the observers
+	if stream.contains(event) {
+		stream.recordHop(event, hop, false)
+		return nil
+	}
+
+	//The event must be stored before anyone acts on it
+	err := stream.add(event)
+	if err != nil {
+		return err
+	}
+	stream.recordHop(event, hop, true)
+
+	return nil
+}
+
+//contains returns true if the event is already in the stream
+//If this can't be determined the error is logged and true is returned, so the event is refused
+func (stream *inMemoryEventStream) contains(event Event) bool {
+	eventID := event.GetID()
+
+	found, err := stream.deduplicator.Contains(eventID)
+	if err != nil {
+		logrus.WithError(err).WithField("event-id", eventID.String()).Error("Unable to check if event is a duplicate, refusing event")
+		return true
+	}
+
+	if found {
+		logrus.WithField("event-id", eventID.String()).Info("Received duplicate event")
+	}
+
+	return found
+}
+
+//add adds a validated and unique event to the end of the stream and queues it for the observers
+//Returns a error if the event could not be stored, the event is not added in that case
+func (stream *inMemoryEventStream) add(event Event) error {
+	stream.eventsLock.Lock()
+	offset := stream.store.end()
+
+	err := stream.store.append(event)
+	if err != nil {
+		stream.eventsLock.Unlock()
+		return errors.Wrapf(err, "Error while storing event '%s'", event.GetID())
+	}
+
+	//The event is stored first, a event which is in the deduplicator but not in the store would be lost
+	err = stream.deduplicator.Add(event.GetID())
+	if err != nil {
+		logrus.WithError(err).WithField("event-id", event.GetID().String()).Error("Error while adding event to deduplicator")
+	}
+
+	//A earlier copy of the event may have been refused, for example because its signer was not yet known
+	if stream.quarantine != nil {
+		stream.quarantine.Remove(event.GetID())
+	}
+
+	//The observer lock is taken before the events are unlocked so AttachFrom can't attach a observer in between,
+	//that observer would receive the event twice: once from AttachFrom and once as update
+	stream.observerLock.Lock()
+	stream.eventsLock.Unlock()
+
+	//Subscribers which are disconnected because of the overflow policy are removed
+	subscribers := stream.subscribers[:0]
+	for _, sub := range stream.subscribers {
+		if stream.enqueue(sub, offset, event) {
+			subscribers = append(subscribers, sub)
+		}
+	}
+	stream.subscribers = subscribers
+
+	stream.observerLock.Unlock()
+
+	return nil
+}
+
+//end returns the offset the next event will get, the events lock must be held by the caller
+func (stream *inMemoryEventStream) end() uint64 {
+	return stream.store.end()
+}
+
+//eventsFrom returns all events starting at the offset, the events lock must be held by the caller
+func (stream *inMemoryEventStream) eventsFrom(offset uint64) ([]Event, error) {
+	base, end := stream.store.base(), stream.store.end()
+	if offset >= base {
+		return stream.store.events(offset, end)
+	}
+
+	//The requested events have been compacted, the subscriber has to bootstrap from the snapshot
+	snapshot, err := stream.store.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := stream.store.events(base, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(snapshot.Events, events...), nil
+}
+
+//attach starts a subscriber for the observer which receives all events from the offset
+//The events lock must be held by the caller
+func (stream *inMemoryEventStream) attach(observer EventObserver, offset uint64) {
+	//Lock the observers to avoid race conditions
+	stream.observerLock.Lock()
+	defer stream.observerLock.Unlock()
+
+	sub := newSubscriber(observer, offset, stream.subscriberPolicy.QueueSize)
+	stream.subscribers = append(stream.subscribers, sub)
+
+	go sub.run(stream)
+}
+
 //Attach can be used by other components to subscribe to updates of the event stream
 //Updates to the EventStream will be sent over the channel.
 //The EventStream must only send validated and unique events.
 func (stream *inMemoryEventStream) Attach(observer EventObserver) {
-	//Lock the observers to avoid race conditions
-	stream.observerLock.Lock()
-	defer stream.observerLock.Unlock()
+	stream.eventsLock.RLock()
+	defer stream.eventsLock.RUnlock()
+
+	stream.attach(observer, stream.end())
+}
+
+//A EventBacklog holds the events a observer has missed before it was attached
+type EventBacklog struct {
+	//The events of the latest snapshot, only set if the requested offset has been compacted
+	//The events of a snapshot don't have a offset, the snapshot covers all events before Offset
+	Snapshot []Event
+
+	//The offset of the first event in Events, the offset after the last event is Offset plus the amount of events
+	Offset uint64
+
+	Events []Event
+}
 
-	stream.observers = append(stream.observers, observer)
+func (stream *inMemoryEventStream) AttachFrom(offset uint64, observer EventObserver) (EventBacklog, error) {
+	//Hold the events lock while attaching so no event can be added between copying and attaching
+	stream.eventsLock.RLock()
+	defer stream.eventsLock.RUnlock()
+
+	base, end := stream.store.base(), stream.end()
+	if offset > end {
+		return EventBacklog{}, errors.Wrapf(ErrOffsetOutOfRange, "Requested offset '%d' but stream ends at '%d'", offset, end)
+	}
+
+	var backlog EventBacklog
+
+	//The requested events have been compacted, the observer has to bootstrap from the snapshot
+	if offset < base {
+		snapshot, err := stream.store.snapshot()
+		if err != nil {
+			return EventBacklog{}, err
+		}
+
+		backlog.Snapshot = snapshot.Events
+		offset = base
+	}
+
+	events, err := stream.store.events(offset, end)
+	if err != nil {
+		return EventBacklog{}, err
+	}
+
+	backlog.Offset = offset
+	backlog.Events = events
+
+	stream.attach(observer, end)
+
+	return backlog, nil
 }
 
 //Detach removes a subscriber
+//The observer may still receive a event which was being delivered while it was detached
 func (stream *inMemoryEventStream) Detach(observer EventObserver) {
 	//Lock the observers to avoid race conditions
 	stream.observerLock.Lock()
 	defer stream.observerLock.Unlock()
 
-	//Find observer with the same value and delete it
-	for index, curObserver := range stream.observers {
-		if curObserver == observer {
-			stream.observers = append(stream.observers[:index], stream.observers[index+1:]...)
+	//Find observer with the same value, stop its subscriber and delete it
+	for index, sub := range stream.subscribers {
+		if sub.observer == observer {
+			close(sub.stop)
+			stream.subscribers = append(stream.subscribers[:index], stream.subscribers[index+1:]...)
 			return
 		}
 	}
@@ -212,10 +556,14 @@ func (stream *inMemoryEventStream) GetAllEvents() []Event {
 	stream.eventsLock.RLock()
 	defer stream.eventsLock.RUnlock()
 
-	events := make([]Event, 0, len(stream.events))
-	for _, event := range stream.events {
-		events = append(events, event)
+	events, err := stream.eventsFrom(0)
+	if err != nil {
+		logrus.WithError(err).Error("Error while reading events")
 	}
 
 	return events
 }
+
+func (stream *inMemoryEventStream) GetDedupStats() DedupStats {
+	return stream.deduplicator.Stats()
+}
@@ -0,0 +1,324 @@
+package entities
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/abuse-mesh/abuse-mesh-go/internal/storage"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	//eventLogMetaKey is the key of the offsets of the log, the base, the end and the amount of events in the snapshot,
+	//every offset is a 8 byte big endian integer
+	eventLogMetaKey = "event-log/meta"
+
+	//eventLogEventPrefix is the prefix of the keys of the events after the snapshot, followed by the 8 byte big endian offset
+	eventLogEventPrefix = "event-log/event/"
+
+	//eventLogSnapshotPrefix is the prefix of the keys of the events in the snapshot, followed by the 8 byte big endian index
+	eventLogSnapshotPrefix = "event-log/snapshot/"
+)
+
+//eventLogKey returns the key of the event with the given offset or index
+func eventLogKey(prefix string, offset uint64) []byte {
+	key := make([]byte, len(prefix)+8)
+	copy(key, prefix)
+	binary.BigEndian.PutUint64(key[len(prefix):], offset)
+
+	return key
+}
+
+//encodeStoredEvent encodes the event to store it
+func encodeStoredEvent(event Event) ([]byte, error) {
+	genericEvent, ok := event.(*GenericEvent)
+	if !ok {
+		return nil, errors.Errorf("Event of type '%T' can't be persisted", event)
+	}
+
+	value, err := proto.Marshal(&genericEvent.TableEvent)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while encoding event")
+	}
+
+	return value, nil
+}
+
+func decodeStoredEvent(value []byte) (Event, error) {
+	event := &GenericEvent{}
+
+	err := proto.Unmarshal(value, &event.TableEvent)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while decoding event")
+	}
+
+	return event, nil
+}
+
+//The storageEventStore keeps the events and the snapshot of a event stream in a storage backend
+//Only the offsets are kept in memory. Every change is committed as a single batch together with the new offsets,
+//so after a crash the store contains every event which was appended and either the old or the new snapshot
+type storageEventStore struct {
+	backend storage.StorageBackend
+
+	first uint64
+	next  uint64
+
+	//The amount of events in the snapshot
+	snapshotSize uint64
+}
+
+//openStorageEventStore opens the event store in the storage backend, a empty backend contains a empty store
+func openStorageEventStore(backend storage.StorageBackend) (*storageEventStore, error) {
+	store := &storageEventStore{
+		backend: backend,
+	}
+
+	meta, err := backend.Get([]byte(eventLogMetaKey))
+	if err == storage.ErrNotFound {
+		return store, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while reading event log")
+	}
+
+	if len(meta) != 24 {
+		return nil, errors.New("Event log is corrupt")
+	}
+
+	store.first = binary.BigEndian.Uint64(meta[0:8])
+	store.next = binary.BigEndian.Uint64(meta[8:16])
+	store.snapshotSize = binary.BigEndian.Uint64(meta[16:24])
+
+	return store, nil
+}
+
+//putMeta adds the current offsets of the store to the batch
+func (store *storageEventStore) putMeta(batch storage.Batch) {
+	meta := make([]byte, 24)
+	binary.BigEndian.PutUint64(meta[0:8], store.first)
+	binary.BigEndian.PutUint64(meta[8:16], store.next)
+	binary.BigEndian.PutUint64(meta[16:24], store.snapshotSize)
+
+	batch.Put([]byte(eventLogMetaKey), meta)
+}
+
+func (store *storageEventStore) base() uint64 {
+	return store.first
+}
+
+func (store *storageEventStore) end() uint64 {
+	return store.next
+}
+
+func (store *storageEventStore) append(event Event) error {
+	value, err := encodeStoredEvent(event)
+	if err != nil {
+		return err
+	}
+
+	batch := store.backend.NewBatch()
+	batch.Put(eventLogKey(eventLogEventPrefix, store.next), value)
+
+	store.next++
+	store.putMeta(batch)
+
+	err = batch.Commit()
+	if err != nil {
+		store.next--
+		return errors.Wrap(err, "Error while writing to event log")
+	}
+
+	return nil
+}
+
+//read reads the events with the keys prefix plus from up to but not including prefix plus to
+func (store *storageEventStore) read(prefix string, from, to uint64) ([]Event, error) {
+	events := make([]Event, 0, to-from)
+
+	for offset := from; offset < to; offset++ {
+		value, err := store.backend.Get(eventLogKey(prefix, offset))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error while reading event '%d' from event log", offset)
+		}
+
+		event, err := decodeStoredEvent(value)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (store *storageEventStore) events(from, to uint64) ([]Event, error) {
+	return store.read(eventLogEventPrefix, from, to)
+}
+
+func (store *storageEventStore) snapshot() (EventSnapshot, error) {
+	events, err := store.read(eventLogSnapshotPrefix, 0, store.snapshotSize)
+	if err != nil {
+		return EventSnapshot{}, err
+	}
+
+	return EventSnapshot{
+		Offset: store.first,
+		Events: events,
+	}, nil
+}
+
+func (store *storageEventStore) compact(snapshot EventSnapshot) error {
+	batch := store.backend.NewBatch()
+
+	for index, event := range snapshot.Events {
+		value, err := encodeStoredEvent(event)
+		if err != nil {
+			return err
+		}
+
+		batch.Put(eventLogKey(eventLogSnapshotPrefix, uint64(index)), value)
+	}
+
+	//Remove the tail of the old snapshot if it was larger and the events which are now covered by the snapshot
+	for index := uint64(len(snapshot.Events)); index < store.snapshotSize; index++ {
+		batch.Delete(eventLogKey(eventLogSnapshotPrefix, index))
+	}
+	for offset := store.first; offset < snapshot.Offset; offset++ {
+		batch.Delete(eventLogKey(eventLogEventPrefix, offset))
+	}
+
+	first, snapshotSize := store.first, store.snapshotSize
+	store.first, store.snapshotSize = snapshot.Offset, uint64(len(snapshot.Events))
+	store.putMeta(batch)
+
+	err := batch.Commit()
+	if err != nil {
+		store.first, store.snapshotSize = first, snapshotSize
+		return errors.Wrap(err, "Error while compacting event log")
+	}
+
+	return nil
+}
+
+//replay calls the callback for every event in the snapshot followed by every event after it
+//The events are read one by one, so they don't have to fit in memory
+func (store *storageEventStore) replay(callback func(Event) error) error {
+	for _, prefix := range []string{eventLogSnapshotPrefix, eventLogEventPrefix} {
+		err := store.backend.Iterate([]byte(prefix), func(key, value []byte) error {
+			event, err := decodeStoredEvent(value)
+			if err != nil {
+				return errors.Wrapf(err, "Error while replaying '%s'", key)
+			}
+
+			return callback(event)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//The logEventStream implements EventStream and persists every accepted event in a storage backend
+//before the observers are notified. On startup the stored events are replayed so observers like the TableSet are rebuild.
+//Since events are stored with their offset the offsets of events don't change across restarts.
+//When the stream is compacted the snapshot replaces the compacted events in the same batch
+type logEventStream struct {
+	*inMemoryEventStream
+
+	store *storageEventStore
+}
+
+//NewLogEventStream creates a new event stream which persists events in the storage backend
+//The stored events are replayed when the stream is started with Run
+func NewLogEventStream(
+	tableSet *TableSet,
+	writeChanBufferSize int,
+	compaction CompactionPolicy,
+	subscriberPolicy SubscriberPolicy,
+	deduplicator *EventDeduplicator,
+	provenance *ProvenanceTracker,
+	quarantine *Quarantine,
+	equivocations *EquivocationDetector,
+	backend storage.StorageBackend,
+) (EventStream, error) {
+	store, err := openStorageEventStore(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := NewInMemoryEventStream(
+		tableSet,
+		writeChanBufferSize,
+		compaction,
+		subscriberPolicy,
+		deduplicator,
+		provenance,
+		quarantine,
+		equivocations,
+	).(*inMemoryEventStream)
+	stream.store = store
+
+	return &logEventStream{
+		inMemoryEventStream: stream,
+		store:               store,
+	}, nil
+}
+
+func (stream *logEventStream) Run(ctx context.Context) error {
+	err := stream.replay()
+	if err != nil {
+		return err
+	}
+
+	return stream.inMemoryEventStream.Run(ctx)
+}
+
+//replay passes the stored events to the validation observers, the deduplicator and the equivocation detector
+//Events in the store have been validated when they were accepted, so they are replayed without validation.
+//The events are passed to the observers directly, no events are accepted before the replay is done
+func (stream *logEventStream) replay() error {
+	stream.observerLock.Lock()
+	var observers []EventObserver
+	for _, sub := range stream.subscribers {
+		if _, ok := sub.observer.(validationObserver); ok {
+			observers = append(observers, sub.observer)
+		}
+	}
+	stream.observerLock.Unlock()
+
+	replayed := 0
+
+	err := stream.store.replay(func(event Event) error {
+		//The deduplicator may already contain the event if its index is persistent
+		err := stream.deduplicator.Add(event.GetID())
+		if err != nil {
+			return err
+		}
+
+		stream.detectEquivocation(event)
+
+		for _, observer := range observers {
+			observer.EventUpdate(event)
+		}
+
+		replayed++
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"offset": stream.store.base(),
+		"events": replayed,
+	}).Info("Event log replayed")
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package entities
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/storage/local"
+	"github.com/google/uuid"
+)
+
+//eventIDs returns the ids of the events in order
+func eventIDs(events []Event) []uuid.UUID {
+	ids := make([]uuid.UUID, len(events))
+	for i, event := range events {
+		ids[i] = event.GetID()
+	}
+
+	return ids
+}
+
+func assertEventIDs(t *testing.T, name string, events []Event, expected []Event) {
+	if len(events) != len(expected) {
+		t.Errorf("%s: expected %d events, got %d", name, len(expected), len(events))
+		return
+	}
+
+	for i, id := range eventIDs(events) {
+		if id != expected[i].GetID() {
+			t.Errorf("%s: expected event %d to be %s, got %s", name, i, expected[i].GetID(), id)
+		}
+	}
+}
+
+//The offsets, events and snapshot of the store survive reopening the storage
+func Test_StorageEventStore_Compact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "event-log")
+	if err != nil {
+		t.Fatalf("Error while creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	backend, err := local.NewStorageBackend(dir, false)
+	if err != nil {
+		t.Fatalf("Error while opening storage backend: %s", err)
+	}
+
+	store, err := openStorageEventStore(backend)
+	if err != nil {
+		t.Fatalf("Error while opening event store: %s", err)
+	}
+
+	node := newTestNode(t, "node-a")
+	events := []Event{node.announce(t)}
+	for i := 0; i < 4; i++ {
+		events = append(events, node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, uuid.New(), "198.51.100.1"))
+	}
+
+	for _, event := range events {
+		err := store.append(event)
+		if err != nil {
+			t.Fatalf("Error while appending event: %s", err)
+		}
+	}
+
+	err = store.compact(EventSnapshot{Offset: 3, Events: events[1:3]})
+	if err != nil {
+		t.Fatalf("Error while compacting event store: %s", err)
+	}
+
+	backend.Close()
+	backend, err = local.NewStorageBackend(dir, false)
+	if err != nil {
+		t.Fatalf("Error while opening storage backend: %s", err)
+	}
+	defer backend.Close()
+
+	store, err = openStorageEventStore(backend)
+	if err != nil {
+		t.Fatalf("Error while opening event store: %s", err)
+	}
+
+	if store.base() != 3 || store.end() != 5 {
+		t.Errorf("Expected offsets 3 to 5, got %d to %d", store.base(), store.end())
+	}
+
+	stored, err := store.events(3, 5)
+	if err != nil {
+		t.Fatalf("Error while reading events: %s", err)
+	}
+	assertEventIDs(t, "events", stored, events[3:])
+
+	snapshot, err := store.snapshot()
+	if err != nil {
+		t.Fatalf("Error while reading snapshot: %s", err)
+	}
+	if snapshot.Offset != 3 {
+		t.Errorf("Expected snapshot at offset 3, got %d", snapshot.Offset)
+	}
+	assertEventIDs(t, "snapshot", snapshot.Events, events[1:3])
+
+	var replayed []Event
+	err = store.replay(func(event Event) error {
+		replayed = append(replayed, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error while replaying events: %s", err)
+	}
+	assertEventIDs(t, "replay", replayed, events[1:])
+
+	//The compacted events and the tail of the larger snapshot are removed
+	err = store.compact(EventSnapshot{Offset: 4, Events: events[3:4]})
+	if err != nil {
+		t.Fatalf("Error while compacting event store: %s", err)
+	}
+	if _, err := backend.Get(eventLogKey(eventLogSnapshotPrefix, 1)); err == nil {
+		t.Error("Expected the old snapshot to be removed")
+	}
+	if _, err := backend.Get(eventLogKey(eventLogEventPrefix, 3)); err == nil {
+		t.Error("Expected the compacted event to be removed")
+	}
+}
+
+//The TableSet is rebuild from the stored events when the stream is started again
+func Test_LogEventStream_Replay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "event-log")
+	if err != nil {
+		t.Fatalf("Error while creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	backend, err := local.NewStorageBackend(dir, false)
+	if err != nil {
+		t.Fatalf("Error while opening storage backend: %s", err)
+	}
+	defer backend.Close()
+
+	startStream := func(tableSet *TableSet) (EventStream, context.CancelFunc) {
+		deduplicator, err := NewEventDeduplicator(DefaultDedupPolicy, backend)
+		if err != nil {
+			t.Fatalf("Error while creating deduplicator: %s", err)
+		}
+
+		stream, err := NewLogEventStream(tableSet, 100, CompactionPolicy{}, SubscriberPolicy{}, deduplicator, nil, NewQuarantine(100), nil, backend)
+		if err != nil {
+			t.Fatalf("Error while opening event log: %s", err)
+		}
+		stream.Attach(tableSet)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go stream.Run(ctx)
+
+		return stream, cancel
+	}
+
+	node := newTestNode(t, "node-a")
+	reportID := uuid.New()
+
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	stream, stopStream := startStream(tableSet)
+	stream.GetWriteChannel() <- node.announce(t)
+	stream.GetWriteChannel() <- node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, reportID, "198.51.100.1")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for tableSet.getReport(reportID) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Report was not applied")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	stopStream()
+
+	restarted, stopRestarted := runTestTableSet()
+	defer stopRestarted()
+
+	stream, stopStream = startStream(restarted)
+	defer stopStream()
+
+	deadline = time.Now().Add(5 * time.Second)
+	for restarted.getReport(reportID) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Report was not replayed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if events := stream.GetAllEvents(); len(events) != 2 {
+		t.Errorf("Expected 2 stored events, got %d", len(events))
+	}
+}
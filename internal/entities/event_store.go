@@ -0,0 +1,66 @@
+package entities
+
+//A eventStore holds the events of a event stream after its latest snapshot and the snapshot itself
+//The offset of a event is its position in the stream, the snapshot covers all events before base.
+//The stream holds its events lock while calling the store, only the goroutine of the stream appends and compacts
+type eventStore interface {
+	//base returns the offset of the first event after the snapshot
+	base() uint64
+
+	//end returns the offset the next event will get
+	end() uint64
+
+	//append adds the event at the end, the event must be stored once append returns
+	append(event Event) error
+
+	//events returns the events from offset up to but not including to, the range must be between base and end
+	events(from, to uint64) ([]Event, error)
+
+	//snapshot returns the latest snapshot
+	snapshot() (EventSnapshot, error)
+
+	//compact replaces the snapshot and removes the events before the offset of the new snapshot
+	compact(snapshot EventSnapshot) error
+}
+
+//The memoryEventStore keeps the events and the snapshot in memory
+type memoryEventStore struct {
+	//All events after the snapshot, the offset of a event is first plus its index
+	stored []Event
+	first  uint64
+
+	latest EventSnapshot
+}
+
+func (store *memoryEventStore) base() uint64 {
+	return store.first
+}
+
+func (store *memoryEventStore) end() uint64 {
+	return store.first + uint64(len(store.stored))
+}
+
+func (store *memoryEventStore) append(event Event) error {
+	store.stored = append(store.stored, event)
+	return nil
+}
+
+func (store *memoryEventStore) events(from, to uint64) ([]Event, error) {
+	events := make([]Event, to-from)
+	copy(events, store.stored[from-store.first:to-store.first])
+
+	return events, nil
+}
+
+func (store *memoryEventStore) snapshot() (EventSnapshot, error) {
+	return store.latest, nil
+}
+
+func (store *memoryEventStore) compact(snapshot EventSnapshot) error {
+	//Copy the remaining events so the memory of the compacted events can be freed
+	store.stored = append([]Event(nil), store.stored[snapshot.Offset-store.first:]...)
+	store.first = snapshot.Offset
+	store.latest = snapshot
+
+	return nil
+}
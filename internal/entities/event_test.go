@@ -0,0 +1,237 @@
+package entities
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/google/uuid"
+)
+
+func newTestEventStream(t *testing.T, tableSet *TableSet) EventStream {
+	deduplicator, err := NewEventDeduplicator(DefaultDedupPolicy, nil)
+	if err != nil {
+		t.Fatalf("Error while creating deduplicator: %s", err)
+	}
+
+	return NewInMemoryEventStream(
+		tableSet,
+		100,
+		CompactionPolicy{},
+		SubscriberPolicy{},
+		deduplicator,
+		nil,
+		NewQuarantine(100),
+		nil,
+	)
+}
+
+//A report which directly follows the node of its reporter must be validated against a TableSet which knows the node
+func Test_EventStream_NodeThenReport(t *testing.T) {
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	stream := newTestEventStream(t, tableSet)
+	stream.Attach(tableSet)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go stream.Run(ctx)
+
+	//The race is not hit every time, so several nodes are announced
+	for _, name := range []string{"node-a", "node-b", "node-c", "node-d", "node-e"} {
+		node := newTestNode(t, name)
+		reportID := uuid.New()
+
+		stream.GetWriteChannel() <- node.announce(t)
+		stream.GetWriteChannel() <- node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, reportID, "198.51.100.1")
+
+		deadline := time.Now().Add(5 * time.Second)
+		for tableSet.getReport(reportID) == nil {
+			if time.Now().After(deadline) {
+				t.Fatalf("Report of %s was not applied, %d events are quarantined", name, len(stream.GetQuarantinedEvents()))
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	for _, quarantined := range stream.GetQuarantinedEvents() {
+		t.Errorf("Expected no quarantined events, got %s: %s", quarantined.EventID, quarantined.Reason)
+	}
+}
+
+//A observer which requests a compacted offset receives the snapshot and learns the offset after it,
+//resuming from that offset plus the events it received returns only newer events
+func Test_EventStream_AttachFrom(t *testing.T) {
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	stream := newTestEventStream(t, tableSet)
+	stream.Attach(tableSet)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go stream.Run(ctx)
+
+	node := newTestNode(t, "node-a")
+	stream.GetWriteChannel() <- node.announce(t)
+
+	reportIDs := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	for _, reportID := range reportIDs {
+		stream.GetWriteChannel() <- node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, reportID, "198.51.100.1")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for tableSet.getReport(reportIDs[len(reportIDs)-1]) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Reports were not applied")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	events := stream.GetAllEvents()
+
+	err := stream.(*inMemoryEventStream).compact(3)
+	if err != nil {
+		t.Fatalf("Error while compacting stream: %s", err)
+	}
+
+	observer, stopObserver := runTestTableSet()
+	defer stopObserver()
+
+	backlog, err := stream.AttachFrom(1, observer)
+	if err != nil {
+		t.Fatalf("Error while attaching: %s", err)
+	}
+	stream.Detach(observer)
+
+	if backlog.Offset != 3 {
+		t.Errorf("Expected the backlog to start at offset 3, got %d", backlog.Offset)
+	}
+	assertEventIDs(t, "snapshot", backlog.Snapshot, events[:3])
+	assertEventIDs(t, "events", backlog.Events, events[3:])
+
+	stream.GetWriteChannel() <- node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, uuid.New(), "198.51.100.2")
+
+	deadline = time.Now().Add(5 * time.Second)
+	for len(stream.GetAllEvents()) != len(events)+1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Report was not added")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	resumed, err := stream.AttachFrom(backlog.Offset+uint64(len(backlog.Events)), observer)
+	if err != nil {
+		t.Fatalf("Error while attaching: %s", err)
+	}
+	stream.Detach(observer)
+
+	if len(resumed.Snapshot) != 0 {
+		t.Errorf("Expected no snapshot events, got %d", len(resumed.Snapshot))
+	}
+	assertEventIDs(t, "resumed", resumed.Events, stream.GetAllEvents()[len(events):])
+}
+
+//A blockingValidationObserver is a validation observer which doesn't receive events until it is released
+type blockingValidationObserver struct {
+	release chan struct{}
+
+	events []uuid.UUID
+	lock   sync.Mutex
+}
+
+func (observer *blockingValidationObserver) EventUpdate(event Event) {
+	<-observer.release
+
+	observer.lock.Lock()
+	defer observer.lock.Unlock()
+
+	observer.events = append(observer.events, event.GetID())
+}
+
+func (observer *blockingValidationObserver) validation() {}
+
+func (observer *blockingValidationObserver) received() int {
+	observer.lock.Lock()
+	defer observer.lock.Unlock()
+
+	return len(observer.events)
+}
+
+//A validation observer which doesn't keep up only holds up the intake for the ValidationTimeout,
+//it is resynced and receives all events once it continues
+func Test_EventStream_SlowValidationObserver(t *testing.T) {
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	deduplicator, err := NewEventDeduplicator(DefaultDedupPolicy, nil)
+	if err != nil {
+		t.Fatalf("Error while creating deduplicator: %s", err)
+	}
+
+	stream := NewInMemoryEventStream(
+		tableSet,
+		100,
+		CompactionPolicy{},
+		SubscriberPolicy{ValidationTimeout: 10 * time.Millisecond},
+		deduplicator,
+		nil,
+		NewQuarantine(100),
+		nil,
+	)
+	stream.Attach(tableSet)
+
+	observer := &blockingValidationObserver{release: make(chan struct{})}
+	stream.Attach(observer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go stream.Run(ctx)
+
+	node := newTestNode(t, "node-a")
+	events := []Event{node.announce(t)}
+	for i := 0; i < 10; i++ {
+		events = append(events, node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, uuid.New(), "198.51.100.1"))
+	}
+
+	started := time.Now()
+	for _, event := range events {
+		stream.GetWriteChannel() <- event
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(stream.GetAllEvents()) != len(events) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d events to be accepted, got %d", len(events), len(stream.GetAllEvents()))
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	//Only the first event after the observer stopped receiving waits for it
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Expected the intake not to wait for the resyncing observer, took %s", elapsed)
+	}
+
+	resyncing := false
+	for _, status := range stream.GetSubscribers() {
+		if status.Observer == "*entities.blockingValidationObserver" {
+			resyncing = status.Resyncing
+		}
+	}
+	if !resyncing {
+		t.Error("Expected the slow observer to be resyncing")
+	}
+
+	close(observer.release)
+
+	deadline = time.Now().Add(5 * time.Second)
+	for observer.received() != len(events) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the observer to catch up with %d events, got %d", len(events), observer.received())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
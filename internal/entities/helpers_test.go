@@ -0,0 +1,144 @@
+package entities
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+//testKeys caches the keys of the test nodes, generating a key takes a noticeable amount of time
+var (
+	testKeys     = make(map[string]*openpgp.Entity)
+	testKeysLock sync.Mutex
+)
+
+//testKey returns the key of the test node with the given name
+func testKey(t *testing.T, name string) *openpgp.Entity {
+	testKeysLock.Lock()
+	defer testKeysLock.Unlock()
+
+	if key, found := testKeys[name]; found {
+		return key
+	}
+
+	key, err := openpgp.NewEntity(name, "", name+"@example.com", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatalf("Error while generating key: %s", err)
+	}
+
+	testKeys[name] = key
+
+	return key
+}
+
+//testPGPProvider provides the key of a test node
+type testPGPProvider struct {
+	entity *openpgp.Entity
+}
+
+func (provider testPGPProvider) GetEntity() *openpgp.Entity {
+	return provider.entity
+}
+
+//acceptingNodeVerifier confirms all node announcements
+type acceptingNodeVerifier struct{}
+
+func (acceptingNodeVerifier) VerifyNode(*abusemesh.Node) error {
+	return nil
+}
+
+//A testNode is a node of the mesh which signs its own events
+type testNode struct {
+	id     uuid.UUID
+	key    *openpgp.Entity
+	author *EventAuthor
+}
+
+func newTestNode(t *testing.T, name string) testNode {
+	key := testKey(t, name)
+
+	return testNode{
+		id:     uuid.New(),
+		key:    key,
+		author: NewEventAuthor(testPGPProvider{key}, nil),
+	}
+}
+
+//message returns the node message which announces the node
+func (node testNode) message(t *testing.T) *abusemesh.Node {
+	var packets bytes.Buffer
+	err := node.key.Serialize(&packets)
+	if err != nil {
+		t.Fatalf("Error while serializing key: %s", err)
+	}
+
+	return &abusemesh.Node{
+		Uuid:      &abusemesh.UUID{Uuid: node.id.String()},
+		IpAddress: &abusemesh.IPAddress{Address: "192.0.2.1"},
+		PgpEntity: &abusemesh.PGPEntity{PgpPackets: packets.Bytes()},
+	}
+}
+
+//announce returns a signed event which announces the node
+func (node testNode) announce(t *testing.T) *GenericEvent {
+	event, err := node.author.NodeEvent(abusemesh.TableEventType_TABLE_UPDATE_NEW, node.message(t))
+	if err != nil {
+		t.Fatalf("Error while signing node event: %s", err)
+	}
+
+	return event
+}
+
+//report returns a signed event about a report of the node
+func (node testNode) report(t *testing.T, updateType abusemesh.TableEventType, reportID uuid.UUID, address string) *GenericEvent {
+	event, err := node.author.ReportEvent(updateType, &abusemesh.Report{
+		Uuid:      &abusemesh.UUID{Uuid: reportID.String()},
+		Reporter:  &abusemesh.UUID{Uuid: node.id.String()},
+		IpAddress: &abusemesh.IPAddress{Address: address},
+		Category:  "spam",
+	})
+	if err != nil {
+		t.Fatalf("Error while signing report event: %s", err)
+	}
+
+	return event
+}
+
+//runTestTableSet starts a TableSet which accepts all node announcements, the returned function stops it
+func runTestTableSet() (*TableSet, context.CancelFunc) {
+	tableSet := NewTableSet(100)
+	tableSet.NodeVerifier = acceptingNodeVerifier{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go tableSet.Run(ctx)
+
+	return tableSet, cancel
+}
+
+//applyEvent validates the event and applies it to the tables, like the event stream and TableSet would
+func applyEvent(tableSet *TableSet, event Event) error {
+	valid, err := event.Validate(tableSet)
+	if !valid {
+		return err
+	}
+
+	return processEvent(tableSet, event)
+}
+
+//processEvent applies the event to the tables without validating it first,
+//like a event which was validated before a other event about the same entity was applied
+func processEvent(tableSet *TableSet, event Event) error {
+	errorChan := make(chan error, 1)
+	tableSet.Channel <- &UpdateTableRequest{
+		Event:     event,
+		ErrorChan: errorChan,
+	}
+
+	return <-errorChan
+}
@@ -0,0 +1,367 @@
+package entities
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/utils/conv"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var (
+	//ErrUnknownNeighbor signals that a neighborship refers to a node which is not in the node table
+	ErrUnknownNeighbor = errors.New("The neighbor is not a known node")
+
+	//ErrNotNeighborOwner signals that a event changes a neighborship but is not made by the node which announced it
+	ErrNotNeighborOwner = errors.New("Only the node which announced a neighborship can change or withdraw it")
+)
+
+//A Neighbor is a neighborship between two nodes
+//Each node announces the neighborship with a event of its own, it only exists once both nodes have announced it
+type Neighbor struct {
+	//The node which announced the neighborship
+	Node uuid.UUID
+	//The node with which Node is neighbors
+	Neighbor uuid.UUID
+	//The signed timestamp of the announcement which completed the neighborship
+	Since time.Time
+}
+
+//NeighborFromProtobuf creates a neighbor object from the protobuf stub
+//Since is not part of the message, it is determined by the NeighborTable
+func NeighborFromProtobuf(protobufNeighbor *abusemesh.Neighbor) (Neighbor, error) {
+	nodeID, err := conv.AuuidToGuuid(protobufNeighbor.GetNode())
+	if err != nil {
+		return Neighbor{}, errors.Wrap(err, "Node ID invalid")
+	}
+
+	neighborID, err := conv.AuuidToGuuid(protobufNeighbor.GetNeighbor())
+	if err != nil {
+		return Neighbor{}, errors.Wrap(err, "Neighbor ID invalid")
+	}
+
+	if nodeID == neighborID {
+		return Neighbor{}, errors.New("A node can't be its own neighbor")
+	}
+
+	return Neighbor{
+		Node:     nodeID,
+		Neighbor: neighborID,
+	}, nil
+}
+
+//A NeighborEdge identifies the undirected edge between two nodes in the mesh
+type NeighborEdge [2]uuid.UUID
+
+//Edge returns the edge of the neighborship, the edge is the same regardless of which of the two nodes announced it
+func (neighbor Neighbor) Edge() NeighborEdge {
+	if bytes.Compare(neighbor.Node[:], neighbor.Neighbor[:]) < 0 {
+		return NeighborEdge{neighbor.Node, neighbor.Neighbor}
+	}
+
+	return NeighborEdge{neighbor.Neighbor, neighbor.Node}
+}
+
+//String returns the UUIDs of both nodes separated by a '/'
+func (edge NeighborEdge) String() string {
+	return edge[0].String() + "/" + edge[1].String()
+}
+
+//validateNeighbor checks that both nodes of the neighborship are known and that the announcing node signed it
+func validateNeighbor(event *GenericEvent, tableSet *TableSet, neighbor *abusemesh.Neighbor) error {
+	neighborID, err := conv.AuuidToGuuid(neighbor.GetNeighbor())
+	if err != nil {
+		return errors.Wrap(err, "Neighbor ID invalid")
+	}
+
+	//A node can withdraw a neighborship with a node which no longer exists
+	if event.UpdateType != abusemesh.TableEventType_TABLE_UPDATE_DELETE && tableSet.getNode(neighborID) == nil {
+		return ErrUnknownNeighbor
+	}
+
+	return event.verifySignedBy(tableSet, neighbor.GetNode())
+}
+
+//A neighborAnnouncement is the announcement of a neighborship by one of the two nodes
+type neighborAnnouncement struct {
+	Node     uuid.UUID
+	Neighbor uuid.UUID
+}
+
+//A NeighborTable holds all neighborships in the network known to the current node
+//Together with the node table it forms the topology graph of the mesh
+type NeighborTable struct {
+	//The neighborships which both nodes have announced
+	Entities map[NeighborEdge]Neighbor
+
+	//announced holds the signed timestamp of every announcement, including those the other node hasn't confirmed yet
+	announced map[neighborAnnouncement]time.Time
+
+	//adjacency is the set of neighbors of every node which has at least one neighbor
+	adjacency map[uuid.UUID]map[uuid.UUID]struct{}
+
+	//shared is true if Entities is part of a snapshot and has to be copied before it is written
+	shared bool
+
+	//changes records the changes to Entities
+	changes *changeSet
+}
+
+//handleTableEvent applies the announcement or withdrawal of a neighborship by one of its nodes
+//A node can't make a other node its neighbor on its own: the neighborship is added once the other node has announced it
+//as well and is removed as soon as one of the nodes withdraws its announcement
+func (table *NeighborTable) handleTableEvent(eventType abusemesh.TableEventType, entity *abusemesh.TableEvent_Neighbor, signedAt time.Time) error {
+	neighbor, err := NeighborFromProtobuf(entity.Neighbor)
+	if err != nil {
+		return err
+	}
+
+	announcement := neighborAnnouncement{Node: neighbor.Node, Neighbor: neighbor.Neighbor}
+	reverse := neighborAnnouncement{Node: neighbor.Neighbor, Neighbor: neighbor.Node}
+
+	switch eventType {
+	case abusemesh.TableEventType_TABLE_UPDATE_NEW, abusemesh.TableEventType_TABLE_UPDATE_EDIT:
+		//A edit doesn't change when the node announced the neighborship
+		if _, found := table.announced[announcement]; found {
+			return nil
+		}
+		table.announced[announcement] = signedAt
+
+		reverseAt, found := table.announced[reverse]
+		if !found {
+			return nil
+		}
+
+		neighbor.Since = signedAt
+		if reverseAt.After(signedAt) {
+			neighbor.Since = reverseAt
+		}
+
+		table.own()
+		table.changes.record(TableNeighbors, neighbor.Edge().String(), nil, neighbor)
+		table.Entities[neighbor.Edge()] = neighbor
+		table.link(neighbor.Node, neighbor.Neighbor)
+		table.link(neighbor.Neighbor, neighbor.Node)
+
+	case abusemesh.TableEventType_TABLE_UPDATE_DELETE:
+		delete(table.announced, announcement)
+
+		existing, found := table.Entities[neighbor.Edge()]
+		if !found {
+			return nil
+		}
+
+		table.own()
+		table.changes.record(TableNeighbors, neighbor.Edge().String(), existing, nil)
+		delete(table.Entities, neighbor.Edge())
+		table.unlink(neighbor.Node, neighbor.Neighbor)
+		table.unlink(neighbor.Neighbor, neighbor.Node)
+
+	default:
+		return errors.Errorf("Unknown abusemesh.TableEventType type '%T'", eventType)
+	}
+
+	return nil
+}
+
+func (table *NeighborTable) link(from, to uuid.UUID) {
+	neighbors, found := table.adjacency[from]
+	if !found {
+		neighbors = make(map[uuid.UUID]struct{})
+		table.adjacency[from] = neighbors
+	}
+
+	neighbors[to] = struct{}{}
+}
+
+func (table *NeighborTable) unlink(from, to uuid.UUID) {
+	delete(table.adjacency[from], to)
+
+	if len(table.adjacency[from]) == 0 {
+		delete(table.adjacency, from)
+	}
+}
+
+//neighbors returns the neighbors of a node in a stable order
+func (table *NeighborTable) neighbors(nodeID uuid.UUID) []uuid.UUID {
+	neighbors := make([]uuid.UUID, 0, len(table.adjacency[nodeID]))
+	for neighbor := range table.adjacency[nodeID] {
+		neighbors = append(neighbors, neighbor)
+	}
+
+	sortUUIDs(neighbors)
+
+	return neighbors
+}
+
+//shortestPath returns the nodes on the shortest path from one node to the other including both ends
+//nil is returned if no path exists
+func (table *NeighborTable) shortestPath(from, to uuid.UUID) []uuid.UUID {
+	if from == to {
+		return []uuid.UUID{from}
+	}
+
+	//Breadth first search, previous holds the node from which each visited node was reached
+	previous := map[uuid.UUID]uuid.UUID{from: from}
+	queue := []uuid.UUID{from}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, neighbor := range table.neighbors(current) {
+			if _, visited := previous[neighbor]; visited {
+				continue
+			}
+
+			previous[neighbor] = current
+
+			if neighbor == to {
+				//Walk back from the destination to construct the path
+				path := []uuid.UUID{to}
+				for node := current; node != from; node = previous[node] {
+					path = append(path, node)
+				}
+				path = append(path, from)
+
+				for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+					path[i], path[j] = path[j], path[i]
+				}
+
+				return path
+			}
+
+			queue = append(queue, neighbor)
+		}
+	}
+
+	return nil
+}
+
+//reachable returns the set of nodes which can be reached from the given node, including the node itself
+func (table *NeighborTable) reachable(from uuid.UUID) map[uuid.UUID]struct{} {
+	visited := map[uuid.UUID]struct{}{from: {}}
+	stack := []uuid.UUID{from}
+
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for neighbor := range table.adjacency[current] {
+			if _, found := visited[neighbor]; !found {
+				visited[neighbor] = struct{}{}
+				stack = append(stack, neighbor)
+			}
+		}
+	}
+
+	return visited
+}
+
+//sortUUIDs sorts a slice of UUIDs in byte order
+func sortUUIDs(uuids []uuid.UUID) {
+	sort.Slice(uuids, func(i, j int) bool {
+		return bytes.Compare(uuids[i][:], uuids[j][:]) < 0
+	})
+}
+
+//GetNeighborsRequest can be used to request the neighbors of a node
+type GetNeighborsRequest struct {
+	ResponseChan chan<- []uuid.UUID
+	NodeID       uuid.UUID
+}
+
+//Process processes the request and sends the UUIDs of the neighbors on the ResponseChan
+func (req *GetNeighborsRequest) Process(tables *TableSet) error {
+	req.ResponseChan <- tables.neighborTable.neighbors(req.NodeID)
+
+	return nil
+}
+
+//GetShortestPathRequest can be used to request the shortest path between two nodes in the mesh
+type GetShortestPathRequest struct {
+	ResponseChan chan<- []uuid.UUID
+	From         uuid.UUID
+	To           uuid.UUID
+}
+
+//Process processes the request and sends the path including both ends on the ResponseChan or nil if there is no path
+func (req *GetShortestPathRequest) Process(tables *TableSet) error {
+	req.ResponseChan <- tables.neighborTable.shortestPath(req.From, req.To)
+
+	return nil
+}
+
+//GetConnectedComponentsRequest can be used to request all connected components of the mesh
+//Nodes without any neighbors are a component on their own
+type GetConnectedComponentsRequest struct {
+	ResponseChan chan<- [][]uuid.UUID
+}
+
+//Process processes the request and sends the components on the ResponseChan, largest component first
+func (req *GetConnectedComponentsRequest) Process(tables *TableSet) error {
+	//All nodes known from either the node table or a neighborship
+	nodes := make([]uuid.UUID, 0, len(tables.nodeTable.Entities))
+	for nodeID := range tables.nodeTable.Entities {
+		nodes = append(nodes, nodeID)
+	}
+	for nodeID := range tables.neighborTable.adjacency {
+		if _, found := tables.nodeTable.Entities[nodeID]; !found {
+			nodes = append(nodes, nodeID)
+		}
+	}
+	sortUUIDs(nodes)
+
+	assigned := make(map[uuid.UUID]struct{}, len(nodes))
+	var components [][]uuid.UUID
+
+	for _, nodeID := range nodes {
+		if _, found := assigned[nodeID]; found {
+			continue
+		}
+
+		var component []uuid.UUID
+		for member := range tables.neighborTable.reachable(nodeID) {
+			assigned[member] = struct{}{}
+			component = append(component, member)
+		}
+		sortUUIDs(component)
+
+		components = append(components, component)
+	}
+
+	sort.SliceStable(components, func(i, j int) bool {
+		return len(components[i]) > len(components[j])
+	})
+
+	req.ResponseChan <- components
+
+	return nil
+}
+
+//GetUnreachableNodesRequest can be used to request all known nodes which can't be reached from a given node
+type GetUnreachableNodesRequest struct {
+	ResponseChan chan<- []uuid.UUID
+	//The node from which reachability is determined, usually the current node
+	From uuid.UUID
+}
+
+//Process processes the request and sends the UUIDs of the unreachable nodes on the ResponseChan
+func (req *GetUnreachableNodesRequest) Process(tables *TableSet) error {
+	reachable := tables.neighborTable.reachable(req.From)
+
+	var unreachable []uuid.UUID
+	for nodeID := range tables.nodeTable.Entities {
+		if _, found := reachable[nodeID]; !found {
+			unreachable = append(unreachable, nodeID)
+		}
+	}
+	sortUUIDs(unreachable)
+
+	req.ResponseChan <- unreachable
+
+	return nil
+}
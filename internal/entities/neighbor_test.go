@@ -0,0 +1,148 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/google/uuid"
+)
+
+func neighborEvent(node, neighbor uuid.UUID) *abusemesh.TableEvent_Neighbor {
+	return &abusemesh.TableEvent_Neighbor{
+		Neighbor: &abusemesh.Neighbor{
+			Node:     &abusemesh.UUID{Uuid: node.String()},
+			Neighbor: &abusemesh.UUID{Uuid: neighbor.String()},
+		},
+	}
+}
+
+//announceNeighbors lets both nodes of every pair announce the neighborship
+func announceNeighbors(t *testing.T, table *NeighborTable, pairs ...[2]uuid.UUID) {
+	for _, pair := range pairs {
+		for _, event := range []*abusemesh.TableEvent_Neighbor{neighborEvent(pair[0], pair[1]), neighborEvent(pair[1], pair[0])} {
+			err := table.handleTableEvent(abusemesh.TableEventType_TABLE_UPDATE_NEW, event, time.Now())
+			if err != nil {
+				t.Fatalf("Error while announcing neighborship: %s", err)
+			}
+		}
+	}
+}
+
+//sortedUUIDs returns n UUIDs in byte order, so the order of paths and components is predictable
+func sortedUUIDs(n int) []uuid.UUID {
+	uuids := make([]uuid.UUID, n)
+	for i := range uuids {
+		uuids[i] = uuid.New()
+	}
+	sortUUIDs(uuids)
+
+	return uuids
+}
+
+//A neighborship only exists once both nodes have announced it
+func Test_NeighborTable_Consent(t *testing.T) {
+	table := &NewTableSet(0).neighborTable
+	a, b := uuid.New(), uuid.New()
+
+	announcedAt := time.Unix(1000, 0)
+	confirmedAt := time.Unix(2000, 0)
+
+	err := table.handleTableEvent(abusemesh.TableEventType_TABLE_UPDATE_NEW, neighborEvent(a, b), announcedAt)
+	if err != nil {
+		t.Fatalf("Error while announcing neighborship: %s", err)
+	}
+
+	if len(table.Entities) != 0 || len(table.neighbors(a)) != 0 {
+		t.Fatal("Expected no neighborship before the other node announced it")
+	}
+
+	err = table.handleTableEvent(abusemesh.TableEventType_TABLE_UPDATE_NEW, neighborEvent(b, a), confirmedAt)
+	if err != nil {
+		t.Fatalf("Error while announcing neighborship: %s", err)
+	}
+
+	neighbor, found := table.Entities[Neighbor{Node: a, Neighbor: b}.Edge()]
+	if !found {
+		t.Fatal("Expected neighborship once both nodes announced it")
+	}
+	if !neighbor.Since.Equal(confirmedAt) {
+		t.Errorf("Expected neighborship since %s, got %s", confirmedAt, neighbor.Since)
+	}
+	if !reflect.DeepEqual(table.neighbors(a), []uuid.UUID{b}) || !reflect.DeepEqual(table.neighbors(b), []uuid.UUID{a}) {
+		t.Errorf("Expected a and b to be neighbors, got %v and %v", table.neighbors(a), table.neighbors(b))
+	}
+
+	//Either node can end the neighborship
+	err = table.handleTableEvent(abusemesh.TableEventType_TABLE_UPDATE_DELETE, neighborEvent(b, a), time.Unix(3000, 0))
+	if err != nil {
+		t.Fatalf("Error while withdrawing neighborship: %s", err)
+	}
+
+	if len(table.Entities) != 0 || len(table.neighbors(a)) != 0 || len(table.neighbors(b)) != 0 {
+		t.Error("Expected no neighborship after it was withdrawn")
+	}
+}
+
+func Test_NeighborTable_ShortestPath(t *testing.T) {
+	n := sortedUUIDs(6)
+
+	//0 - 1 - 2 - 3 and 0 - 4 - 3, node 5 has no neighbors
+	table := &NewTableSet(0).neighborTable
+	announceNeighbors(t, table, [2]uuid.UUID{n[0], n[1]}, [2]uuid.UUID{n[1], n[2]}, [2]uuid.UUID{n[2], n[3]}, [2]uuid.UUID{n[0], n[4]}, [2]uuid.UUID{n[4], n[3]})
+
+	tests := []struct {
+		from, to uuid.UUID
+		expected []uuid.UUID
+	}{
+		{n[0], n[0], []uuid.UUID{n[0]}},
+		{n[0], n[1], []uuid.UUID{n[0], n[1]}},
+		{n[0], n[3], []uuid.UUID{n[0], n[4], n[3]}},
+		{n[1], n[4], []uuid.UUID{n[1], n[0], n[4]}},
+		{n[2], n[4], []uuid.UUID{n[2], n[3], n[4]}},
+		{n[0], n[5], nil},
+		{n[5], n[0], nil},
+	}
+
+	for _, test := range tests {
+		path := table.shortestPath(test.from, test.to)
+		if !reflect.DeepEqual(path, test.expected) {
+			t.Errorf("Path from %s to %s: expected %v, got %v", test.from, test.to, test.expected, path)
+		}
+	}
+}
+
+func Test_TableSet_Reachability(t *testing.T) {
+	n := sortedUUIDs(5)
+
+	tableSet := NewTableSet(0)
+	for _, nodeID := range n {
+		tableSet.nodeTable.Entities[nodeID] = Node{UUID: nodeID}
+	}
+
+	//Two components: 0 - 1 - 2 and 3 - 4
+	announceNeighbors(t, &tableSet.neighborTable, [2]uuid.UUID{n[0], n[1]}, [2]uuid.UUID{n[1], n[2]}, [2]uuid.UUID{n[3], n[4]})
+
+	componentsChan := make(chan [][]uuid.UUID, 1)
+	err := (&GetConnectedComponentsRequest{ResponseChan: componentsChan}).Process(tableSet)
+	if err != nil {
+		t.Fatalf("Error while getting components: %s", err)
+	}
+
+	expectedComponents := [][]uuid.UUID{{n[0], n[1], n[2]}, {n[3], n[4]}}
+	if components := <-componentsChan; !reflect.DeepEqual(components, expectedComponents) {
+		t.Errorf("Expected components %v, got %v", expectedComponents, components)
+	}
+
+	unreachableChan := make(chan []uuid.UUID, 1)
+	err = (&GetUnreachableNodesRequest{ResponseChan: unreachableChan, From: n[0]}).Process(tableSet)
+	if err != nil {
+		t.Fatalf("Error while getting unreachable nodes: %s", err)
+	}
+
+	expectedUnreachable := []uuid.UUID{n[3], n[4]}
+	if unreachable := <-unreachableChan; !reflect.DeepEqual(unreachable, expectedUnreachable) {
+		t.Errorf("Expected unreachable nodes %v, got %v", expectedUnreachable, unreachable)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"net"
+	"strconv"
 
 	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
 	"github.com/abuse-mesh/abuse-mesh-go/internal/pgp"
@@ -14,6 +15,9 @@ import (
 	"golang.org/x/crypto/openpgp"
 )
 
+//ErrNotNodeOwner signals that a event changes a node but is not signed with the key of that node
+var ErrNotNodeOwner = errors.New("Only the node itself can change its node entity")
+
 //A Node is a node as defined by the AbuseMesh protocol[inset link to docs] with extra information internal to the node
 type Node struct {
 	UUID            uuid.UUID
@@ -22,6 +26,9 @@ type Node struct {
 	ContactDetails  abusemesh.ContactDetails
 	ASN             int32
 	PGPEntity       *openpgp.Entity
+
+	//Equivocated is true if the node has signed conflicting events, see EquivocationProof
+	Equivocated bool
 }
 
 //ToProtobuf converts the node struct into a protobuf stub
@@ -60,57 +67,169 @@ func (node Node) ToProtobuf() (*abusemesh.Node, error) {
 
 //NodeFromProtobuf creates a node object from the node stub of the protobuf definition
 func NodeFromProtobuf(protobufNode *abusemesh.Node) (Node, error) {
-	uuid, err := conv.AuuidToGuuid(protobufNode.Uuid)
+	uuid, err := conv.AuuidToGuuid(protobufNode.GetUuid())
 	if err != nil {
 		return Node{}, err
 	}
 
 	//Read the packets into a entity which can be used to check signatures
 	var pgpEntity *openpgp.Entity
-	pgpEntity, err = pgp.PGPEntityFromBytes(protobufNode.PgpEntity.PgpPackets)
+	pgpEntity, err = pgp.PGPEntityFromBytes(protobufNode.GetPgpEntity().GetPgpPackets())
 	if err != nil {
 		return Node{}, errors.WithStack(err)
 	}
 
+	var contactDetails abusemesh.ContactDetails
+	if protobufNode.GetContactDetails() != nil {
+		contactDetails = *protobufNode.GetContactDetails()
+	}
+
 	return Node{
 		UUID:            uuid,
-		ProtocolVersion: protobufNode.ProtocolVersion,
-		IPAddress:       net.ParseIP(protobufNode.IpAddress.Address),
-		ContactDetails:  *protobufNode.ContactDetails,
-		ASN:             protobufNode.ASN,
+		ProtocolVersion: protobufNode.GetProtocolVersion(),
+		IPAddress:       net.ParseIP(protobufNode.GetIpAddress().GetAddress()),
+		ContactDetails:  contactDetails,
+		ASN:             protobufNode.GetASN(),
 		PGPEntity:       pgpEntity,
 	}, nil
 }
 
+//validateNode checks that a event about a node is signed by the node itself
+//Changes to a known node must be signed with the key in the node table, so only the node itself can replace its key.
+//A node which is not known yet signs its announcement with the key it announces.
+func validateNode(event *GenericEvent, tableSet *TableSet, protobufNode *abusemesh.Node) error {
+	nodeID, err := conv.AuuidToGuuid(protobufNode.GetUuid())
+	if err != nil {
+		return errors.Wrap(err, "Node ID invalid")
+	}
+
+	if owner := tableSet.getNode(nodeID); owner != nil {
+		return event.verifyOwnedBy(owner, ErrNotNodeOwner)
+	}
+
+	//There is no key to check the deletion of a unknown node with
+	if event.UpdateType == abusemesh.TableEventType_TABLE_UPDATE_DELETE {
+		return ErrUnknownSigner
+	}
+
+	announced, err := NodeFromProtobuf(protobufNode)
+	if err != nil {
+		return errors.Wrap(err, "Node invalid")
+	}
+
+	return event.verifyOwnedBy(&announced, ErrNotNodeOwner)
+}
+
+//A nodeIndex maps a key to the nodes which have that key
+type nodeIndex map[string]map[uuid.UUID]struct{}
+
+func (index nodeIndex) add(key string, nodeID uuid.UUID) {
+	nodes, found := index[key]
+	if !found {
+		nodes = make(map[uuid.UUID]struct{})
+		index[key] = nodes
+	}
+	nodes[nodeID] = struct{}{}
+}
+
+func (index nodeIndex) remove(key string, nodeID uuid.UUID) {
+	delete(index[key], nodeID)
+
+	if len(index[key]) == 0 {
+		delete(index, key)
+	}
+}
+
+//lookup returns the ids of the nodes with the key sorted by UUID
+func (index nodeIndex) lookup(key string) []uuid.UUID {
+	nodeIDs := make([]uuid.UUID, 0, len(index[key]))
+	for nodeID := range index[key] {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sortUUIDs(nodeIDs)
+
+	return nodeIDs
+}
+
+//asnKey, ipKey and fingerprintKey return the keys under which nodes are indexed
+func asnKey(asn int32) string {
+	return strconv.FormatInt(int64(asn), 10)
+}
+
+func ipKey(ip net.IP) string {
+	//To16 makes sure a IPv4 address and its IPv4-mapped IPv6 form get the same key
+	return string(ip.To16())
+}
+
+func fingerprintKey(fingerprint [20]byte) string {
+	return string(fingerprint[:])
+}
+
+//fingerprints returns the fingerprints of the primary key and all subkeys of the entity
+func fingerprints(entity *openpgp.Entity) [][20]byte {
+	if entity == nil || entity.PrimaryKey == nil {
+		return nil
+	}
+
+	keyFingerprints := [][20]byte{entity.PrimaryKey.Fingerprint}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PublicKey != nil {
+			keyFingerprints = append(keyFingerprints, subkey.PublicKey.Fingerprint)
+		}
+	}
+
+	return keyFingerprints
+}
+
 //A NodeTable holds the current derived state of all nodes in the network known to the current node
 type NodeTable struct {
 	Entities map[uuid.UUID]Node
+
+	//byASN, byIP and byFingerprint index the nodes on their ASN, IP address and the fingerprints of their PGP keys
+	//Multiple nodes can claim the same key, so every key maps to a set of nodes
+	byASN         nodeIndex
+	byIP          nodeIndex
+	byFingerprint nodeIndex
+
+	//equivocators are the nodes which have signed conflicting events, including nodes which are not in the table
+	equivocators map[uuid.UUID]struct{}
+
+	//shared is true if Entities is part of a snapshot and has to be copied before it is written
+	shared bool
+
+	//changes records the changes to Entities
+	changes *changeSet
 }
 
-func (table *NodeTable) handleTableEvent(eventType abusemesh.TableEventType, entity *abusemesh.TableEvent_Node) error {
-	switch eventType {
-	case abusemesh.TableEventType_TABLE_UPDATE_NEW:
-		node, err := NodeFromProtobuf(entity.Node)
+//handleTableEvent applies the event to the node table
+//The event was validated against the node table before it was applied, but a other event about the node may have
+//been applied in between, so a event about a known node is checked again against the key in the table
+func (table *NodeTable) handleTableEvent(event *GenericEvent, entity *abusemesh.TableEvent_Node) error {
+	nodeID, err := conv.AuuidToGuuid(entity.Node.GetUuid())
+	if err != nil {
+		return err
+	}
+
+	if owner, found := table.Entities[nodeID]; found {
+		err := event.verifyOwnedBy(&owner, ErrNotNodeOwner)
 		if err != nil {
 			return err
 		}
+	}
 
-		table.Entities[node.UUID] = node
-
-	case abusemesh.TableEventType_TABLE_UPDATE_EDIT:
+	switch eventType := event.UpdateType; eventType {
+	case abusemesh.TableEventType_TABLE_UPDATE_NEW, abusemesh.TableEventType_TABLE_UPDATE_EDIT:
 		node, err := NodeFromProtobuf(entity.Node)
 		if err != nil {
 			return err
 		}
 
-		table.Entities[node.UUID] = node
+		//A edit may change any of the indexed fields
+		table.remove(node.UUID)
+		table.add(node)
 
 	case abusemesh.TableEventType_TABLE_UPDATE_DELETE:
-		uuid, err := conv.AuuidToGuuid(entity.Node.Uuid)
-		if err != nil {
-			return err
-		}
-		delete(table.Entities, uuid)
+		table.remove(nodeID)
 
 	default:
 		return errors.Errorf("Unknown abusemesh.TableEventType type '%T'", eventType)
@@ -119,6 +238,85 @@ func (table *NodeTable) handleTableEvent(eventType abusemesh.TableEventType, ent
 	return nil
 }
 
+//add adds a node to the table and the indexes
+func (table *NodeTable) add(node Node) {
+	//A node can't clear its flag by announcing itself again
+	if _, found := table.equivocators[node.UUID]; found {
+		node.Equivocated = true
+	}
+
+	table.own()
+	table.changes.record(TableNodes, node.UUID.String(), nil, node)
+	table.Entities[node.UUID] = node
+
+	if node.IPAddress != nil {
+		for otherID := range table.byIP[ipKey(node.IPAddress)] {
+			logrus.WithFields(logrus.Fields{
+				"node":       node.UUID.String(),
+				"other-node": otherID.String(),
+				"ip-address": node.IPAddress.String(),
+			}).Warn("Multiple nodes claim the same IP address")
+		}
+
+		table.byIP.add(ipKey(node.IPAddress), node.UUID)
+	}
+
+	table.byASN.add(asnKey(node.ASN), node.UUID)
+
+	for _, fingerprint := range fingerprints(node.PGPEntity) {
+		table.byFingerprint.add(fingerprintKey(fingerprint), node.UUID)
+	}
+}
+
+//remove deletes a node from the table and the indexes
+func (table *NodeTable) remove(nodeID uuid.UUID) {
+	node, found := table.Entities[nodeID]
+	if !found {
+		return
+	}
+
+	table.own()
+	table.changes.record(TableNodes, nodeID.String(), node, nil)
+	delete(table.Entities, nodeID)
+
+	if node.IPAddress != nil {
+		table.byIP.remove(ipKey(node.IPAddress), nodeID)
+	}
+
+	table.byASN.remove(asnKey(node.ASN), nodeID)
+
+	for _, fingerprint := range fingerprints(node.PGPEntity) {
+		table.byFingerprint.remove(fingerprintKey(fingerprint), nodeID)
+	}
+}
+
+//flagEquivocation flags the node as a node which has signed conflicting events
+func (table *NodeTable) flagEquivocation(nodeID uuid.UUID) {
+	table.equivocators[nodeID] = struct{}{}
+
+	node, found := table.Entities[nodeID]
+	if !found || node.Equivocated {
+		return
+	}
+
+	flagged := node
+	flagged.Equivocated = true
+
+	table.own()
+	table.changes.record(TableNodes, nodeID.String(), node, flagged)
+	table.Entities[nodeID] = flagged
+}
+
+//nodes returns the nodes with the given ids
+func (table *NodeTable) nodes(nodeIDs []uuid.UUID) []Node {
+	nodes := make([]Node, 0, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		nodes = append(nodes, table.Entities[nodeID])
+	}
+
+	return nodes
+}
+
 //GetNodeRequest can be used to request a specific node from a table
 type GetNodeRequest struct {
 	ResponseChan chan<- *Node
@@ -137,6 +335,19 @@ func (req *GetNodeRequest) Process(tables *TableSet) error {
 	return nil
 }
 
+//getNode queries the node table for the node with the given id, nil is returned if the node is unknown
+//NOTE: must not be called from the goroutine of the TableSet since it waits for the request to be processed
+func (set *TableSet) getNode(nodeID uuid.UUID) *Node {
+	responseChan := make(chan *Node, 1)
+
+	set.Channel <- &GetNodeRequest{
+		ResponseChan: responseChan,
+		NodeID:       nodeID,
+	}
+
+	return <-responseChan
+}
+
 //GetAllNodesRequest can be used to request all nodes from the nodes table
 type GetAllNodesRequest struct {
 	//ResponseChan is the channel over which multiple nodes will be sent
@@ -146,19 +357,70 @@ type GetAllNodesRequest struct {
 	Context context.Context
 }
 
-//Process processes the request and sends a slice of nodes on the ResponseChan or nil if the node was not found
+//Process processes the request and sends all nodes on the ResponseChan, the channel is closed when all nodes are sent
+//The nodes are sent from a snapshot in a separate goroutine, so a slow requester doesn't block the TableSet
 func (req *GetAllNodesRequest) Process(tables *TableSet) error {
-	defer close(req.ResponseChan)
+	snapshot := tables.takeSnapshot()
 
-	for _, node := range tables.nodeTable.Entities {
-		//Of the requester no longer wishes to receive nodes we return
-		if req.Context.Err() != nil {
+	go func() {
+		defer close(req.ResponseChan)
 
-			return nil
+		for _, node := range snapshot.Nodes {
+			select {
+			case req.ResponseChan <- node:
+			//If the requester no longer wishes to receive nodes we return
+			case <-req.Context.Done():
+				return
+			}
 		}
+	}()
 
-		req.ResponseChan <- node
-	}
+	return nil
+}
+
+//GetNodesByASNRequest can be used to request the nodes which belong to a ASN
+type GetNodesByASNRequest struct {
+	//ResponseChan receives the nodes sorted by UUID, the slice is empty if no node has the ASN
+	ResponseChan chan<- []Node
+	ASN          int32
+}
+
+//Process processes the request and sends the nodes with the ASN on the ResponseChan
+func (req *GetNodesByASNRequest) Process(tables *TableSet) error {
+	table := &tables.nodeTable
+	req.ResponseChan <- table.nodes(table.byASN.lookup(asnKey(req.ASN)))
+
+	return nil
+}
+
+//GetNodesByIPRequest can be used to request the nodes which claim a IP address
+//Normally at most one node is returned, more than one node means multiple nodes claim the same address
+type GetNodesByIPRequest struct {
+	//ResponseChan receives the nodes sorted by UUID, the slice is empty if no node has the IP address
+	ResponseChan chan<- []Node
+	IPAddress    net.IP
+}
+
+//Process processes the request and sends the nodes with the IP address on the ResponseChan
+func (req *GetNodesByIPRequest) Process(tables *TableSet) error {
+	table := &tables.nodeTable
+	req.ResponseChan <- table.nodes(table.byIP.lookup(ipKey(req.IPAddress)))
+
+	return nil
+}
+
+//GetNodesByFingerprintRequest can be used to request the nodes which use a PGP key
+//Both the fingerprints of primary keys and of subkeys are matched
+type GetNodesByFingerprintRequest struct {
+	//ResponseChan receives the nodes sorted by UUID, the slice is empty if no node uses the key
+	ResponseChan chan<- []Node
+	Fingerprint  [20]byte
+}
+
+//Process processes the request and sends the nodes with the key on the ResponseChan
+func (req *GetNodesByFingerprintRequest) Process(tables *TableSet) error {
+	table := &tables.nodeTable
+	req.ResponseChan <- table.nodes(table.byFingerprint.lookup(fingerprintKey(req.Fingerprint)))
 
 	return nil
 }
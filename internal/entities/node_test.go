@@ -0,0 +1,144 @@
+package entities
+
+import (
+	"net"
+	"testing"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/google/uuid"
+)
+
+//nodeEvent returns a signed event about the node with the given ASN and IP address
+func (node testNode) nodeEvent(t *testing.T, updateType abusemesh.TableEventType, asn int32, address string) *GenericEvent {
+	message := node.message(t)
+	message.ASN = asn
+	message.IpAddress = &abusemesh.IPAddress{Address: address}
+
+	event, err := node.author.NodeEvent(updateType, message)
+	if err != nil {
+		t.Fatalf("Error while signing node event: %s", err)
+	}
+
+	return event
+}
+
+//lookupNodes returns the ids of the nodes found by the request, the request must send its result on responseChan
+func lookupNodes(tableSet *TableSet, responseChan chan []Node, request TableRequest) []uuid.UUID {
+	tableSet.Channel <- request
+
+	nodes := <-responseChan
+	ids := make([]uuid.UUID, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.UUID
+	}
+
+	return ids
+}
+
+//A nodeLookup is a lookup of the node indexes and the ids of the nodes it should find
+type nodeLookup struct {
+	name   string
+	ids    func() []uuid.UUID
+	expect []uuid.UUID
+}
+
+//The indexes on ASN, IP address and PGP key follow the NEW, EDIT and DELETE events of nodes
+func Test_NodeTable_Indexes(t *testing.T) {
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	first := newTestNode(t, "node-a")
+	second := newTestNode(t, "node-b")
+
+	responseChan := make(chan []Node, 1)
+	byASN := func(asn int32) func() []uuid.UUID {
+		return func() []uuid.UUID {
+			return lookupNodes(tableSet, responseChan, &GetNodesByASNRequest{ResponseChan: responseChan, ASN: asn})
+		}
+	}
+	byIP := func(address string) func() []uuid.UUID {
+		return func() []uuid.UUID {
+			return lookupNodes(tableSet, responseChan, &GetNodesByIPRequest{ResponseChan: responseChan, IPAddress: net.ParseIP(address)})
+		}
+	}
+	byFingerprint := func(fingerprint [20]byte) func() []uuid.UUID {
+		return func() []uuid.UUID {
+			return lookupNodes(tableSet, responseChan, &GetNodesByFingerprintRequest{ResponseChan: responseChan, Fingerprint: fingerprint})
+		}
+	}
+
+	both := []uuid.UUID{first.id, second.id}
+	sortUUIDs(both)
+
+	//Every step applies its events and then checks the lookups against the resulting tables
+	tests := []struct {
+		name    string
+		events  []*GenericEvent
+		lookups []nodeLookup
+	}{
+		{
+			name: "announce",
+			events: []*GenericEvent{
+				first.nodeEvent(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, 64500, "192.0.2.1"),
+				second.nodeEvent(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, 64500, "192.0.2.2"),
+			},
+			lookups: []nodeLookup{
+				{name: "asn", ids: byASN(64500), expect: both},
+				{name: "ip", ids: byIP("192.0.2.1"), expect: []uuid.UUID{first.id}},
+				{name: "ipv4-mapped ip", ids: byIP("::ffff:192.0.2.2"), expect: []uuid.UUID{second.id}},
+				{name: "fingerprint", ids: byFingerprint(first.key.PrimaryKey.Fingerprint), expect: []uuid.UUID{first.id}},
+				{name: "subkey fingerprint", ids: byFingerprint(first.key.Subkeys[0].PublicKey.Fingerprint), expect: []uuid.UUID{first.id}},
+			},
+		},
+		{
+			//A edit moves the node to its new ASN and IP address
+			name: "edit",
+			events: []*GenericEvent{
+				first.nodeEvent(t, abusemesh.TableEventType_TABLE_UPDATE_EDIT, 64501, "192.0.2.3"),
+			},
+			lookups: []nodeLookup{
+				{name: "old asn", ids: byASN(64500), expect: []uuid.UUID{second.id}},
+				{name: "new asn", ids: byASN(64501), expect: []uuid.UUID{first.id}},
+				{name: "old ip", ids: byIP("192.0.2.1")},
+				{name: "new ip", ids: byIP("192.0.2.3"), expect: []uuid.UUID{first.id}},
+				{name: "fingerprint", ids: byFingerprint(first.key.PrimaryKey.Fingerprint), expect: []uuid.UUID{first.id}},
+			},
+		},
+		{
+			//A second node claiming the same address is indexed next to the first
+			name: "shared ip",
+			events: []*GenericEvent{
+				second.nodeEvent(t, abusemesh.TableEventType_TABLE_UPDATE_EDIT, 64500, "192.0.2.3"),
+			},
+			lookups: []nodeLookup{
+				{name: "ip", ids: byIP("192.0.2.3"), expect: both},
+			},
+		},
+		{
+			name: "delete",
+			events: []*GenericEvent{
+				first.nodeEvent(t, abusemesh.TableEventType_TABLE_UPDATE_DELETE, 64501, "192.0.2.3"),
+			},
+			lookups: []nodeLookup{
+				{name: "asn", ids: byASN(64501)},
+				{name: "ip", ids: byIP("192.0.2.3"), expect: []uuid.UUID{second.id}},
+				{name: "fingerprint", ids: byFingerprint(first.key.PrimaryKey.Fingerprint)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, event := range tt.events {
+				err := applyEvent(tableSet, event)
+				if err != nil {
+					t.Fatalf("Error while applying node event: %s", err)
+				}
+			}
+
+			for _, lookup := range tt.lookups {
+				assertReportIDs(t, lookup.name, lookup.ids(), lookup.expect...)
+			}
+		})
+	}
+}
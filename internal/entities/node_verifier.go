@@ -0,0 +1,305 @@
+package entities
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/pgp"
+	"github.com/abuse-mesh/abuse-mesh-go/pkg/client"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+var (
+	//ErrNodeUnreachable signals that the announced node could not be contacted to confirm its claims
+	ErrNodeUnreachable = errors.New("The announced node could not be contacted")
+
+	//ErrNodeClaimMismatch signals that the announced node responded with different data than was claimed in the event
+	ErrNodeClaimMismatch = errors.New("The announced node doesn't confirm the claims of the event")
+
+	//ErrNodeVerificationPending signals that the announced node is being contacted, the announcement is validated again once that is done
+	ErrNodeVerificationPending = errors.New("The announced node has not been verified yet")
+)
+
+const (
+	//DefaultNodeVerificationTimeout is the Timeout of the policy if the policy doesn't specify one
+	DefaultNodeVerificationTimeout = 5 * time.Second
+
+	//DefaultNodeVerificationCacheTTL is the CacheTTL of the policy if the policy doesn't specify one
+	DefaultNodeVerificationCacheTTL = 5 * time.Minute
+
+	//DefaultNodeVerificationCacheSize is the CacheSize of the policy if the policy doesn't specify one
+	DefaultNodeVerificationCacheSize = 10000
+)
+
+//verifiedQueueSize is the amount of finished verifications which can be queued before the event stream picks them up
+const verifiedQueueSize = 100
+
+//NodeVerifierPolicy determines how announced nodes are contacted and how long the results are reused
+type NodeVerifierPolicy struct {
+	//The TCP port on which announced nodes are contacted, client.DefaultPort if not specified
+	Port int
+
+	//How long contacting a announced node may take
+	Timeout time.Duration
+
+	//How long a verification result is reused for announcements with the same claims
+	CacheTTL time.Duration
+
+	//The maximum amount of verification results which are kept
+	CacheSize int
+}
+
+//A NodeVerifier confirms that a announced node exists and that the claims made about it are true
+type NodeVerifier interface {
+	//VerifyNode returns nil if the claims are confirmed or a error containing the reason they are not
+	VerifyNode(claimed *abusemesh.Node) error
+}
+
+//A verificationNotifier is a NodeVerifier which contacts announced nodes in the background.
+//Announcements are refused with ErrNodeVerificationPending until the node has been contacted, after which the UUID
+//of the node is sent on the verified channel so the event stream can validate its quarantined announcements again
+type verificationNotifier interface {
+	NodeVerifier
+
+	verified() <-chan string
+}
+
+//nodeVerification is the cached result of a verification attempt
+type nodeVerification struct {
+	//The claims which were verified
+	claimed *abusemesh.Node
+	//The result of the verification, nil if the claims were confirmed
+	err error
+	//The time at which the verification was done
+	verifiedAt time.Time
+}
+
+//pendingVerification is a verification which is in progress, other announcements of the same claims don't start another one
+type pendingVerification struct {
+	//The claims which are verified
+	claimed *abusemesh.Node
+}
+
+//The callbackNodeVerifier implements NodeVerifier by contacting the node on the IP address it announced
+//and comparing the node data it returns with the announced claims
+type callbackNodeVerifier struct {
+	policy NodeVerifierPolicy
+
+	//The options used to dial the announced node
+	dialOptions []grpc.DialOption
+
+	//Contacts the announced node and compares its claims, callback unless replaced by tests
+	verify func(claimed *abusemesh.Node) error
+
+	//Receives the UUID of every node whose verification is done
+	verifiedChan chan string
+
+	//Recent verification results indexed by the UUID of the announced node
+	cache map[string]nodeVerification
+
+	//The verifications in progress indexed by the UUID of the announced node
+	pending map[string]*pendingVerification
+
+	//A mutex lock for the cache and the pending verifications, it is not held while contacting a node
+	cacheLock sync.Mutex
+}
+
+//NewCallbackNodeVerifier creates a NodeVerifier which confirms announcements by calling back the announced node
+//Nodes are contacted in the background so a unreachable node doesn't hold up the event stream.
+//Results are cached for the CacheTTL so a flood of announcements doesn't result in a flood of connections
+func NewCallbackNodeVerifier(policy NodeVerifierPolicy, dialOptions ...grpc.DialOption) NodeVerifier {
+	if policy.Port <= 0 {
+		policy.Port = client.DefaultPort
+	}
+	if policy.Timeout <= 0 {
+		policy.Timeout = DefaultNodeVerificationTimeout
+	}
+	if policy.CacheTTL <= 0 {
+		policy.CacheTTL = DefaultNodeVerificationCacheTTL
+	}
+	if policy.CacheSize <= 0 {
+		policy.CacheSize = DefaultNodeVerificationCacheSize
+	}
+
+	verifier := &callbackNodeVerifier{
+		policy:       policy,
+		dialOptions:  dialOptions,
+		verifiedChan: make(chan string, verifiedQueueSize),
+		cache:        make(map[string]nodeVerification),
+		pending:      make(map[string]*pendingVerification),
+	}
+	verifier.verify = verifier.callback
+
+	return verifier
+}
+
+//VerifyNode returns the result of a recent verification of the same claims,
+//otherwise it starts contacting the node and returns ErrNodeVerificationPending
+func (verifier *callbackNodeVerifier) VerifyNode(claimed *abusemesh.Node) error {
+	cacheKey := claimed.GetUuid().GetUuid()
+
+	verifier.cacheLock.Lock()
+	defer verifier.cacheLock.Unlock()
+
+	//Reuse the result of a recent verification of the exact same claims
+	if cached, found := verifier.cache[cacheKey]; found {
+		if time.Since(cached.verifiedAt) < verifier.policy.CacheTTL && proto.Equal(cached.claimed, claimed) {
+			return cached.err
+		}
+	}
+
+	//A verification of the same claims is already in progress, we don't contact the node again
+	if pending, found := verifier.pending[cacheKey]; found && proto.Equal(pending.claimed, claimed) {
+		return ErrNodeVerificationPending
+	}
+
+	pending := &pendingVerification{
+		claimed: claimed,
+	}
+	verifier.pending[cacheKey] = pending
+
+	//Contacting the node can take a while, the event stream continues in the mean time
+	go verifier.run(cacheKey, pending)
+
+	return ErrNodeVerificationPending
+}
+
+func (verifier *callbackNodeVerifier) verified() <-chan string {
+	return verifier.verifiedChan
+}
+
+//run verifies the claims of the pending verification, stores the result and notifies the event stream
+func (verifier *callbackNodeVerifier) run(cacheKey string, pending *pendingVerification) {
+	err := verifier.verify(pending.claimed)
+
+	verifier.cacheLock.Lock()
+	verifier.storeResult(cacheKey, nodeVerification{
+		claimed:    pending.claimed,
+		err:        err,
+		verifiedAt: time.Now(),
+	})
+
+	//A verification of different claims may have replaced this one in the mean time
+	if verifier.pending[cacheKey] == pending {
+		delete(verifier.pending, cacheKey)
+	}
+	verifier.cacheLock.Unlock()
+
+	select {
+	case verifier.verifiedChan <- cacheKey:
+	default:
+		logrus.WithField("node", cacheKey).Warn("Too many node verifications finished at once, the announcements of the node have to be revalidated manually")
+	}
+}
+
+//storeResult adds a verification result to the cache and evicts entries if the cache is full
+//The cacheLock must be held by the caller
+func (verifier *callbackNodeVerifier) storeResult(key string, result nodeVerification) {
+	if _, found := verifier.cache[key]; !found && len(verifier.cache) >= verifier.policy.CacheSize {
+		//First remove all expired results
+		for cachedKey, cached := range verifier.cache {
+			if time.Since(cached.verifiedAt) >= verifier.policy.CacheTTL {
+				delete(verifier.cache, cachedKey)
+			}
+		}
+
+		//If the cache is still full we evict a random result
+		for cachedKey := range verifier.cache {
+			if len(verifier.cache) < verifier.policy.CacheSize {
+				break
+			}
+			delete(verifier.cache, cachedKey)
+		}
+	}
+
+	verifier.cache[key] = result
+}
+
+//callback contacts the announced node and compares its response with the claims
+func (verifier *callbackNodeVerifier) callback(claimed *abusemesh.Node) error {
+	ip := net.ParseIP(claimed.GetIpAddress().GetAddress())
+	if ip == nil {
+		return errors.Wrapf(ErrNodeClaimMismatch, "'%s' is not a valid IP address", claimed.GetIpAddress().GetAddress())
+	}
+
+	address := net.JoinHostPort(ip.String(), strconv.Itoa(verifier.policy.Port))
+
+	//The whole call, including connecting to the node, is bounded by the timeout of the policy
+	timeout := grpc.WithUnaryInterceptor(func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		conn *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		ctx, cancel := context.WithTimeout(ctx, verifier.policy.Timeout)
+		defer cancel()
+
+		return invoker(ctx, method, req, reply, conn, opts...)
+	})
+
+	abuseMeshClient, err := client.DialAbuseMeshClient(address, append(verifier.dialOptions, timeout)...)
+	if err != nil {
+		return errors.Wrap(ErrNodeUnreachable, err.Error())
+	}
+	defer abuseMeshClient.Close()
+
+	actual, err := abuseMeshClient.GetNode(&abusemesh.GetNodeRequest{})
+	if err != nil {
+		logrus.WithError(err).WithField("address", address).Info("Unable to contact announced node")
+		return errors.Wrap(ErrNodeUnreachable, err.Error())
+	}
+
+	return compareNodeClaims(claimed, actual)
+}
+
+//compareNodeClaims returns a ErrNodeClaimMismatch if the node data returned by the node differs from the claimed data
+func compareNodeClaims(claimed, actual *abusemesh.Node) error {
+	mismatch := func(field string, claimedValue, actualValue interface{}) error {
+		return errors.Wrap(ErrNodeClaimMismatch, fmt.Sprintf("%s claimed '%v' but node reported '%v'", field, claimedValue, actualValue))
+	}
+
+	if claimed.GetUuid().GetUuid() != actual.GetUuid().GetUuid() {
+		return mismatch("UUID", claimed.GetUuid().GetUuid(), actual.GetUuid().GetUuid())
+	}
+
+	if claimed.GetASN() != actual.GetASN() {
+		return mismatch("ASN", claimed.GetASN(), actual.GetASN())
+	}
+
+	if !net.ParseIP(claimed.GetIpAddress().GetAddress()).Equal(net.ParseIP(actual.GetIpAddress().GetAddress())) {
+		return mismatch("IP address", claimed.GetIpAddress().GetAddress(), actual.GetIpAddress().GetAddress())
+	}
+
+	if !proto.Equal(claimed.GetContactDetails(), actual.GetContactDetails()) {
+		return mismatch("Contact details", claimed.GetContactDetails(), actual.GetContactDetails())
+	}
+
+	claimedEntity, err := pgp.PGPEntityFromBytes(claimed.GetPgpEntity().GetPgpPackets())
+	if err != nil {
+		return errors.Wrap(ErrNodeClaimMismatch, "Claimed PGP entity is invalid")
+	}
+
+	actualEntity, err := pgp.PGPEntityFromBytes(actual.GetPgpEntity().GetPgpPackets())
+	if err != nil {
+		return errors.Wrap(ErrNodeClaimMismatch, "PGP entity reported by node is invalid")
+	}
+
+	if claimedEntity.PrimaryKey.Fingerprint != actualEntity.PrimaryKey.Fingerprint {
+		return mismatch("PGP fingerprint",
+			fmt.Sprintf("%X", claimedEntity.PrimaryKey.Fingerprint),
+			fmt.Sprintf("%X", actualEntity.PrimaryKey.Fingerprint),
+		)
+	}
+
+	return nil
+}
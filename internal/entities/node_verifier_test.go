@@ -0,0 +1,92 @@
+package entities
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/pkg/errors"
+)
+
+//A announcement is quarantined while its node is contacted and validated again once the result is known
+func Test_EventStream_PendingNodeVerification(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   error
+		accepted bool
+	}{
+		{name: "confirmed", result: nil, accepted: true},
+		{name: "mismatch", result: ErrNodeClaimMismatch, accepted: false},
+		{name: "unreachable", result: ErrNodeUnreachable, accepted: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tableSet := NewTableSet(100)
+
+			//The node is only "contacted" once the test releases it
+			release := make(chan struct{})
+			calls := make(chan struct{}, 10)
+			verifier := NewCallbackNodeVerifier(NodeVerifierPolicy{}).(*callbackNodeVerifier)
+			verifier.verify = func(*abusemesh.Node) error {
+				calls <- struct{}{}
+				<-release
+				return tt.result
+			}
+			tableSet.NodeVerifier = verifier
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go tableSet.Run(ctx)
+
+			stream := newTestEventStream(t, tableSet)
+			stream.Attach(tableSet)
+			go stream.Run(ctx)
+
+			node := newTestNode(t, "node-a")
+			announcement := node.announce(t)
+
+			stream.GetWriteChannel() <- announcement
+
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				quarantined, found := stream.GetQuarantinedEvent(announcement.GetID())
+				if found && errors.Cause(quarantined.Reason) == ErrNodeVerificationPending {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("Expected the announcement to be quarantined while the node is verified")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			//A copy of the announcement doesn't contact the node again
+			err := verifier.VerifyNode(announcement.GetNode())
+			if err != ErrNodeVerificationPending {
+				t.Errorf("Expected the verification to be pending, got %v", err)
+			}
+
+			close(release)
+
+			deadline = time.Now().Add(5 * time.Second)
+			for {
+				quarantined, found := stream.GetQuarantinedEvent(announcement.GetID())
+				if tt.accepted && !found && tableSet.getNode(node.id) != nil {
+					break
+				}
+				if !tt.accepted && found && errors.Cause(quarantined.Reason) == tt.result {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("Expected the announcement to be revalidated once the node was verified")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			if len(calls) != 1 {
+				t.Errorf("Expected the node to be contacted once, got %d", len(calls))
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package entities
+
+import (
+	"net"
+
+	"github.com/google/uuid"
+)
+
+//PrefixMatch determines which prefixes match a prefix query
+type PrefixMatch int
+
+const (
+	//PrefixMatchExact matches only the queried prefix itself
+	PrefixMatchExact PrefixMatch = iota
+
+	//PrefixMatchCovering matches the queried prefix and all less specific prefixes which contain it,
+	//the longest match comes first
+	PrefixMatchCovering
+
+	//PrefixMatchCoveredBy matches the queried prefix and all more specific prefixes within it
+	PrefixMatchCoveredBy
+)
+
+//A prefixNode is a node in a path compressed binary trie (patricia trie) of IP prefixes
+//Nodes without reports only exist to join two branches
+type prefixNode struct {
+	//The masked address of the prefix
+	ip net.IP
+
+	//The prefix length
+	length int
+
+	children [2]*prefixNode
+
+	//The reports for exactly this prefix
+	reports map[uuid.UUID]struct{}
+}
+
+//bit returns the bit of the address at the given position, 0 being the most significant bit
+func bit(ip net.IP, position int) int {
+	return int(ip[position/8]>>(7-uint(position%8))) & 1
+}
+
+//commonLength returns the amount of leading bits which are equal in both addresses, up to max
+func commonLength(a, b net.IP, max int) int {
+	for i := 0; i < max; i++ {
+		if bit(a, i) != bit(b, i) {
+			return i
+		}
+	}
+
+	return max
+}
+
+func minLength(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+//covers returns true if the prefix of the node contains the given prefix
+func (node *prefixNode) covers(ip net.IP, length int) bool {
+	return node.length <= length && commonLength(node.ip, ip, node.length) == node.length
+}
+
+//insertPrefix adds the report to the prefix in the trie and returns the new root of the trie
+func insertPrefix(node *prefixNode, ip net.IP, length int, reportID uuid.UUID) *prefixNode {
+	if node == nil {
+		return &prefixNode{
+			ip:      ip,
+			length:  length,
+			reports: map[uuid.UUID]struct{}{reportID: {}},
+		}
+	}
+
+	common := commonLength(node.ip, ip, minLength(node.length, length))
+
+	//The node contains the prefix, add it to the node or descend
+	if common == node.length {
+		if length == node.length {
+			if node.reports == nil {
+				node.reports = make(map[uuid.UUID]struct{})
+			}
+			node.reports[reportID] = struct{}{}
+
+			return node
+		}
+
+		branch := bit(ip, node.length)
+		node.children[branch] = insertPrefix(node.children[branch], ip, length, reportID)
+
+		return node
+	}
+
+	leaf := &prefixNode{
+		ip:      ip,
+		length:  length,
+		reports: map[uuid.UUID]struct{}{reportID: {}},
+	}
+
+	//The prefix contains the node, the prefix becomes the parent of the node
+	if common == length {
+		leaf.children[bit(node.ip, length)] = node
+		return leaf
+	}
+
+	//The prefix and node diverge, they are joined by a node for the prefix they have in common
+	join := &prefixNode{
+		ip:     ip.Mask(net.CIDRMask(common, len(ip)*8)),
+		length: common,
+	}
+	join.children[bit(node.ip, common)] = node
+	join.children[bit(ip, common)] = leaf
+
+	return join
+}
+
+//removePrefix removes the report from the prefix in the trie and returns the new root of the trie
+func removePrefix(node *prefixNode, ip net.IP, length int, reportID uuid.UUID) *prefixNode {
+	if node == nil || !node.covers(ip, length) {
+		return node
+	}
+
+	if node.length == length {
+		delete(node.reports, reportID)
+	} else {
+		branch := bit(ip, node.length)
+		node.children[branch] = removePrefix(node.children[branch], ip, length, reportID)
+	}
+
+	//Nodes without reports are only kept if they join two branches
+	if len(node.reports) > 0 {
+		return node
+	}
+
+	switch {
+	case node.children[0] != nil && node.children[1] != nil:
+		return node
+	case node.children[0] != nil:
+		return node.children[0]
+	default:
+		return node.children[1]
+	}
+}
+
+//collect appends the reports in the trie below the node to the ids
+func (node *prefixNode) collect(ids []uuid.UUID) []uuid.UUID {
+	if node == nil {
+		return ids
+	}
+
+	ids = appendReports(ids, node.reports)
+	ids = node.children[0].collect(ids)
+
+	return node.children[1].collect(ids)
+}
+
+//appendReports appends the reports of a single prefix in a stable order
+func appendReports(ids []uuid.UUID, reports map[uuid.UUID]struct{}) []uuid.UUID {
+	prefixIDs := make([]uuid.UUID, 0, len(reports))
+	for id := range reports {
+		prefixIDs = append(prefixIDs, id)
+	}
+	sortUUIDs(prefixIDs)
+
+	return append(ids, prefixIDs...)
+}
+
+//A PrefixIndex indexes reports on their IP prefix, one trie is kept per address family
+//All lookups take at most one step per bit of the address
+type PrefixIndex struct {
+	v4 *prefixNode
+	v6 *prefixNode
+}
+
+//root returns the root of the trie for the family of the prefix and the normalized address and length
+func (index *PrefixIndex) root(prefix net.IPNet) (**prefixNode, net.IP, int) {
+	ones, bits := prefix.Mask.Size()
+
+	if ip4 := prefix.IP.To4(); ip4 != nil && bits == 32 {
+		return &index.v4, ip4.Mask(prefix.Mask), ones
+	}
+
+	//IPv4 addresses in a IPv6 prefix are treated as IPv4-mapped IPv6 addresses
+	return &index.v6, prefix.IP.To16().Mask(net.CIDRMask(ones, 128)), ones
+}
+
+//Insert adds the report to the index
+func (index *PrefixIndex) Insert(prefix net.IPNet, reportID uuid.UUID) {
+	root, ip, length := index.root(prefix)
+	if ip == nil {
+		return
+	}
+
+	*root = insertPrefix(*root, ip, length, reportID)
+}
+
+//Remove removes the report from the index
+func (index *PrefixIndex) Remove(prefix net.IPNet, reportID uuid.UUID) {
+	root, ip, length := index.root(prefix)
+	if ip == nil {
+		return
+	}
+
+	*root = removePrefix(*root, ip, length, reportID)
+}
+
+//Lookup returns the ids of the reports with prefixes which match the queried prefix
+func (index *PrefixIndex) Lookup(prefix net.IPNet, match PrefixMatch) []uuid.UUID {
+	root, ip, length := index.root(prefix)
+	if ip == nil {
+		return nil
+	}
+
+	var ids []uuid.UUID
+	node := *root
+
+	switch match {
+	case PrefixMatchExact:
+		for node != nil && node.covers(ip, length) {
+			if node.length == length {
+				return appendReports(ids, node.reports)
+			}
+
+			node = node.children[bit(ip, node.length)]
+		}
+
+	case PrefixMatchCovering:
+		//Walk down while the nodes contain the prefix, every node is more specific than the previous
+		var matches []*prefixNode
+		for node != nil && node.covers(ip, length) {
+			matches = append(matches, node)
+
+			if node.length == length {
+				break
+			}
+
+			node = node.children[bit(ip, node.length)]
+		}
+
+		for i := len(matches) - 1; i >= 0; i-- {
+			ids = appendReports(ids, matches[i].reports)
+		}
+
+	case PrefixMatchCoveredBy:
+		//Walk down to the first node within the prefix, everything below it is within the prefix as well
+		for node != nil && node.length < length {
+			if !node.covers(ip, length) {
+				return nil
+			}
+
+			node = node.children[bit(ip, node.length)]
+		}
+
+		if node != nil && commonLength(node.ip, ip, length) == length {
+			ids = node.collect(ids)
+		}
+	}
+
+	return ids
+}
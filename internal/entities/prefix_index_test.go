@@ -0,0 +1,106 @@
+package entities
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+//mustParsePrefix parses the IP address or network and fails the test if it is invalid
+func mustParsePrefix(t *testing.T, address string) net.IPNet {
+	prefix, err := ParsePrefix(address)
+	if err != nil {
+		t.Fatalf("Error while parsing prefix: %s", err)
+	}
+
+	return prefix
+}
+
+//assertReportIDs checks that the ids are the expected report ids in the same order
+func assertReportIDs(t *testing.T, name string, ids []uuid.UUID, expected ...uuid.UUID) {
+	if len(ids) != len(expected) {
+		t.Errorf("%s: expected %d reports, got %d", name, len(expected), len(ids))
+		return
+	}
+
+	for i, id := range ids {
+		if id != expected[i] {
+			t.Errorf("%s: expected report %d to be %s, got %s", name, i, expected[i], id)
+		}
+	}
+}
+
+//Lookups of a prefix return the reports of the prefix itself, the prefixes containing it or the prefixes within it
+func Test_PrefixIndex_Lookup(t *testing.T) {
+	index := &PrefixIndex{}
+
+	network := uuid.New()
+	subnet := uuid.New()
+	address := uuid.New()
+	otherAddress := uuid.New()
+	v6Network := uuid.New()
+
+	index.Insert(mustParsePrefix(t, "198.51.100.0/24"), network)
+	index.Insert(mustParsePrefix(t, "198.51.100.0/28"), subnet)
+	index.Insert(mustParsePrefix(t, "198.51.100.1"), address)
+	index.Insert(mustParsePrefix(t, "198.51.100.200"), otherAddress)
+	index.Insert(mustParsePrefix(t, "2001:db8::/32"), v6Network)
+
+	assertReportIDs(t, "exact address", index.Lookup(mustParsePrefix(t, "198.51.100.1"), PrefixMatchExact), address)
+	assertReportIDs(t, "exact network", index.Lookup(mustParsePrefix(t, "198.51.100.0/28"), PrefixMatchExact), subnet)
+	assertReportIDs(t, "exact unknown", index.Lookup(mustParsePrefix(t, "198.51.100.2"), PrefixMatchExact))
+
+	//The most specific prefix first
+	assertReportIDs(t, "covering address", index.Lookup(mustParsePrefix(t, "198.51.100.1"), PrefixMatchCovering), address, subnet, network)
+	assertReportIDs(t, "covering unknown address", index.Lookup(mustParsePrefix(t, "198.51.100.2"), PrefixMatchCovering), subnet, network)
+	assertReportIDs(t, "covering outside", index.Lookup(mustParsePrefix(t, "192.0.2.1"), PrefixMatchCovering))
+
+	coveredBy := index.Lookup(mustParsePrefix(t, "198.51.100.0/24"), PrefixMatchCoveredBy)
+	if len(coveredBy) != 4 {
+		t.Errorf("covered by network: expected 4 reports, got %d", len(coveredBy))
+	}
+	assertReportIDs(t, "covered by subnet", index.Lookup(mustParsePrefix(t, "198.51.100.0/28"), PrefixMatchCoveredBy), subnet, address)
+	assertReportIDs(t, "covered by outside", index.Lookup(mustParsePrefix(t, "192.0.2.0/24"), PrefixMatchCoveredBy))
+
+	//The address families are indexed separately
+	assertReportIDs(t, "covering v6", index.Lookup(mustParsePrefix(t, "2001:db8::1"), PrefixMatchCovering), v6Network)
+	assertReportIDs(t, "covered by v6", index.Lookup(mustParsePrefix(t, "::/0"), PrefixMatchCoveredBy), v6Network)
+}
+
+//A IPv4-mapped IPv6 address is the same address as the IPv4 address
+func Test_PrefixIndex_IPv4Mapped(t *testing.T) {
+	index := &PrefixIndex{}
+
+	reportID := uuid.New()
+	index.Insert(mustParsePrefix(t, "::ffff:198.51.100.1"), reportID)
+
+	assertReportIDs(t, "exact", index.Lookup(mustParsePrefix(t, "198.51.100.1"), PrefixMatchExact), reportID)
+	assertReportIDs(t, "covered by", index.Lookup(mustParsePrefix(t, "198.51.100.0/24"), PrefixMatchCoveredBy), reportID)
+}
+
+//Removed reports are no longer returned, other reports about the same prefix are kept
+func Test_PrefixIndex_Remove(t *testing.T) {
+	index := &PrefixIndex{}
+
+	first := uuid.New()
+	second := uuid.New()
+	network := uuid.New()
+
+	index.Insert(mustParsePrefix(t, "198.51.100.1"), first)
+	index.Insert(mustParsePrefix(t, "198.51.100.1"), second)
+	index.Insert(mustParsePrefix(t, "198.51.100.0/24"), network)
+
+	index.Remove(mustParsePrefix(t, "198.51.100.1"), first)
+
+	assertReportIDs(t, "after first removal", index.Lookup(mustParsePrefix(t, "198.51.100.1"), PrefixMatchExact), second)
+
+	index.Remove(mustParsePrefix(t, "198.51.100.1"), second)
+
+	assertReportIDs(t, "after second removal", index.Lookup(mustParsePrefix(t, "198.51.100.1"), PrefixMatchExact))
+	assertReportIDs(t, "network kept", index.Lookup(mustParsePrefix(t, "198.51.100.1"), PrefixMatchCovering), network)
+
+	index.Remove(mustParsePrefix(t, "198.51.100.0/24"), network)
+
+	assertReportIDs(t, "empty", index.Lookup(mustParsePrefix(t, "0.0.0.0/0"), PrefixMatchCoveredBy))
+}
@@ -0,0 +1,294 @@
+package entities
+
+import (
+	"container/list"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go/internal/storage"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+//provenanceKeyPrefix is the prefix of the keys of the provenance records in the storage backend, followed by the event id.
+//The value holds the hops of the event in the order in which they arrived, each encoded as the neighbor id,
+//a byte which is 1 if the hop was accepted and the arrival time in unix nanoseconds
+const provenanceKeyPrefix = "provenance/event/"
+
+//provenanceHopSize is the size of a encoded hop
+const provenanceHopSize = 16 + 1 + 8
+
+//MaxEventHops is the maximum amount of hops recorded per event, a event which is relayed by more neighbors
+//than this is still accepted and suppressed but the extra hops are not recorded
+const MaxEventHops = 16
+
+//MemoryProvenanceRecords is the amount of events of which the provenance is kept if the records are kept in memory
+const MemoryProvenanceRecords = 100000
+
+//A ReceivedEvent is a event which was received from a neighbor
+//Writing a ReceivedEvent to the event stream records where the event came from, the stream itself only stores the wrapped event
+type ReceivedEvent struct {
+	Event
+
+	//The neighbor which sent the event
+	From uuid.UUID
+
+	//The time at which the event arrived
+	ReceivedAt time.Time
+}
+
+//A EventHop records the arrival of a event at this node
+type EventHop struct {
+	//The neighbor which sent the event, uuid.Nil if the event was written locally
+	Neighbor uuid.UUID
+
+	ReceivedAt time.Time
+
+	//True if this copy of the event was accepted into the stream, the other hops delivered duplicates
+	Accepted bool
+}
+
+//EventProvenance describes how a event reached this node, the hops are in the order in which they arrived
+type EventProvenance struct {
+	EventID uuid.UUID
+	Hops    []EventHop
+}
+
+//Source returns the hop through which the event was accepted
+func (provenance EventProvenance) Source() (EventHop, bool) {
+	for _, hop := range provenance.Hops {
+		if hop.Accepted {
+			return hop, true
+		}
+	}
+
+	return EventHop{}, false
+}
+
+//ReceivedFrom returns true if the neighbor has sent us the event, in which case the neighbor doesn't need it again
+func (provenance EventProvenance) ReceivedFrom(neighbor uuid.UUID) bool {
+	for _, hop := range provenance.Hops {
+		if hop.Neighbor == neighbor {
+			return true
+		}
+	}
+
+	return false
+}
+
+//A ProvenanceTracker records from which neighbors events were received
+//In a storage backend the hops of a event are stored under a single key, the records are kept for all events including compacted events.
+//In memory only the records of the most recent events are kept, a neighbor which sent a older event may receive it back
+//and refuse it as a duplicate
+type ProvenanceTracker struct {
+	//The storage of the records, if nil the memory records are used
+	index storage.StorageBackend
+
+	//The memory records ordered by the time the first hop was recorded, the oldest first
+	order  *list.List
+	memory map[uuid.UUID]*list.Element
+
+	lock sync.Mutex
+}
+
+//memoryProvenance is the memory record of a single event
+type memoryProvenance struct {
+	eventID uuid.UUID
+	hops    []EventHop
+}
+
+//NewProvenanceTracker creates a tracker which stores the records in the storage backend
+//If index is nil the records of the last MemoryProvenanceRecords events are kept in memory
+func NewProvenanceTracker(index storage.StorageBackend) *ProvenanceTracker {
+	tracker := &ProvenanceTracker{
+		index: index,
+	}
+
+	if index == nil {
+		tracker.order = list.New()
+		tracker.memory = make(map[uuid.UUID]*list.Element)
+	}
+
+	return tracker
+}
+
+//provenanceKey returns the key of the provenance record of the event
+func provenanceKey(eventID uuid.UUID) []byte {
+	return append([]byte(provenanceKeyPrefix), eventID[:]...)
+}
+
+//encodeHops encodes the hops as the value of a provenance record
+func encodeHops(hops []EventHop) []byte {
+	value := make([]byte, 0, len(hops)*provenanceHopSize)
+	for _, hop := range hops {
+		value = append(value, hop.Neighbor[:]...)
+
+		if hop.Accepted {
+			value = append(value, 1)
+		} else {
+			value = append(value, 0)
+		}
+
+		var receivedAt [8]byte
+		binary.BigEndian.PutUint64(receivedAt[:], uint64(hop.ReceivedAt.UnixNano()))
+		value = append(value, receivedAt[:]...)
+	}
+
+	return value
+}
+
+//decodeHops decodes the value of a provenance record
+func decodeHops(value []byte) ([]EventHop, error) {
+	if len(value)%provenanceHopSize != 0 {
+		return nil, errors.New("Provenance record is corrupt")
+	}
+
+	hops := make([]EventHop, 0, len(value)/provenanceHopSize)
+	for offset := 0; offset < len(value); offset += provenanceHopSize {
+		var hop EventHop
+		copy(hop.Neighbor[:], value[offset:offset+16])
+		hop.Accepted = value[offset+16] == 1
+		hop.ReceivedAt = time.Unix(0, int64(binary.BigEndian.Uint64(value[offset+17:offset+provenanceHopSize])))
+
+		hops = append(hops, hop)
+	}
+
+	return hops, nil
+}
+
+//hops returns the hops recorded for the event in the order in which they arrived, the lock must be held by the caller
+func (tracker *ProvenanceTracker) hops(eventID uuid.UUID) ([]EventHop, error) {
+	if tracker.index == nil {
+		if element, found := tracker.memory[eventID]; found {
+			return element.Value.(*memoryProvenance).hops, nil
+		}
+
+		return nil, nil
+	}
+
+	value, err := tracker.index.Get(provenanceKey(eventID))
+	if err == storage.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while reading provenance record")
+	}
+
+	return decodeHops(value)
+}
+
+//addHop returns the hops with the hop added, or false if the hop is already recorded or the event has MaxEventHops hops
+func addHop(hops []EventHop, hop EventHop) ([]EventHop, bool) {
+	if len(hops) >= MaxEventHops {
+		return hops, false
+	}
+
+	for _, existing := range hops {
+		if existing.Neighbor == hop.Neighbor && existing.Accepted == hop.Accepted {
+			return hops, false
+		}
+	}
+
+	return append(hops, hop), true
+}
+
+//Record records a hop of the event
+//A neighbor which sends the same event multiple times, for example after a reconnect, is only recorded once
+func (tracker *ProvenanceTracker) Record(eventID uuid.UUID, hop EventHop) error {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	if tracker.index == nil {
+		tracker.recordMemory(eventID, hop)
+		return nil
+	}
+
+	hops, err := tracker.hops(eventID)
+	if err != nil {
+		return err
+	}
+
+	hops, added := addHop(hops, hop)
+	if !added {
+		return nil
+	}
+
+	err = tracker.index.Put(provenanceKey(eventID), encodeHops(hops))
+	if err != nil {
+		return errors.Wrap(err, "Error while writing provenance record")
+	}
+
+	return nil
+}
+
+//recordMemory records the hop in memory, the lock must be held by the caller
+func (tracker *ProvenanceTracker) recordMemory(eventID uuid.UUID, hop EventHop) {
+	element, found := tracker.memory[eventID]
+	if !found {
+		element = tracker.order.PushBack(&memoryProvenance{eventID: eventID})
+		tracker.memory[eventID] = element
+
+		for tracker.order.Len() > MemoryProvenanceRecords {
+			oldest := tracker.order.Front()
+			tracker.order.Remove(oldest)
+			delete(tracker.memory, oldest.Value.(*memoryProvenance).eventID)
+		}
+	}
+
+	record := element.Value.(*memoryProvenance)
+	record.hops, _ = addHop(record.hops, hop)
+}
+
+//Get returns the provenance of the event, the provenance has no hops if the event is unknown
+func (tracker *ProvenanceTracker) Get(eventID uuid.UUID) (EventProvenance, error) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	hops, err := tracker.hops(eventID)
+	if err != nil {
+		return EventProvenance{}, err
+	}
+
+	return EventProvenance{
+		EventID: eventID,
+		Hops:    append([]EventHop(nil), hops...),
+	}, nil
+}
+
+//receivedFrom unwraps a event written to the stream and returns the hop through which it arrived
+func receivedFrom(event Event) (Event, EventHop) {
+	if received, ok := event.(*ReceivedEvent); ok {
+		return received.Event, EventHop{
+			Neighbor:   received.From,
+			ReceivedAt: received.ReceivedAt,
+		}
+	}
+
+	return event, EventHop{
+		ReceivedAt: time.Now(),
+	}
+}
+
+//recordHop records the hop of the event, errors are logged since a missing record doesn't affect the stream itself
+func (stream *inMemoryEventStream) recordHop(event Event, hop EventHop, accepted bool) {
+	if stream.provenance == nil {
+		return
+	}
+
+	hop.Accepted = accepted
+
+	err := stream.provenance.Record(event.GetID(), hop)
+	if err != nil {
+		logrus.WithError(err).WithField("event-id", event.GetID().String()).Error("Error while recording event provenance")
+	}
+}
+
+func (stream *inMemoryEventStream) GetProvenance(eventID uuid.UUID) (EventProvenance, error) {
+	if stream.provenance == nil {
+		return EventProvenance{EventID: eventID}, nil
+	}
+
+	return stream.provenance.Get(eventID)
+}
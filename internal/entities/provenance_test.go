@@ -0,0 +1,109 @@
+package entities
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go/internal/storage/local"
+	"github.com/google/uuid"
+)
+
+//Hops are returned in the order they arrived and a neighbor which sends a event again is recorded once
+func Test_ProvenanceTracker_Record(t *testing.T) {
+	dir, err := ioutil.TempDir("", "provenance")
+	if err != nil {
+		t.Fatalf("Error while creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	backend, err := local.NewStorageBackend(dir, false)
+	if err != nil {
+		t.Fatalf("Error while opening storage backend: %s", err)
+	}
+	defer backend.Close()
+
+	for name, tracker := range map[string]*ProvenanceTracker{
+		"memory":  NewProvenanceTracker(nil),
+		"storage": NewProvenanceTracker(backend),
+	} {
+		eventID := uuid.New()
+		now := time.Now()
+
+		hops := []EventHop{
+			{Neighbor: uuid.New(), ReceivedAt: now, Accepted: true},
+			{Neighbor: uuid.New(), ReceivedAt: now.Add(time.Second)},
+			{Neighbor: uuid.New(), ReceivedAt: now.Add(2 * time.Second)},
+		}
+
+		for _, hop := range append(hops, EventHop{Neighbor: hops[1].Neighbor, ReceivedAt: now.Add(3 * time.Second)}) {
+			err := tracker.Record(eventID, hop)
+			if err != nil {
+				t.Fatalf("%s: error while recording hop: %s", name, err)
+			}
+		}
+
+		provenance, err := tracker.Get(eventID)
+		if err != nil {
+			t.Fatalf("%s: error while reading provenance: %s", name, err)
+		}
+
+		if len(provenance.Hops) != len(hops) {
+			t.Fatalf("%s: expected %d hops, got %d", name, len(hops), len(provenance.Hops))
+		}
+		for i, hop := range provenance.Hops {
+			if hop.Neighbor != hops[i].Neighbor || hop.Accepted != hops[i].Accepted || !hop.ReceivedAt.Equal(hops[i].ReceivedAt) {
+				t.Errorf("%s: expected hop %d to be %v, got %v", name, i, hops[i], hop)
+			}
+		}
+
+		if source, found := provenance.Source(); !found || source.Neighbor != hops[0].Neighbor {
+			t.Errorf("%s: expected the first hop to be the source", name)
+		}
+
+		//At most MaxEventHops hops are recorded per event
+		for i := 0; i < MaxEventHops; i++ {
+			err := tracker.Record(eventID, EventHop{Neighbor: uuid.New(), ReceivedAt: now})
+			if err != nil {
+				t.Fatalf("%s: error while recording hop: %s", name, err)
+			}
+		}
+
+		provenance, err = tracker.Get(eventID)
+		if err != nil {
+			t.Fatalf("%s: error while reading provenance: %s", name, err)
+		}
+		if len(provenance.Hops) != MaxEventHops {
+			t.Errorf("%s: expected %d hops, got %d", name, MaxEventHops, len(provenance.Hops))
+		}
+	}
+}
+
+//Only the records of the most recent events are kept in memory
+func Test_ProvenanceTracker_MemoryLimit(t *testing.T) {
+	tracker := NewProvenanceTracker(nil)
+	neighbor := uuid.New()
+
+	first := uuid.New()
+	err := tracker.Record(first, EventHop{Neighbor: neighbor, ReceivedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("Error while recording hop: %s", err)
+	}
+
+	last := first
+	for i := 0; i < MemoryProvenanceRecords; i++ {
+		last = uuid.New()
+		err := tracker.Record(last, EventHop{Neighbor: neighbor, ReceivedAt: time.Now()})
+		if err != nil {
+			t.Fatalf("Error while recording hop: %s", err)
+		}
+	}
+
+	if provenance, _ := tracker.Get(first); provenance.ReceivedFrom(neighbor) {
+		t.Error("Expected the oldest record to be removed")
+	}
+	if provenance, _ := tracker.Get(last); !provenance.ReceivedFrom(neighbor) {
+		t.Error("Expected the newest record to be kept")
+	}
+}
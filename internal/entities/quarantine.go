@@ -0,0 +1,257 @@
+package entities
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+//ErrNotQuarantined signals that the requested event is not in the quarantine
+var ErrNotQuarantined = errors.New("The event is not in the quarantine")
+
+//DefaultQuarantineSize is the amount of events kept in the quarantine if no size is specified
+const DefaultQuarantineSize = 1000
+
+//A QuarantinedEvent is a event which was refused because it is invalid
+type QuarantinedEvent struct {
+	Event Event
+
+	//The id of the event, uuid.Nil if the event has no valid id
+	EventID uuid.UUID
+
+	//The validation error of the last time the event was refused
+	Reason error
+
+	//The neighbor which sent the event, uuid.Nil if the event was written locally
+	Source uuid.UUID
+
+	//The time at which the event arrived
+	ReceivedAt time.Time
+
+	//The time at which the event was last refused
+	QuarantinedAt time.Time
+
+	//The amount of times the event was refused
+	Rejections uint64
+}
+
+//A Quarantine keeps the most recently refused events so the reason they were refused can be inspected
+//Events are kept by id, a event which is refused again replaces the previous copy.
+//Once the quarantine is full the event which was refused the longest ago is removed
+type Quarantine struct {
+	size int
+
+	//The events ordered by the time they were last refused, the oldest first
+	order  *list.List
+	events map[uuid.UUID]*list.Element
+
+	lock sync.Mutex
+}
+
+//NewQuarantine creates a quarantine which holds at most size events
+func NewQuarantine(size int) *Quarantine {
+	if size <= 0 {
+		size = DefaultQuarantineSize
+	}
+
+	return &Quarantine{
+		size:   size,
+		order:  list.New(),
+		events: make(map[uuid.UUID]*list.Element),
+	}
+}
+
+//Add quarantines the event which was refused for the given reason
+func (quarantine *Quarantine) Add(event Event, hop EventHop, reason error) {
+	quarantine.lock.Lock()
+	defer quarantine.lock.Unlock()
+
+	eventID := event.GetID()
+
+	quarantined := QuarantinedEvent{
+		Event:         event,
+		EventID:       eventID,
+		Reason:        reason,
+		Source:        hop.Neighbor,
+		ReceivedAt:    hop.ReceivedAt,
+		QuarantinedAt: time.Now(),
+		Rejections:    1,
+	}
+
+	if element, found := quarantine.events[eventID]; found {
+		quarantined.Rejections += element.Value.(QuarantinedEvent).Rejections
+		quarantine.order.Remove(element)
+	}
+
+	quarantine.events[eventID] = quarantine.order.PushBack(quarantined)
+
+	for quarantine.order.Len() > quarantine.size {
+		oldest := quarantine.order.Front()
+		quarantine.order.Remove(oldest)
+		delete(quarantine.events, oldest.Value.(QuarantinedEvent).EventID)
+	}
+}
+
+//List returns all quarantined events, the most recently refused event last
+func (quarantine *Quarantine) List() []QuarantinedEvent {
+	quarantine.lock.Lock()
+	defer quarantine.lock.Unlock()
+
+	events := make([]QuarantinedEvent, 0, quarantine.order.Len())
+	for element := quarantine.order.Front(); element != nil; element = element.Next() {
+		events = append(events, element.Value.(QuarantinedEvent))
+	}
+
+	return events
+}
+
+//Get returns the quarantined event with the id
+func (quarantine *Quarantine) Get(eventID uuid.UUID) (QuarantinedEvent, bool) {
+	quarantine.lock.Lock()
+	defer quarantine.lock.Unlock()
+
+	element, found := quarantine.events[eventID]
+	if !found {
+		return QuarantinedEvent{}, false
+	}
+
+	return element.Value.(QuarantinedEvent), true
+}
+
+//Remove removes the event from the quarantine, returns false if the event was not quarantined
+func (quarantine *Quarantine) Remove(eventID uuid.UUID) bool {
+	quarantine.lock.Lock()
+	defer quarantine.lock.Unlock()
+
+	element, found := quarantine.events[eventID]
+	if !found {
+		return false
+	}
+
+	quarantine.order.Remove(element)
+	delete(quarantine.events, eventID)
+
+	return true
+}
+
+//Purge removes the events with the given ids, or all events if no ids are given
+//Returns the amount of events which were removed
+func (quarantine *Quarantine) Purge(eventIDs ...uuid.UUID) int {
+	if len(eventIDs) > 0 {
+		purged := 0
+		for _, eventID := range eventIDs {
+			if quarantine.Remove(eventID) {
+				purged++
+			}
+		}
+
+		return purged
+	}
+
+	quarantine.lock.Lock()
+	defer quarantine.lock.Unlock()
+
+	purged := quarantine.order.Len()
+	quarantine.order.Init()
+	quarantine.events = make(map[uuid.UUID]*list.Element)
+
+	return purged
+}
+
+//refuse logs and quarantines a event which is invalid
+func (stream *inMemoryEventStream) refuse(event Event, hop EventHop, reason error) {
+	logrus.WithError(reason).WithFields(logrus.Fields{
+		"event-id": event.GetID().String(),
+		"source":   hop.Neighbor.String(),
+	}).Warn("Event refused because it is invalid")
+
+	if stream.quarantine != nil {
+		stream.quarantine.Add(event, hop, reason)
+	}
+}
+
+func (stream *inMemoryEventStream) GetQuarantinedEvents() []QuarantinedEvent {
+	if stream.quarantine == nil {
+		return nil
+	}
+
+	return stream.quarantine.List()
+}
+
+func (stream *inMemoryEventStream) GetQuarantinedEvent(eventID uuid.UUID) (QuarantinedEvent, bool) {
+	if stream.quarantine == nil {
+		return QuarantinedEvent{}, false
+	}
+
+	return stream.quarantine.Get(eventID)
+}
+
+func (stream *inMemoryEventStream) RevalidateQuarantinedEvent(eventID uuid.UUID) (bool, error) {
+	quarantined, found := stream.GetQuarantinedEvent(eventID)
+	if !found {
+		return false, ErrNotQuarantined
+	}
+
+	valid, reason := quarantined.Event.Validate(stream.tableSet)
+	if !valid {
+		stream.quarantine.Add(quarantined.Event, EventHop{
+			Neighbor:   quarantined.Source,
+			ReceivedAt: quarantined.ReceivedAt,
+		}, reason)
+
+		return false, reason
+	}
+
+	//The event is written as if it just arrived from its source, so it is checked for duplicates and its provenance is recorded
+	stream.quarantine.Remove(eventID)
+	stream.writeChan <- &ReceivedEvent{
+		Event:      quarantined.Event,
+		From:       quarantined.Source,
+		ReceivedAt: quarantined.ReceivedAt,
+	}
+
+	return true, nil
+}
+
+//revalidateNode validates the quarantined announcements of the node again which were refused because the node
+//was still being verified. Valid announcements are processed as if they just arrived from their source
+func (stream *inMemoryEventStream) revalidateNode(nodeID string) error {
+	if stream.quarantine == nil {
+		return nil
+	}
+
+	for _, quarantined := range stream.quarantine.List() {
+		if errors.Cause(quarantined.Reason) != ErrNodeVerificationPending {
+			continue
+		}
+
+		event, ok := quarantined.Event.(*GenericEvent)
+		if !ok || event.GetNode().GetUuid().GetUuid() != nodeID {
+			continue
+		}
+
+		stream.quarantine.Remove(quarantined.EventID)
+
+		err := stream.process(quarantined.Event, EventHop{
+			Neighbor:   quarantined.Source,
+			ReceivedAt: quarantined.ReceivedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (stream *inMemoryEventStream) PurgeQuarantine(eventIDs ...uuid.UUID) int {
+	if stream.quarantine == nil {
+		return 0
+	}
+
+	return stream.quarantine.Purge(eventIDs...)
+}
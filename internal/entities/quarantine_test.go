@@ -0,0 +1,181 @@
+package entities
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+//A full quarantine evicts the event which was refused the longest ago, refusing a event again makes it the newest
+func Test_Quarantine_Eviction(t *testing.T) {
+	node := newTestNode(t, "node-a")
+	quarantine := NewQuarantine(2)
+
+	first := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, uuid.New(), "198.51.100.1")
+	second := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, uuid.New(), "198.51.100.2")
+	third := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, uuid.New(), "198.51.100.3")
+
+	source := uuid.New()
+	hop := EventHop{Neighbor: source, ReceivedAt: time.Now()}
+
+	refuse := func(event Event, reason error) func() int {
+		return func() int {
+			quarantine.Add(event, hop, reason)
+			return 0
+		}
+	}
+	remove := func(event Event) func() int {
+		return func() int {
+			if quarantine.Remove(event.GetID()) {
+				return 1
+			}
+			return 0
+		}
+	}
+
+	//Every step changes the quarantine, after which it must list the given events with the first listed event
+	//refused the given amount of times for the given reason
+	tests := []struct {
+		name       string
+		action     func() int
+		removed    int
+		listed     []Event
+		rejections uint64
+		reason     error
+	}{
+		{name: "refuse first", action: refuse(first, ErrUnknownSigner), listed: []Event{first}, rejections: 1, reason: ErrUnknownSigner},
+		{name: "refuse second", action: refuse(second, ErrUnknownSigner), listed: []Event{first, second}, rejections: 1, reason: ErrUnknownSigner},
+		{name: "refuse first again", action: refuse(first, ErrSignatureInvalid), listed: []Event{second, first}, rejections: 1, reason: ErrUnknownSigner},
+		{name: "evict second", action: refuse(third, ErrUnknownSigner), listed: []Event{first, third}, rejections: 2, reason: ErrSignatureInvalid},
+		{name: "remove evicted", action: remove(second), listed: []Event{first, third}, rejections: 2, reason: ErrSignatureInvalid},
+		{
+			name:       "purge",
+			action:     func() int { return quarantine.Purge(third.GetID(), second.GetID()) },
+			removed:    1,
+			listed:     []Event{first},
+			rejections: 2,
+			reason:     ErrSignatureInvalid,
+		},
+		{name: "purge all", action: func() int { return quarantine.Purge() }, removed: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if removed := tt.action(); removed != tt.removed {
+				t.Errorf("Expected %d removed events, got %d", tt.removed, removed)
+			}
+
+			listed := quarantine.List()
+			if len(listed) != len(tt.listed) {
+				t.Fatalf("Expected %d quarantined events, got %d", len(tt.listed), len(listed))
+			}
+			for i, refused := range listed {
+				if refused.EventID != tt.listed[i].GetID() {
+					t.Errorf("Expected quarantined event %d to be %s, got %s", i, tt.listed[i].GetID(), refused.EventID)
+				}
+				if refused.Source != source {
+					t.Errorf("Expected source %s, got %s", source, refused.Source)
+				}
+			}
+
+			if len(listed) > 0 && (listed[0].Rejections != tt.rejections || listed[0].Reason != tt.reason) {
+				t.Errorf("Expected %d rejections for %v, got %d for %v", tt.rejections, tt.reason, listed[0].Rejections, listed[0].Reason)
+			}
+		})
+	}
+}
+
+//A event which was refused because its signer was unknown is accepted when revalidated after the signer is announced
+func Test_EventStream_RevalidateQuarantinedEvent(t *testing.T) {
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	stream := newTestEventStream(t, tableSet)
+	stream.Attach(tableSet)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go stream.Run(ctx)
+
+	node := newTestNode(t, "node-a")
+	reportID := uuid.New()
+	report := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, reportID, "198.51.100.1")
+
+	quarantined := func() bool {
+		_, found := stream.GetQuarantinedEvent(report.GetID())
+		return found
+	}
+
+	//Every step writes its event, revalidates the given event and then waits until its condition holds
+	tests := []struct {
+		name       string
+		write      Event
+		revalidate uuid.UUID
+		valid      bool
+		reason     error
+		until      func() bool
+	}{
+		{
+			name:       "unknown event",
+			revalidate: uuid.New(),
+			reason:     ErrNotQuarantined,
+		},
+		{
+			name:  "unknown signer",
+			write: report,
+			until: quarantined,
+		},
+		{
+			//The signer is still unknown, the event stays quarantined
+			name:       "signer still unknown",
+			revalidate: report.GetID(),
+			reason:     ErrUnknownSigner,
+			until: func() bool {
+				refused, _ := stream.GetQuarantinedEvent(report.GetID())
+				return refused.Rejections == 2
+			},
+		},
+		{
+			name:  "signer announced",
+			write: node.announce(t),
+			until: func() bool { return tableSet.getNode(node.id) != nil },
+		},
+		{
+			name:       "signer known",
+			revalidate: report.GetID(),
+			valid:      true,
+			until:      func() bool { return tableSet.getReport(reportID) != nil && !quarantined() },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.write != nil {
+				stream.GetWriteChannel() <- tt.write
+			}
+
+			if tt.revalidate != uuid.Nil {
+				valid, err := stream.RevalidateQuarantinedEvent(tt.revalidate)
+				if valid != tt.valid || errors.Cause(err) != tt.reason {
+					t.Errorf("Expected valid %t for %v, got %t for %v", tt.valid, tt.reason, valid, err)
+				}
+			}
+
+			if tt.until == nil {
+				return
+			}
+
+			deadline := time.Now().Add(5 * time.Second)
+			for !tt.until() {
+				if time.Now().After(deadline) {
+					t.Fatal("Condition was not reached in time")
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package entities
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/utils/conv"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+//ErrNotReportOwner signals that a event edits or withdraws a report but is not made by the reporter of the report
+var ErrNotReportOwner = errors.New("Only the reporter of a report can edit or withdraw it")
+
+//A Report is a abuse report as defined by the AbuseMesh protocol[inset link to docs]
+type Report struct {
+	UUID uuid.UUID
+	//The UUID of the node which made the report
+	Reporter uuid.UUID
+	//The reported network, a single IP address is stored as a /32 or /128 prefix
+	Prefix      net.IPNet
+	Category    string
+	Description string
+	//Delisted is true if the reporter has accepted a delist request for this report
+	Delisted bool
+	//TTL is the time the reporter wants the report to stay listed, 0 if the reporter left it to the ReportTTLPolicy
+	TTL time.Duration
+	//ExpiresAt is the time at which the report is removed from the tables, zero if the report never expires
+	ExpiresAt time.Time
+}
+
+//ToProtobuf converts the report struct into a protobuf stub
+func (report Report) ToProtobuf() *abusemesh.Report {
+	//Find out what ip family we have
+	var ipFamily abusemesh.IPAddressFamily
+	if report.Prefix.IP.To4() != nil {
+		ipFamily = abusemesh.IPAddressFamily_IPFAMILY_IPV4
+	} else {
+		ipFamily = abusemesh.IPAddressFamily_IPFAMILY_IPV6
+	}
+
+	//Single addresses are sent without prefix length
+	address := report.Prefix.String()
+	if ones, bits := report.Prefix.Mask.Size(); ones == bits {
+		address = report.Prefix.IP.String()
+	}
+
+	return &abusemesh.Report{
+		Uuid: &abusemesh.UUID{
+			Uuid: report.UUID.String(),
+		},
+		Reporter: &abusemesh.UUID{
+			Uuid: report.Reporter.String(),
+		},
+		IpAddress: &abusemesh.IPAddress{
+			Address:       address,
+			AddressFamily: ipFamily,
+		},
+		Category:    report.Category,
+		Description: report.Description,
+		Ttl:         int64(report.TTL / time.Second),
+	}
+}
+
+//ReportFromProtobuf creates a report object from the report stub of the protobuf definition
+func ReportFromProtobuf(protobufReport *abusemesh.Report) (Report, error) {
+	reportID, err := conv.AuuidToGuuid(protobufReport.GetUuid())
+	if err != nil {
+		return Report{}, errors.Wrap(err, "Report ID invalid")
+	}
+
+	reporterID, err := conv.AuuidToGuuid(protobufReport.GetReporter())
+	if err != nil {
+		return Report{}, errors.Wrap(err, "Reporter ID invalid")
+	}
+
+	prefix, err := ParsePrefix(protobufReport.GetIpAddress().GetAddress())
+	if err != nil {
+		return Report{}, err
+	}
+
+	//The ttl is sent in seconds
+	if protobufReport.GetTtl() < 0 {
+		return Report{}, errors.Errorf("Report TTL '%d' is negative", protobufReport.GetTtl())
+	}
+
+	return Report{
+		UUID:        reportID,
+		Reporter:    reporterID,
+		Prefix:      prefix,
+		Category:    protobufReport.GetCategory(),
+		Description: protobufReport.GetDescription(),
+		TTL:         time.Duration(protobufReport.GetTtl()) * time.Second,
+	}, nil
+}
+
+//validateReport checks that a event about a report is signed by the reporter
+//Edits and withdrawals of a known report must be made by the reporter in the report table, so the reporter can't be changed
+func validateReport(event *GenericEvent, tableSet *TableSet, protobufReport *abusemesh.Report) error {
+	if event.UpdateType == abusemesh.TableEventType_TABLE_UPDATE_NEW {
+		return event.verifySignedBy(tableSet, protobufReport.GetReporter())
+	}
+
+	reportID, err := conv.AuuidToGuuid(protobufReport.GetUuid())
+	if err != nil {
+		return errors.Wrap(err, "Report ID invalid")
+	}
+
+	//The owner of a unknown report can't be determined, the event is refused until the report is known.
+	//A expired report is listed again with a new NEW event
+	report := tableSet.getReport(reportID)
+	if report == nil {
+		return ErrUnknownReport
+	}
+
+	reporterID, err := conv.AuuidToGuuid(protobufReport.GetReporter())
+	if err != nil {
+		return errors.Wrap(err, "Reporter ID invalid")
+	}
+
+	if reporterID != report.Reporter {
+		return ErrNotReportOwner
+	}
+
+	reporter := tableSet.getNode(report.Reporter)
+	if reporter == nil {
+		return ErrUnknownSigner
+	}
+
+	return event.verifyOwnedBy(reporter, ErrNotReportOwner)
+}
+
+//ParsePrefix parses a IP address or a network in CIDR notation into a network
+func ParsePrefix(address string) (net.IPNet, error) {
+	if ip := net.ParseIP(address); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			return net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+
+		return net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+
+	_, network, err := net.ParseCIDR(address)
+	if err != nil {
+		return net.IPNet{}, errors.Errorf("'%s' is not a valid IP address or network", address)
+	}
+
+	return *network, nil
+}
+
+//A ReportTable holds the current derived state of all reports in the network known to the current node
+type ReportTable struct {
+	Entities map[uuid.UUID]Report
+
+	//prefixes indexes the reports on their prefix
+	prefixes PrefixIndex
+
+	//nextExpiry is the earliest expiry of the reports in the table, zero if no report expires
+	//It may be earlier than the actual earliest expiry, it only has to prevent needless scans for expired reports
+	nextExpiry time.Time
+
+	//shared is true if Entities is part of a snapshot and has to be copied before it is written
+	shared bool
+
+	//changes records the changes to Entities
+	changes *changeSet
+}
+
+//put adds or replaces the report and keeps the prefix index up to date
+func (table *ReportTable) put(report Report) {
+	table.own()
+
+	if !report.ExpiresAt.IsZero() && (table.nextExpiry.IsZero() || report.ExpiresAt.Before(table.nextExpiry)) {
+		table.nextExpiry = report.ExpiresAt
+	}
+
+	if existing, found := table.Entities[report.UUID]; found {
+		table.prefixes.Remove(existing.Prefix, existing.UUID)
+		table.changes.record(TableReports, report.UUID.String(), existing, report)
+	} else {
+		table.changes.record(TableReports, report.UUID.String(), nil, report)
+	}
+
+	table.Entities[report.UUID] = report
+	table.prefixes.Insert(report.Prefix, report.UUID)
+}
+
+//remove removes the report and its entry in the prefix index
+func (table *ReportTable) remove(reportID uuid.UUID) {
+	table.own()
+
+	if existing, found := table.Entities[reportID]; found {
+		table.prefixes.Remove(existing.Prefix, existing.UUID)
+		table.changes.record(TableReports, reportID.String(), existing, nil)
+	}
+
+	delete(table.Entities, reportID)
+}
+
+//handleTableEvent applies a event about a report, listedAt is the timestamp of the event from which the ttl of the report counts
+func (table *ReportTable) handleTableEvent(
+	eventType abusemesh.TableEventType,
+	entity *abusemesh.TableEvent_Report,
+	listedAt time.Time,
+	ttl ReportTTLPolicy,
+) error {
+	switch eventType {
+	case abusemesh.TableEventType_TABLE_UPDATE_NEW:
+		report, err := ReportFromProtobuf(entity.Report)
+		if err != nil {
+			return err
+		}
+
+		report.ExpiresAt = ttl.expiresAt(report, listedAt)
+		table.put(report)
+
+	case abusemesh.TableEventType_TABLE_UPDATE_EDIT:
+		report, err := ReportFromProtobuf(entity.Report)
+		if err != nil {
+			return err
+		}
+
+		//A edit lists the report again, so its ttl counts from the edit
+		report.ExpiresAt = ttl.expiresAt(report, listedAt)
+
+		//The delist state is derived from other events and is not part of the report message
+		if existing, found := table.Entities[report.UUID]; found {
+			report.Delisted = existing.Delisted
+		}
+
+		table.put(report)
+
+	case abusemesh.TableEventType_TABLE_UPDATE_DELETE:
+		uuid, err := conv.AuuidToGuuid(entity.Report.GetUuid())
+		if err != nil {
+			return err
+		}
+		table.remove(uuid)
+
+	default:
+		return errors.Errorf("Unknown abusemesh.TableEventType type '%T'", eventType)
+	}
+
+	return nil
+}
+
+//GetReportRequest can be used to request a specific report from a table
+type GetReportRequest struct {
+	ResponseChan chan<- *Report
+	ReportID     uuid.UUID
+}
+
+//Process processes the request and sends a pointer to the report on the ResponseChan or nil if the report was not found
+func (req *GetReportRequest) Process(tables *TableSet) error {
+	report, found := tables.reportTable.Entities[req.ReportID]
+	if found {
+		req.ResponseChan <- &report
+	} else {
+		req.ResponseChan <- nil
+	}
+
+	return nil
+}
+
+//getReport queries the report table for the report with the given id, nil is returned if the report is unknown
+//NOTE: must not be called from the goroutine of the TableSet since it waits for the request to be processed
+func (set *TableSet) getReport(reportID uuid.UUID) *Report {
+	responseChan := make(chan *Report, 1)
+
+	set.Channel <- &GetReportRequest{
+		ResponseChan: responseChan,
+		ReportID:     reportID,
+	}
+
+	return <-responseChan
+}
+
+//GetAllReportsRequest can be used to request all reports from the reports table
+type GetAllReportsRequest struct {
+	//ResponseChan is the channel over which multiple reports will be sent
+	ResponseChan chan<- Report
+
+	//Context can be used to cancel the sending of reports
+	Context context.Context
+}
+
+//Process processes the request and sends all reports on the ResponseChan, the channel is closed when all reports are sent
+//The reports are sent from a snapshot in a separate goroutine, so a slow requester doesn't block the TableSet
+func (req *GetAllReportsRequest) Process(tables *TableSet) error {
+	snapshot := tables.takeSnapshot()
+
+	go func() {
+		defer close(req.ResponseChan)
+
+		for _, report := range snapshot.Reports {
+			select {
+			case req.ResponseChan <- report:
+			//If the requester no longer wishes to receive reports we return
+			case <-req.Context.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+//GetReportsByPrefixRequest can be used to request the reports about a IP address or network
+type GetReportsByPrefixRequest struct {
+	//ResponseChan receives the matching reports, reports about the same prefix are ordered by UUID
+	ResponseChan chan<- []Report
+
+	//The queried network, a single IP address is queried as a /32 or /128 prefix
+	Prefix net.IPNet
+
+	//Match determines if reports about the prefix itself, less specific or more specific prefixes are returned
+	Match PrefixMatch
+}
+
+//Process processes the request and sends the matching reports on the ResponseChan
+func (req *GetReportsByPrefixRequest) Process(tables *TableSet) error {
+	ids := tables.reportTable.prefixes.Lookup(req.Prefix, req.Match)
+
+	reports := make([]Report, 0, len(ids))
+	for _, id := range ids {
+		reports = append(reports, tables.reportTable.Entities[id])
+	}
+
+	req.ResponseChan <- reports
+
+	return nil
+}
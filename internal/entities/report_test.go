@@ -0,0 +1,112 @@
+package entities
+
+import (
+	"context"
+	"testing"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/google/uuid"
+)
+
+//getAllReports reads all reports with a GetAllReportsRequest
+func getAllReports(tableSet *TableSet) map[uuid.UUID]Report {
+	responseChan := make(chan Report)
+	tableSet.Channel <- &GetAllReportsRequest{
+		ResponseChan: responseChan,
+		Context:      context.Background(),
+	}
+
+	reports := make(map[uuid.UUID]Report)
+	for report := range responseChan {
+		reports[report.UUID] = report
+	}
+
+	return reports
+}
+
+//NEW, EDIT and DELETE events of reports are applied to the report table
+func Test_ReportTable_Updates(t *testing.T) {
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	node := newTestNode(t, "reporter")
+	err := applyEvent(tableSet, node.announce(t))
+	if err != nil {
+		t.Fatalf("Error while announcing node: %s", err)
+	}
+
+	reportID := uuid.New()
+	otherID := uuid.New()
+
+	for _, event := range []*GenericEvent{
+		node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, reportID, "198.51.100.1"),
+		node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, otherID, "2001:db8::/48"),
+	} {
+		err := applyEvent(tableSet, event)
+		if err != nil {
+			t.Fatalf("Error while applying report: %s", err)
+		}
+	}
+
+	report := tableSet.getReport(reportID)
+	if report == nil {
+		t.Fatal("Expected the report to be known")
+	}
+	if report.Reporter != node.id || report.Prefix.String() != "198.51.100.1/32" || report.Category != "spam" {
+		t.Errorf("Unexpected report: %+v", *report)
+	}
+
+	if reports := getAllReports(tableSet); len(reports) != 2 {
+		t.Errorf("Expected 2 reports, got %d", len(reports))
+	}
+
+	err = applyEvent(tableSet, node.report(t, abusemesh.TableEventType_TABLE_UPDATE_EDIT, reportID, "198.51.100.0/24"))
+	if err != nil {
+		t.Fatalf("Error while editing report: %s", err)
+	}
+
+	if report := tableSet.getReport(reportID); report == nil || report.Prefix.String() != "198.51.100.0/24" {
+		t.Errorf("Expected the edit to change the prefix, got: %+v", report)
+	}
+
+	err = applyEvent(tableSet, node.report(t, abusemesh.TableEventType_TABLE_UPDATE_DELETE, reportID, "198.51.100.0/24"))
+	if err != nil {
+		t.Fatalf("Error while deleting report: %s", err)
+	}
+
+	if tableSet.getReport(reportID) != nil {
+		t.Error("Expected the report to be deleted")
+	}
+
+	reports := getAllReports(tableSet)
+	if _, found := reports[otherID]; !found || len(reports) != 1 {
+		t.Errorf("Expected only the other report to be left, got %d reports", len(reports))
+	}
+}
+
+//A report which can't be converted is refused without stopping the TableSet
+func Test_ReportTable_InvalidReport(t *testing.T) {
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	node := newTestNode(t, "reporter")
+	err := applyEvent(tableSet, node.announce(t))
+	if err != nil {
+		t.Fatalf("Error while announcing node: %s", err)
+	}
+
+	err = processEvent(tableSet, node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, uuid.New(), "not an address"))
+	if err == nil {
+		t.Error("Expected the report to be refused")
+	}
+
+	//The TableSet still processes requests
+	reportID := uuid.New()
+	err = applyEvent(tableSet, node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, reportID, "198.51.100.1"))
+	if err != nil {
+		t.Fatalf("Error while applying report: %s", err)
+	}
+	if tableSet.getReport(reportID) == nil {
+		t.Error("Expected the report to be known")
+	}
+}
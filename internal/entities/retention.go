@@ -0,0 +1,282 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/utils/conv"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+//ReportTTLPolicy determines how long reports stay listed
+//The ttl set by the reporter takes precedence over the ttl of the category, which takes precedence over the default
+type ReportTTLPolicy struct {
+	//Default is the ttl of reports without ttl of their own or of their category, 0 means those reports never expire
+	Default time.Duration
+
+	//Categories holds the default ttl per report category
+	Categories map[string]time.Duration
+}
+
+//ttl returns the time the report stays listed, 0 if the report never expires
+func (policy ReportTTLPolicy) ttl(report Report) time.Duration {
+	if report.TTL > 0 {
+		return report.TTL
+	}
+
+	if ttl, found := policy.Categories[report.Category]; found {
+		return ttl
+	}
+
+	return policy.Default
+}
+
+//expiresAt returns the time at which a report which is listed at the given time expires, zero if it never expires
+//The ttl counts from the signed timestamp of the event, so all nodes expire the report at the same time
+func (policy ReportTTLPolicy) expiresAt(report Report, listedAt time.Time) time.Time {
+	ttl := policy.ttl(report)
+	if ttl <= 0 {
+		return time.Time{}
+	}
+
+	return listedAt.Add(ttl)
+}
+
+//expireReports removes the reports which have expired from the report table and the prefix index
+//The confirmations of those reports are removed as well, they have no meaning without the report
+func (set *TableSet) expireReports(now time.Time) {
+	table := &set.reportTable
+
+	if table.nextExpiry.IsZero() || now.Before(table.nextExpiry) {
+		return
+	}
+
+	var expired []uuid.UUID
+	var nextExpiry time.Time
+
+	for reportID, report := range table.Entities {
+		if report.ExpiresAt.IsZero() {
+			continue
+		}
+
+		if !now.Before(report.ExpiresAt) {
+			expired = append(expired, reportID)
+			continue
+		}
+
+		if nextExpiry.IsZero() || report.ExpiresAt.Before(nextExpiry) {
+			nextExpiry = report.ExpiresAt
+		}
+	}
+
+	for _, reportID := range expired {
+		//The versions are kept, so older events can't list the report or its confirmations again
+		set.versionTable.expire("report/"+reportID.String(), now)
+		for confirmationID := range set.confirmationTable.byReport[reportID] {
+			set.versionTable.expire("report-confirmation/"+confirmationID.String(), now)
+		}
+
+		table.remove(reportID)
+		set.confirmationTable.removeReport(reportID)
+	}
+
+	table.nextExpiry = nextExpiry
+
+	if len(expired) > 0 {
+		logrus.WithField("reports", len(expired)).Info("Expired reports removed")
+	}
+}
+
+//removeReport removes all confirmations of the report
+func (table *ConfirmationTable) removeReport(reportID uuid.UUID) {
+	confirmations, found := table.byReport[reportID]
+	if !found {
+		return
+	}
+
+	table.own()
+
+	for confirmationID := range confirmations {
+		table.remove(confirmationID)
+	}
+}
+
+//retiredReport is the state of a report derived from the events which are compacted
+type retiredReport struct {
+	//The newest NEW or EDIT event and the newest DELETE event of the report
+	listed  *GenericEvent
+	deleted *GenericEvent
+
+	//The newest acceptance event of every delist request of the report
+	acceptances map[uuid.UUID]*GenericEvent
+}
+
+//retiredAt returns the time at which the report expired or was delisted, zero if it is still listed
+func (state *retiredReport) retiredAt(ttl ReportTTLPolicy) time.Time {
+	if state.listed == nil {
+		return time.Time{}
+	}
+
+	//A deleted report is already compacted to its deletion
+	if state.deleted != nil && eventVersion(state.deleted).After(eventVersion(state.listed)) {
+		return time.Time{}
+	}
+
+	var retiredAt time.Time
+
+	report, err := ReportFromProtobuf(state.listed.GetReport())
+	if err == nil {
+		retiredAt = ttl.expiresAt(report, time.Unix(state.listed.GetTimestamp(), 0))
+	}
+
+	for _, acceptance := range state.acceptances {
+		//A withdrawn acceptance lists the report again
+		if acceptance.UpdateType == abusemesh.TableEventType_TABLE_UPDATE_DELETE {
+			continue
+		}
+
+		delistedAt := time.Unix(acceptance.GetTimestamp(), 0)
+		if retiredAt.IsZero() || delistedAt.Before(retiredAt) {
+			retiredAt = delistedAt
+		}
+	}
+
+	return retiredAt
+}
+
+//retireEvents removes the events of reports which expired or were delisted longer than RetainExpired ago
+//All events about those reports are removed, including the confirmations, delist requests and delist acceptances.
+//Reports which are still listed and the events which can't be related to a report are kept
+func retireEvents(events []Event, policy CompactionPolicy, now time.Time) []Event {
+	if policy.RetainExpired <= 0 {
+		return events
+	}
+
+	reports := make(map[uuid.UUID]*retiredReport)
+	report := func(reportID uuid.UUID) *retiredReport {
+		state, found := reports[reportID]
+		if !found {
+			state = &retiredReport{acceptances: make(map[uuid.UUID]*GenericEvent)}
+			reports[reportID] = state
+		}
+
+		return state
+	}
+
+	//The report of every delist request, acceptances only refer to the delist request
+	delistRequests := make(map[uuid.UUID]uuid.UUID)
+	var acceptances []*GenericEvent
+
+	for _, event := range events {
+		genericEvent, ok := event.(*GenericEvent)
+		if !ok {
+			continue
+		}
+
+		switch e := genericEvent.GetTableEntity().(type) {
+		case *abusemesh.TableEvent_Report:
+			reportID, err := conv.AuuidToGuuid(e.Report.GetUuid())
+			if err != nil {
+				continue
+			}
+
+			state := report(reportID)
+			if genericEvent.UpdateType == abusemesh.TableEventType_TABLE_UPDATE_DELETE {
+				state.deleted = newestEvent(state.deleted, genericEvent)
+			} else {
+				state.listed = newestEvent(state.listed, genericEvent)
+			}
+
+		case *abusemesh.TableEvent_DelistRequests:
+			requestID, requestErr := conv.AuuidToGuuid(e.DelistRequests.GetUuid())
+			reportID, reportErr := conv.AuuidToGuuid(e.DelistRequests.GetReport())
+			if requestErr == nil && reportErr == nil {
+				delistRequests[requestID] = reportID
+			}
+
+		case *abusemesh.TableEvent_DelistAcceptance:
+			acceptances = append(acceptances, genericEvent)
+		}
+	}
+
+	for _, acceptance := range acceptances {
+		requestID, err := conv.AuuidToGuuid(acceptance.GetDelistAcceptance().GetDelistRequest())
+		if err != nil {
+			continue
+		}
+
+		reportID, found := delistRequests[requestID]
+		if !found {
+			continue
+		}
+
+		state := report(reportID)
+		state.acceptances[requestID] = newestEvent(state.acceptances[requestID], acceptance)
+	}
+
+	retired := make(map[uuid.UUID]bool)
+	for reportID, state := range reports {
+		retiredAt := state.retiredAt(policy.ReportTTL)
+		if !retiredAt.IsZero() && now.Sub(retiredAt) > policy.RetainExpired {
+			retired[reportID] = true
+		}
+	}
+
+	if len(retired) == 0 {
+		return events
+	}
+
+	kept := make([]Event, 0, len(events))
+	for _, event := range events {
+		genericEvent, ok := event.(*GenericEvent)
+		if !ok || !retired[eventReport(genericEvent, delistRequests)] {
+			kept = append(kept, event)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"reports": len(retired),
+		"events":  len(events) - len(kept),
+	}).Info("Events of expired and delisted reports removed")
+
+	return kept
+}
+
+//newestEvent returns the newest of the two events, current may be nil
+func newestEvent(current, event *GenericEvent) *GenericEvent {
+	if current == nil || eventVersion(event).After(eventVersion(current)) {
+		return event
+	}
+
+	return current
+}
+
+//eventReport returns the report the event is about, uuid.Nil if the event is not about a report
+func eventReport(event *GenericEvent, delistRequests map[uuid.UUID]uuid.UUID) uuid.UUID {
+	var reportID *abusemesh.UUID
+
+	switch e := event.GetTableEntity().(type) {
+	case *abusemesh.TableEvent_Report:
+		reportID = e.Report.GetUuid()
+	case *abusemesh.TableEvent_ReportConfirmation:
+		reportID = e.ReportConfirmation.GetReport()
+	case *abusemesh.TableEvent_DelistRequests:
+		reportID = e.DelistRequests.GetReport()
+	case *abusemesh.TableEvent_DelistAcceptance:
+		requestID, err := conv.AuuidToGuuid(e.DelistAcceptance.GetDelistRequest())
+		if err != nil {
+			return uuid.Nil
+		}
+		return delistRequests[requestID]
+	default:
+		return uuid.Nil
+	}
+
+	id, err := conv.AuuidToGuuid(reportID)
+	if err != nil {
+		return uuid.Nil
+	}
+
+	return id
+}
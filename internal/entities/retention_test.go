@@ -0,0 +1,261 @@
+package entities
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/google/uuid"
+)
+
+//The ttl of the reporter takes precedence over the ttl of the category, which takes precedence over the default
+func Test_ReportTTLPolicy_ExpiresAt(t *testing.T) {
+	policy := ReportTTLPolicy{
+		Default:    time.Hour,
+		Categories: map[string]time.Duration{"spam": 2 * time.Hour, "scanning": 0},
+	}
+	listedAt := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		policy    ReportTTLPolicy
+		report    Report
+		expiresAt time.Time
+	}{
+		{
+			name:      "default",
+			policy:    policy,
+			report:    Report{Category: "phishing"},
+			expiresAt: listedAt.Add(time.Hour),
+		},
+		{
+			name:      "category",
+			policy:    policy,
+			report:    Report{Category: "spam"},
+			expiresAt: listedAt.Add(2 * time.Hour),
+		},
+		{
+			name:      "reporter",
+			policy:    policy,
+			report:    Report{Category: "spam", TTL: 3 * time.Hour},
+			expiresAt: listedAt.Add(3 * time.Hour),
+		},
+		{
+			name:   "category never expires",
+			policy: policy,
+			report: Report{Category: "scanning"},
+		},
+		{
+			name:   "no ttl",
+			policy: ReportTTLPolicy{},
+			report: Report{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expiresAt := tt.policy.expiresAt(tt.report, listedAt)
+			if !expiresAt.Equal(tt.expiresAt) {
+				t.Errorf("Expected expiry at %s, got %s", tt.expiresAt, expiresAt)
+			}
+		})
+	}
+}
+
+//Expired reports are removed with their confirmations and prefix, reports which haven't expired yet are kept
+func Test_TableSet_ExpireReports(t *testing.T) {
+	tableSet := NewTableSet(0)
+	tableSet.ReportTTL = ReportTTLPolicy{Default: time.Hour}
+
+	now := time.Now()
+	reporter := uuid.New()
+
+	tests := []struct {
+		name     string
+		reportID uuid.UUID
+		address  string
+		ttl      time.Duration
+		listedAt time.Time
+		expired  bool
+	}{
+		{name: "expired", reportID: uuid.New(), address: "198.51.100.1", listedAt: now.Add(-2 * time.Hour), expired: true},
+		{name: "listed", reportID: uuid.New(), address: "198.51.100.2", listedAt: now},
+		//A report which sets its own ttl of a day stays listed past the default
+		{name: "long lived", reportID: uuid.New(), address: "198.51.100.3", ttl: 24 * time.Hour, listedAt: now.Add(-2 * time.Hour)},
+	}
+
+	//Every report is confirmed, the confirmations of expired reports are removed with them
+	confirmations := make(map[uuid.UUID]uuid.UUID)
+
+	for _, tt := range tests {
+		err := tableSet.reportTable.handleTableEvent(abusemesh.TableEventType_TABLE_UPDATE_NEW, &abusemesh.TableEvent_Report{
+			Report: &abusemesh.Report{
+				Uuid:      &abusemesh.UUID{Uuid: tt.reportID.String()},
+				Reporter:  &abusemesh.UUID{Uuid: reporter.String()},
+				IpAddress: &abusemesh.IPAddress{Address: tt.address},
+				Ttl:       int64(tt.ttl.Seconds()),
+			},
+		}, tt.listedAt, tableSet.ReportTTL)
+		if err != nil {
+			t.Fatalf("Error while listing report: %s", err)
+		}
+
+		confirmations[tt.reportID] = uuid.New()
+		err = tableSet.confirmationTable.handleTableEvent(abusemesh.TableEventType_TABLE_UPDATE_NEW, &abusemesh.TableEvent_ReportConfirmation{
+			ReportConfirmation: &abusemesh.ReportConfirmation{
+				Uuid:      &abusemesh.UUID{Uuid: confirmations[tt.reportID].String()},
+				Report:    &abusemesh.UUID{Uuid: tt.reportID.String()},
+				Confirmer: &abusemesh.UUID{Uuid: uuid.New().String()},
+			},
+		}, now)
+		if err != nil {
+			t.Fatalf("Error while confirming report: %s", err)
+		}
+	}
+
+	tableSet.maintain(now)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, found := tableSet.reportTable.Entities[tt.reportID]; found == tt.expired {
+				t.Errorf("Expected the report to be listed %t, got %t", !tt.expired, found)
+			}
+			if _, found := tableSet.confirmationTable.Entities[confirmations[tt.reportID]]; found == tt.expired {
+				t.Errorf("Expected the confirmation to be kept %t, got %t", !tt.expired, found)
+			}
+
+			ids := tableSet.reportTable.prefixes.Lookup(net.IPNet{IP: net.ParseIP(tt.address).To4(), Mask: net.CIDRMask(32, 32)}, PrefixMatchExact)
+			if (len(ids) == 0) != tt.expired {
+				t.Errorf("Expected the prefix to be indexed %t, got %d reports", !tt.expired, len(ids))
+			}
+		})
+	}
+
+	if expected := now.Add(time.Hour); !tableSet.reportTable.nextExpiry.Equal(expected) {
+		t.Errorf("Expected the next expiry at %s, got %s", expected, tableSet.reportTable.nextExpiry)
+	}
+}
+
+//Only the events of reports which expired or were delisted longer than RetainExpired ago are removed
+func Test_RetireEvents(t *testing.T) {
+	node := newTestNode(t, "node-a")
+	now := time.Now()
+
+	signedAt := func(event *GenericEvent, timestamp time.Time) *GenericEvent {
+		event.Timestamp = timestamp.Unix()
+		return event
+	}
+
+	report := func(updateType abusemesh.TableEventType, reportID uuid.UUID, address string, listedAt time.Time) Event {
+		return signedAt(node.report(t, updateType, reportID, address), listedAt)
+	}
+
+	delist := func(reportID uuid.UUID, acceptanceType abusemesh.TableEventType, acceptedAt time.Time) []Event {
+		requestID := uuid.New()
+
+		request, err := node.author.DelistRequestEvent(abusemesh.TableEventType_TABLE_UPDATE_NEW, &abusemesh.DelistRequest{
+			Uuid:      &abusemesh.UUID{Uuid: requestID.String()},
+			Report:    &abusemesh.UUID{Uuid: reportID.String()},
+			Requester: &abusemesh.UUID{Uuid: uuid.New().String()},
+		})
+		if err != nil {
+			t.Fatalf("Error while signing delist request: %s", err)
+		}
+
+		acceptance, err := node.author.DelistAcceptanceEvent(acceptanceType, &abusemesh.DelistAcceptance{
+			Uuid:          &abusemesh.UUID{Uuid: uuid.New().String()},
+			DelistRequest: &abusemesh.UUID{Uuid: requestID.String()},
+			Acceptor:      &abusemesh.UUID{Uuid: node.id.String()},
+		})
+		if err != nil {
+			t.Fatalf("Error while signing delist acceptance: %s", err)
+		}
+
+		return []Event{signedAt(request, acceptedAt), signedAt(acceptance, acceptedAt)}
+	}
+
+	expired, recent, delisted, relisted, deleted := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	confirmation, err := node.author.ReportConfirmationEvent(abusemesh.TableEventType_TABLE_UPDATE_NEW, &abusemesh.ReportConfirmation{
+		Uuid:      &abusemesh.UUID{Uuid: uuid.New().String()},
+		Report:    &abusemesh.UUID{Uuid: expired.String()},
+		Confirmer: &abusemesh.UUID{Uuid: uuid.New().String()},
+	})
+	if err != nil {
+		t.Fatalf("Error while signing confirmation: %s", err)
+	}
+
+	//The events of a report, retired is true if they are removed once RetainExpired has passed
+	reports := []struct {
+		name    string
+		events  []Event
+		retired bool
+	}{
+		{
+			//Expired two hours ago, longer than RetainExpired
+			name:    "expired",
+			events:  []Event{report(abusemesh.TableEventType_TABLE_UPDATE_NEW, expired, "198.51.100.1", now.Add(-3*time.Hour)), confirmation},
+			retired: true,
+		},
+		{
+			//Expired half a hour ago, within RetainExpired
+			name:   "recent",
+			events: []Event{report(abusemesh.TableEventType_TABLE_UPDATE_NEW, recent, "198.51.100.2", now.Add(-90*time.Minute))},
+		},
+		{
+			//Delisted two hours ago
+			name: "delisted",
+			events: append(
+				[]Event{report(abusemesh.TableEventType_TABLE_UPDATE_NEW, delisted, "198.51.100.3", now)},
+				delist(delisted, abusemesh.TableEventType_TABLE_UPDATE_NEW, now.Add(-2*time.Hour))...,
+			),
+			retired: true,
+		},
+		{
+			//The acceptance was withdrawn, so the report is listed again
+			name: "relisted",
+			events: append(
+				[]Event{report(abusemesh.TableEventType_TABLE_UPDATE_NEW, relisted, "198.51.100.4", now)},
+				delist(relisted, abusemesh.TableEventType_TABLE_UPDATE_DELETE, now.Add(-2*time.Hour))...,
+			),
+		},
+		{
+			//Deleted reports are left to the compaction, which keeps the deletion
+			name: "deleted",
+			events: []Event{
+				report(abusemesh.TableEventType_TABLE_UPDATE_NEW, deleted, "198.51.100.5", now.Add(-3*time.Hour)),
+				report(abusemesh.TableEventType_TABLE_UPDATE_DELETE, deleted, "198.51.100.5", now.Add(-2*time.Hour)),
+			},
+		},
+	}
+
+	events := []Event{node.announce(t)}
+	retained := []Event{events[0]}
+	for _, report := range reports {
+		events = append(events, report.events...)
+		if !report.retired {
+			retained = append(retained, report.events...)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		retainExpired time.Duration
+		expected      []Event
+	}{
+		{name: "retired", retainExpired: time.Hour, expected: retained},
+		{name: "retained forever", retainExpired: 0, expected: events},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := CompactionPolicy{
+				RetainExpired: tt.retainExpired,
+				ReportTTL:     ReportTTLPolicy{Default: time.Hour},
+			}
+
+			assertEventIDs(t, tt.name, retireEvents(events, policy, now), tt.expected)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package entities
+
+import (
+	"bytes"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/utils/conv"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"
+	pgperrors "golang.org/x/crypto/openpgp/errors"
+)
+
+//signedPayload returns the bytes of the event which are covered by the signature
+//This is the canonical encoding of the event with the signature field left empty
+func (event *GenericEvent) signedPayload() ([]byte, error) {
+	unsigned := event.TableEvent
+	unsigned.Signature = nil
+
+	payload, err := CanonicalEncode(&unsigned)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while encoding event payload")
+	}
+
+	return payload, nil
+}
+
+//sign creates a detached signature over the payload of the event with the private key of the entity
+//Returns ErrNoPrivateKey if the entity has no private key which can be used for signing
+func (event *GenericEvent) sign(entity *openpgp.Entity) error {
+	if entity == nil || entity.PrivateKey == nil || entity.PrivateKey.Encrypted {
+		return ErrNoPrivateKey
+	}
+
+	payload, err := event.signedPayload()
+	if err != nil {
+		return err
+	}
+
+	var signature bytes.Buffer
+
+	err = openpgp.DetachSign(&signature, entity, bytes.NewReader(payload), nil)
+	if err != nil {
+		return errors.Wrap(err, "Error while signing event")
+	}
+
+	event.Signature = signature.Bytes()
+
+	return nil
+}
+
+//verifySignature checks that the detached signature of the event was made by the key of the signer over the payload of the event
+//Returns ErrSignatureInvalid or ErrPayloadTampered if the signature can't be verified
+func (event *GenericEvent) verifySignature(signer *Node) error {
+	signature := event.GetSignature()
+	if len(signature) == 0 {
+		return errors.Wrap(ErrSignatureInvalid, "Event is not signed")
+	}
+
+	if signer.PGPEntity == nil {
+		return errors.Wrap(ErrSignatureInvalid, "Signer has no PGP entity")
+	}
+
+	payload, err := event.signedPayload()
+	if err != nil {
+		return err
+	}
+
+	keyRing := openpgp.EntityList{signer.PGPEntity}
+
+	_, err = openpgp.CheckDetachedSignature(keyRing, bytes.NewReader(payload), bytes.NewReader(signature))
+	if err == nil {
+		return nil
+	}
+
+	//A signature error means the signature was made by the key of the signer but the hash doesn't match the payload
+	if _, ok := err.(pgperrors.SignatureError); ok {
+		return errors.Wrap(ErrPayloadTampered, err.Error())
+	}
+
+	//Any other error means the signature is malformed or made by a different key
+	return errors.Wrap(ErrSignatureInvalid, err.Error())
+}
+
+//verifySignedBy checks that the event is signed by the node with the given id
+//Returns ErrUnknownSigner if the node is not in the node table
+func (event *GenericEvent) verifySignedBy(tableSet *TableSet, signerID *abusemesh.UUID) error {
+	nodeID, err := conv.AuuidToGuuid(signerID)
+	if err != nil {
+		return errors.Wrap(err, "Signer ID invalid")
+	}
+
+	signer := tableSet.getNode(nodeID)
+	if signer == nil {
+		return ErrUnknownSigner
+	}
+
+	return event.verifySignature(signer)
+}
+
+//verifyOwnedBy checks that the event is signed by the owner of the entity it changes
+//A signature which is not made by the key of the owner is rejected with the notOwner error instead of ErrSignatureInvalid
+func (event *GenericEvent) verifyOwnedBy(owner *Node, notOwner error) error {
+	err := event.verifySignature(owner)
+	if errors.Cause(err) == ErrSignatureInvalid {
+		return errors.Wrap(notOwner, err.Error())
+	}
+
+	return err
+}
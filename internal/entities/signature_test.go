@@ -0,0 +1,75 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+//cloneEvent returns a deep copy of the event which can be changed without changing the original
+func cloneEvent(event *GenericEvent) *GenericEvent {
+	return &GenericEvent{TableEvent: *proto.Clone(&event.TableEvent).(*abusemesh.TableEvent)}
+}
+
+//Report events are only valid if they are signed by the key of the reporter over the exact payload
+func Test_GenericEvent_ValidateReportSignature(t *testing.T) {
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	reporter := newTestNode(t, "reporter")
+	other := newTestNode(t, "other")
+	unknown := newTestNode(t, "unknown")
+
+	for _, node := range []testNode{reporter, other} {
+		err := applyEvent(tableSet, node.announce(t))
+		if err != nil {
+			t.Fatalf("Error while announcing node: %s", err)
+		}
+	}
+
+	valid := reporter.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, uuid.New(), "198.51.100.1")
+
+	unsigned := cloneEvent(valid)
+	unsigned.Signature = nil
+
+	tampered := cloneEvent(valid)
+	tampered.GetReport().IpAddress.Address = "198.51.100.2"
+
+	//Signed by a other known node on behalf of the reporter
+	forged, err := other.author.ReportEvent(abusemesh.TableEventType_TABLE_UPDATE_NEW, valid.GetReport())
+	if err != nil {
+		t.Fatalf("Error while signing report event: %s", err)
+	}
+
+	tests := []struct {
+		name     string
+		event    *GenericEvent
+		expected error
+	}{
+		{name: "valid", event: valid},
+		{name: "unknown signer", event: unknown.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, uuid.New(), "198.51.100.1"), expected: ErrUnknownSigner},
+		{name: "unsigned", event: unsigned, expected: ErrSignatureInvalid},
+		{name: "tampered", event: tampered, expected: ErrPayloadTampered},
+		{name: "forged", event: forged, expected: ErrSignatureInvalid},
+	}
+
+	for _, test := range tests {
+		valid, err := test.event.Validate(tableSet)
+		if test.expected == nil {
+			if !valid || err != nil {
+				t.Errorf("%s: expected the event to be valid, got: %v", test.name, err)
+			}
+			continue
+		}
+
+		if valid {
+			t.Errorf("%s: expected the event to be refused", test.name)
+		}
+		if errors.Cause(err) != test.expected {
+			t.Errorf("%s: expected %s, got: %v", test.name, test.expected, err)
+		}
+	}
+}
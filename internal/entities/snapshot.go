@@ -0,0 +1,263 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+//A EventSnapshot is a compacted version of the event stream up to a offset
+//Instead of the derived table state a snapshot holds the original signed events, this way a peer which bootstraps
+//from a snapshot can still verify every event and derive the same TableSet state as it would from the full stream
+type EventSnapshot struct {
+	//The snapshot covers all events before this offset
+	Offset uint64
+
+	//The events needed to derive the TableSet state at Offset, ordered by their original offset
+	Events []Event
+}
+
+//CompactionPolicy determines when and how far a event stream is compacted
+type CompactionPolicy struct {
+	//How often a snapshot is taken, 0 disables compaction
+	Interval time.Duration
+
+	//The amount of most recent events which are never compacted,
+	//peers which were disconnected for less than this amount of events can resume without a snapshot
+	Retain uint64
+
+	//How long the events of reports which expired or were delisted are kept, 0 keeps them forever
+	//The events are only removed when they are compacted into a snapshot
+	RetainExpired time.Duration
+
+	//Determines when reports expire, should be the same policy as the one of the TableSet
+	ReportTTL ReportTTLPolicy
+}
+
+//timer returns a channel which receives a value every interval and a function to stop it
+//If compaction is disabled the channel never receives a value
+func (policy CompactionPolicy) timer() (<-chan time.Time, func()) {
+	if policy.Interval <= 0 {
+		return nil, func() {}
+	}
+
+	ticker := time.NewTicker(policy.Interval)
+
+	return ticker.C, ticker.Stop
+}
+
+//compactEvents returns the events which are needed to derive the same table state as the given events
+//For every entity the first event and the newest event of every update type are kept, in their original order.
+//Replaying those gives the same result because NEW and EDIT events replace the whole entity
+//and the tables only apply a event if it is newer than the last event applied to the entity.
+//A deletion is final, so of a deleted entity only the first event and the deletion are kept, the entity has to exist to be deleted.
+//Revivable entities are compacted by compactRevivable, a deletion of those is not final.
+//The events of nodes are never compacted, every key a node has used is needed to verify the events it signed with that key
+func compactEvents(events []Event) []Event {
+	type entityEvents map[abusemesh.TableEventType]int
+
+	//The first deletion of every entity, which is the one the tables apply
+	deletions := make(map[string]int)
+	for i, event := range events {
+		genericEvent, ok := event.(*GenericEvent)
+		if !ok || genericEvent.UpdateType != abusemesh.TableEventType_TABLE_UPDATE_DELETE {
+			continue
+		}
+
+		key := eventEntityKey(genericEvent)
+		if _, found := deletions[key]; !found && key != "" {
+			deletions[key] = i
+		}
+	}
+
+	keep := compactRevivable(events)
+	entities := make(map[string]entityEvents)
+
+	for i, event := range events {
+		genericEvent, ok := event.(*GenericEvent)
+		if !ok {
+			//Events we can't interpret are always kept
+			keep[i] = true
+			continue
+		}
+
+		key := eventEntityKey(genericEvent)
+		if key == "" || genericEvent.GetNode() != nil {
+			keep[i] = true
+			continue
+		}
+
+		if revivable(key) {
+			continue
+		}
+
+		entity, found := entities[key]
+		if !found {
+			//The first event of a entity is kept regardless of its type
+			keep[i] = true
+			entities[key] = entityEvents{}
+			continue
+		}
+
+		if deletion, deleted := deletions[key]; deleted {
+			if i == deletion {
+				keep[i] = true
+			}
+			continue
+		}
+
+		//Replace the previous event of the same type, unless the previous event is newer
+		if previous, found := entity[genericEvent.UpdateType]; found {
+			previousVersion := eventVersion(events[previous].(*GenericEvent))
+			if previousVersion.After(eventVersion(genericEvent)) {
+				continue
+			}
+
+			delete(keep, previous)
+		}
+
+		entity[genericEvent.UpdateType] = i
+		keep[i] = true
+	}
+
+	compacted := make([]Event, 0, len(keep))
+	for i, event := range events {
+		if keep[i] {
+			compacted = append(compacted, event)
+		}
+	}
+
+	return compacted
+}
+
+//compactRevivable returns the indexes of the events which are kept of the revivable entities
+//The events are checked against the versions in the same way as the tables do. Of a entity which exists after the events
+//the event which created it the last time and the last applied event are kept, replaying them recreates the entity.
+//Of a deleted entity only the deletion is kept, its version rejects the older events which may still arrive
+func compactRevivable(events []Event) map[int]bool {
+	versions := VersionTable{versions: make(map[string]entityVersion)}
+
+	created := make(map[string]int)
+	last := make(map[string]int)
+
+	for i, event := range events {
+		genericEvent, ok := event.(*GenericEvent)
+		if !ok {
+			continue
+		}
+
+		key, version := eventEntityKey(genericEvent), eventVersion(genericEvent)
+		if !revivable(key) || versions.check(key, version, genericEvent.UpdateType) != nil {
+			continue
+		}
+
+		previous, found := last[key]
+		if !found || events[previous].(*GenericEvent).UpdateType == abusemesh.TableEventType_TABLE_UPDATE_DELETE {
+			created[key] = i
+		}
+
+		last[key] = i
+		versions.record(key, version, genericEvent.UpdateType, uuid.Nil, time.Time{})
+	}
+
+	keep := make(map[int]bool)
+	for key, i := range last {
+		keep[i] = true
+
+		if events[i].(*GenericEvent).UpdateType != abusemesh.TableEventType_TABLE_UPDATE_DELETE {
+			keep[created[key]] = true
+		}
+	}
+
+	return keep
+}
+
+//eventEntityKey returns a key which is equal for all events about the same entity, or a empty string if the event has no entity
+func eventEntityKey(event *GenericEvent) string {
+	switch e := event.GetTableEntity().(type) {
+	case *abusemesh.TableEvent_Node:
+		return "node/" + e.Node.GetUuid().GetUuid()
+	case *abusemesh.TableEvent_Report:
+		return "report/" + e.Report.GetUuid().GetUuid()
+	case *abusemesh.TableEvent_ReportConfirmation:
+		return "report-confirmation/" + e.ReportConfirmation.GetUuid().GetUuid()
+	case *abusemesh.TableEvent_DelistRequests:
+		return "delist-request/" + e.DelistRequests.GetUuid().GetUuid()
+	case *abusemesh.TableEvent_DelistAcceptance:
+		return "delist-acceptance/" + e.DelistAcceptance.GetUuid().GetUuid()
+	case *abusemesh.TableEvent_Neighbor:
+		//Both nodes of a neighborship announce it, each announcement is a entity owned by the announcing node
+		return "neighbor/" + e.Neighbor.GetNode().GetUuid() + "/" + e.Neighbor.GetNeighbor().GetUuid()
+	default:
+		return ""
+	}
+}
+
+//compactionOffset returns the offset up to which the stream can be compacted according to the compaction policy
+//Events which subscribers still have to read from the stream to resync are not compacted
+func (stream *inMemoryEventStream) compactionOffset() uint64 {
+	stream.eventsLock.RLock()
+	defer stream.eventsLock.RUnlock()
+
+	base, end := stream.store.base(), stream.store.end()
+	if end < base+stream.compaction.Retain {
+		return base
+	}
+
+	offset := end - stream.compaction.Retain
+
+	stream.observerLock.Lock()
+	for _, sub := range stream.subscribers {
+		if sub.resync && sub.offset < offset {
+			offset = sub.offset
+		}
+	}
+	stream.observerLock.Unlock()
+
+	return offset
+}
+
+//compact takes a new snapshot which covers all events before the offset and removes those events from the stream
+func (stream *inMemoryEventStream) compact(offset uint64) error {
+	stream.eventsLock.Lock()
+	defer stream.eventsLock.Unlock()
+
+	base := stream.store.base()
+	if offset <= base {
+		return nil
+	}
+
+	snapshot, err := stream.store.snapshot()
+	if err != nil {
+		return err
+	}
+
+	compacted, err := stream.store.events(base, offset)
+	if err != nil {
+		return err
+	}
+
+	events := make([]Event, 0, len(snapshot.Events)+len(compacted))
+	events = append(events, snapshot.Events...)
+	events = append(events, compacted...)
+
+	snapshot = EventSnapshot{
+		Offset: offset,
+		Events: compactEvents(retireEvents(events, stream.compaction, time.Now())),
+	}
+
+	err = stream.store.compact(snapshot)
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"offset":          offset,
+		"snapshot-events": len(snapshot.Events),
+		"removed-events":  len(events) - len(snapshot.Events),
+	}).Info("Event stream compacted")
+
+	return nil
+}
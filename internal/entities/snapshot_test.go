@@ -0,0 +1,146 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/google/uuid"
+)
+
+//A deletion is final, a newer edit after it must not be kept in the snapshot
+func Test_CompactEvents_Deletion(t *testing.T) {
+	node := newTestNode(t, "node-a")
+	reportID := uuid.New()
+	now := time.Now()
+
+	newReport := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, reportID, "198.51.100.1")
+	newReport.Timestamp = now.Add(-3 * time.Second).Unix()
+	deleteReport := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_DELETE, reportID, "198.51.100.1")
+	deleteReport.Timestamp = now.Add(-2 * time.Second).Unix()
+	editReport := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_EDIT, reportID, "198.51.100.2")
+	editReport.Timestamp = now.Unix()
+
+	events := []Event{newReport, deleteReport, editReport}
+	assertEventIDs(t, "compacted", compactEvents(events), []Event{newReport, deleteReport})
+
+	//Replaying the compacted events leaves the report deleted, the timestamps were changed so the events are not validated
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	err := processEvent(tableSet, node.announce(t))
+	if err != nil {
+		t.Fatalf("Error while announcing node: %s", err)
+	}
+	for _, event := range compactEvents(events) {
+		err := processEvent(tableSet, event)
+		if err != nil {
+			t.Fatalf("Error while replaying event: %s", err)
+		}
+	}
+	if tableSet.getReport(reportID) != nil {
+		t.Error("Expected the report to stay deleted")
+	}
+}
+
+//Every event of a node is kept, older events of other entities are replaced
+func Test_CompactEvents_Nodes(t *testing.T) {
+	node := newTestNode(t, "node-a")
+	reportID := uuid.New()
+	now := time.Now()
+
+	announce := node.announce(t)
+	announce.Timestamp = now.Add(-3 * time.Second).Unix()
+	reannounce := node.announce(t)
+	reannounce.UpdateType = abusemesh.TableEventType_TABLE_UPDATE_EDIT
+	reannounce.Timestamp = now.Add(-2 * time.Second).Unix()
+	newReport := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, reportID, "198.51.100.1")
+	newReport.Timestamp = now.Add(-3 * time.Second).Unix()
+	firstEdit := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_EDIT, reportID, "198.51.100.2")
+	firstEdit.Timestamp = now.Add(-2 * time.Second).Unix()
+	secondEdit := node.report(t, abusemesh.TableEventType_TABLE_UPDATE_EDIT, reportID, "198.51.100.3")
+	secondEdit.Timestamp = now.Unix()
+	reannounceAgain := node.announce(t)
+	reannounceAgain.UpdateType = abusemesh.TableEventType_TABLE_UPDATE_EDIT
+	reannounceAgain.Timestamp = now.Unix()
+
+	events := []Event{announce, reannounce, newReport, firstEdit, secondEdit, reannounceAgain}
+	expected := []Event{announce, reannounce, newReport, secondEdit, reannounceAgain}
+	assertEventIDs(t, "compacted", compactEvents(events), expected)
+}
+
+//A neighborship which is withdrawn and announced again exists after replaying the compacted events,
+//a withdrawal which is the newest event leaves it withdrawn
+func Test_CompactEvents_NeighborFlap(t *testing.T) {
+	node, neighbor := newTestNode(t, "node-a"), newTestNode(t, "node-b")
+	now := time.Now()
+
+	neighborEvent := func(from, to testNode, updateType abusemesh.TableEventType, age time.Duration) *GenericEvent {
+		event, err := from.author.NeighborEvent(updateType, &abusemesh.Neighbor{
+			Node:     &abusemesh.UUID{Uuid: from.id.String()},
+			Neighbor: &abusemesh.UUID{Uuid: to.id.String()},
+		})
+		if err != nil {
+			t.Fatalf("Error while signing neighbor event: %s", err)
+		}
+		event.Timestamp = now.Add(-age).Unix()
+
+		return event
+	}
+
+	confirm := neighborEvent(neighbor, node, abusemesh.TableEventType_TABLE_UPDATE_NEW, 5*time.Second)
+	announce := neighborEvent(node, neighbor, abusemesh.TableEventType_TABLE_UPDATE_NEW, 4*time.Second)
+	withdraw := neighborEvent(node, neighbor, abusemesh.TableEventType_TABLE_UPDATE_DELETE, 3*time.Second)
+	reannounce := neighborEvent(node, neighbor, abusemesh.TableEventType_TABLE_UPDATE_NEW, 2*time.Second)
+	edit := neighborEvent(node, neighbor, abusemesh.TableEventType_TABLE_UPDATE_EDIT, time.Second)
+	withdrawAgain := neighborEvent(node, neighbor, abusemesh.TableEventType_TABLE_UPDATE_DELETE, 0)
+
+	tests := []struct {
+		name      string
+		events    []Event
+		compacted []Event
+		neighbors bool
+	}{
+		{
+			name:      "announced again",
+			events:    []Event{confirm, announce, withdraw, reannounce, edit},
+			compacted: []Event{confirm, reannounce, edit},
+			neighbors: true,
+		},
+		{
+			name:      "withdrawn again",
+			events:    []Event{confirm, announce, withdraw, reannounce, withdrawAgain},
+			compacted: []Event{confirm, withdrawAgain},
+		},
+		{
+			name:      "stale withdrawal",
+			events:    []Event{confirm, announce, reannounce, withdraw},
+			compacted: []Event{confirm, announce, reannounce},
+			neighbors: true,
+		},
+	}
+
+	for _, test := range tests {
+		compacted := compactEvents(test.events)
+		assertEventIDs(t, test.name, compacted, test.compacted)
+
+		//Both the full and the compacted events lead to the same neighborship
+		for _, events := range [][]Event{test.events, compacted} {
+			tableSet, stopTableSet := runTestTableSet()
+
+			for _, event := range events {
+				err := processEvent(tableSet, event)
+				if err != nil && !isStale(err) {
+					t.Fatalf("%s: error while replaying event: %s", test.name, err)
+				}
+			}
+
+			_, found := tableSet.Snapshot().Neighbors[Neighbor{Node: node.id, Neighbor: neighbor.id}.Edge()]
+			if found != test.neighbors {
+				t.Errorf("%s: expected neighborship %t after %d events, got %t", test.name, test.neighbors, len(events), found)
+			}
+
+			stopTableSet()
+		}
+	}
+}
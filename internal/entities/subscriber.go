@@ -0,0 +1,368 @@
+package entities
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+//ErrSlowConsumer signals that a observer was disconnected because it couldn't keep up with the event stream
+var ErrSlowConsumer = errors.New("The observer can't keep up with the event stream")
+
+//OverflowPolicy determines what happens when the queue of a subscriber is full
+type OverflowPolicy int
+
+const (
+	//OverflowResync drops new events for the subscriber and marks it for resync,
+	//once the subscriber has processed its queue the dropped events are read from the stream and delivered
+	OverflowResync OverflowPolicy = iota
+
+	//OverflowDisconnect detaches the subscriber, only applies to observers which implement DisconnectableObserver
+	//other observers are resynced instead
+	OverflowDisconnect
+)
+
+func (policy OverflowPolicy) String() string {
+	switch policy {
+	case OverflowResync:
+		return "resync"
+	case OverflowDisconnect:
+		return "disconnect"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(policy))
+	}
+}
+
+//SubscriberPolicy determines how events are delivered to the observers of a event stream
+type SubscriberPolicy struct {
+	//The amount of events which can be queued for a single observer
+	QueueSize int
+
+	//What happens when the queue of a observer is full
+	Overflow OverflowPolicy
+
+	//How long the stream waits for a validation observer to receive the earlier events before it validates a event,
+	//a observer which doesn't receive them in time is resynced
+	ValidationTimeout time.Duration
+}
+
+//DefaultSubscriberQueueSize is the queue size of subscribers if the policy doesn't specify one
+const DefaultSubscriberQueueSize = 1000
+
+//DefaultValidationTimeout is the ValidationTimeout of the policy if the policy doesn't specify one
+const DefaultValidationTimeout = time.Second
+
+//catchUpRetryInterval is the time after which a subscriber tries again to read the events it missed from the stream
+const catchUpRetryInterval = time.Second
+
+//A DisconnectableObserver is a EventObserver which can be disconnected from the event stream
+type DisconnectableObserver interface {
+	EventObserver
+
+	//Disconnected is called after the observer has been detached from the event stream by the stream itself
+	Disconnected(reason error)
+}
+
+//A validationObserver is a EventObserver whose state is queried by the validation of events, the TableSet.
+//It has its own queue like every observer, but before a event is validated the stream waits until the observer
+//has received the events before it, otherwise a report which directly follows the node of its reporter would be refused.
+//The stream waits at most the ValidationTimeout of the SubscriberPolicy, a observer which is slower is resynced
+//and events are validated against its state as it is. Those events may be refused and quarantined, they can be revalidated later
+type validationObserver interface {
+	EventObserver
+
+	validation()
+}
+
+//SubscriberStatus describes how far a observer is behind the event stream
+type SubscriberStatus struct {
+	//The type of the observer
+	Observer string
+
+	//The offset of the next event which will be delivered to the observer
+	Offset uint64
+
+	//The amount of events the observer is behind
+	Lag uint64
+
+	//The amount of events in the queue of the observer
+	Queued int
+
+	//The amount of events which were dropped because the queue was full
+	Dropped uint64
+
+	//True if the observer will be resynced once its queue is empty
+	Resyncing bool
+}
+
+//A queuedEvent is a event in the queue of a subscriber
+type queuedEvent struct {
+	offset uint64
+	event  Event
+}
+
+//A subscriber delivers events to a observer from its own goroutine, so a slow observer doesn't block the event stream
+type subscriber struct {
+	observer EventObserver
+
+	//The events which have yet to be delivered
+	queue chan queuedEvent
+
+	//The offset of the next event which will be queued, protected by the observer lock of the stream
+	offset uint64
+
+	//The offset of the next event which will be delivered, accessed atomically
+	delivered uint64
+
+	//True if events have been dropped and must be read from the stream, protected by the observer lock of the stream
+	resync bool
+
+	//The amount of events which were not queued, protected by the observer lock of the stream
+	dropped uint64
+
+	//Signals the goroutine that it has to resync
+	wake chan struct{}
+
+	//Receives a value when events have been delivered
+	progress chan struct{}
+
+	//Closed when the subscriber is detached
+	stop chan struct{}
+}
+
+func newSubscriber(observer EventObserver, offset uint64, queueSize int) *subscriber {
+	if queueSize <= 0 {
+		queueSize = DefaultSubscriberQueueSize
+	}
+
+	return &subscriber{
+		observer:  observer,
+		queue:     make(chan queuedEvent, queueSize),
+		offset:    offset,
+		delivered: offset,
+		wake:      make(chan struct{}, 1),
+		progress:  make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+	}
+}
+
+//markResync marks the subscriber for resync, the observer lock must be held by the caller
+func (sub *subscriber) markResync() {
+	sub.resync = true
+
+	select {
+	case sub.wake <- struct{}{}:
+	default:
+	}
+}
+
+//setDelivered records that all events before the offset have been delivered
+func (sub *subscriber) setDelivered(offset uint64) {
+	atomic.StoreUint64(&sub.delivered, offset)
+
+	select {
+	case sub.progress <- struct{}{}:
+	default:
+	}
+}
+
+//run delivers events to the observer until the subscriber is stopped
+func (sub *subscriber) run(stream *inMemoryEventStream) {
+	for {
+		//First deliver everything in the queue
+		select {
+		case queued := <-sub.queue:
+			sub.observer.EventUpdate(queued.event)
+			sub.setDelivered(queued.offset + 1)
+			continue
+		case <-sub.stop:
+			return
+		default:
+		}
+
+		//The queue is empty, if events have been dropped they are read from the stream
+		events, end, resyncing, err := stream.catchUp(sub)
+		if err != nil {
+			//The subscriber stays marked for resync, reading the events is tried again later
+			logrus.WithError(err).Error("Error while reading events for subscriber")
+
+			select {
+			case <-time.After(catchUpRetryInterval):
+			case <-sub.stop:
+				return
+			}
+			continue
+		}
+
+		if resyncing {
+			for _, event := range events {
+				select {
+				case <-sub.stop:
+					return
+				default:
+				}
+
+				sub.observer.EventUpdate(event)
+			}
+
+			sub.setDelivered(end)
+			continue
+		}
+
+		select {
+		case queued := <-sub.queue:
+			sub.observer.EventUpdate(queued.event)
+			sub.setDelivered(queued.offset + 1)
+		case <-sub.wake:
+		case <-sub.stop:
+			return
+		}
+	}
+}
+
+//enqueue queues the event for the subscriber or applies the overflow policy if the queue is full
+//Returns false if the subscriber has to be detached, the observer lock must be held by the caller
+func (stream *inMemoryEventStream) enqueue(sub *subscriber, offset uint64, event Event) bool {
+	if sub.resync {
+		sub.dropped++
+		return true
+	}
+
+	select {
+	case sub.queue <- queuedEvent{offset: offset, event: event}:
+		sub.offset = offset + 1
+		return true
+	default:
+	}
+
+	logger := logrus.WithFields(logrus.Fields{
+		"observer": fmt.Sprintf("%T", sub.observer),
+		"lag":      offset - atomic.LoadUint64(&sub.delivered),
+	})
+
+	if disconnectable, ok := sub.observer.(DisconnectableObserver); ok && stream.subscriberPolicy.Overflow == OverflowDisconnect {
+		logger.Warn("Observer can't keep up with the event stream, disconnecting")
+
+		close(sub.stop)
+		go disconnectable.Disconnected(ErrSlowConsumer)
+
+		return false
+	}
+
+	logger.Warn("Observer can't keep up with the event stream, dropping events until it has caught up")
+
+	sub.dropped++
+	sub.markResync()
+
+	return true
+}
+
+//catchUp returns the events a subscriber has missed since it was marked for resync and the offset after them
+//Returns false if the subscriber is not marked for resync
+func (stream *inMemoryEventStream) catchUp(sub *subscriber) ([]Event, uint64, bool, error) {
+	stream.eventsLock.RLock()
+	defer stream.eventsLock.RUnlock()
+
+	stream.observerLock.Lock()
+	defer stream.observerLock.Unlock()
+
+	if !sub.resync {
+		return nil, 0, false, nil
+	}
+
+	events, err := stream.eventsFrom(sub.offset)
+	if err != nil {
+		return nil, 0, true, err
+	}
+	end := stream.end()
+
+	sub.offset = end
+	sub.resync = false
+
+	return events, end, true, nil
+}
+
+//awaitValidationObservers waits until the validation observers have received all events in the stream
+//A observer which is resyncing is not waited for. A observer which doesn't receive the events within the ValidationTimeout
+//is marked for resync, so the next events are not held up by it either
+func (stream *inMemoryEventStream) awaitValidationObservers() {
+	stream.eventsLock.RLock()
+	end := stream.end()
+	stream.eventsLock.RUnlock()
+
+	stream.observerLock.Lock()
+	var waiting []*subscriber
+	for _, sub := range stream.subscribers {
+		if _, ok := sub.observer.(validationObserver); ok && !sub.resync && atomic.LoadUint64(&sub.delivered) < end {
+			waiting = append(waiting, sub)
+		}
+	}
+	stream.observerLock.Unlock()
+
+	if len(waiting) == 0 {
+		return
+	}
+
+	timeout := stream.subscriberPolicy.ValidationTimeout
+	if timeout <= 0 {
+		timeout = DefaultValidationTimeout
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+waiting:
+	for _, sub := range waiting {
+		for atomic.LoadUint64(&sub.delivered) < end {
+			select {
+			case <-sub.progress:
+			case <-sub.stop:
+				//A detached subscriber won't deliver anything anymore
+				continue waiting
+			case <-deadline.C:
+				stream.observerLock.Lock()
+				if !sub.resync {
+					logrus.WithFields(logrus.Fields{
+						"observer": fmt.Sprintf("%T", sub.observer),
+						"lag":      end - atomic.LoadUint64(&sub.delivered),
+					}).Warn("Observer needed for validation can't keep up with the event stream, resyncing it")
+
+					sub.markResync()
+				}
+				stream.observerLock.Unlock()
+
+				return
+			}
+		}
+	}
+}
+
+func (stream *inMemoryEventStream) GetSubscribers() []SubscriberStatus {
+	stream.eventsLock.RLock()
+	defer stream.eventsLock.RUnlock()
+
+	stream.observerLock.Lock()
+	defer stream.observerLock.Unlock()
+
+	end := stream.end()
+
+	statuses := make([]SubscriberStatus, 0, len(stream.subscribers))
+
+	for _, sub := range stream.subscribers {
+		delivered := atomic.LoadUint64(&sub.delivered)
+
+		statuses = append(statuses, SubscriberStatus{
+			Observer:  fmt.Sprintf("%T", sub.observer),
+			Offset:    delivered,
+			Lag:       end - delivered,
+			Queued:    len(sub.queue),
+			Dropped:   sub.dropped,
+			Resyncing: sub.resync,
+		})
+	}
+
+	return statuses
+}
@@ -2,63 +2,330 @@ package entities
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
+//ErrTableCorrupt signals that the state of the tables can no longer be trusted, it stops the TableSet
+//Any other error returned by a request only fails that request
+var ErrTableCorrupt = errors.New("The table state is corrupt")
+
 //A TableRequest is request which can be made to the table set
 type TableRequest interface {
 	Process(*TableSet) error
 }
 
+//A resultReporter is a TableRequest which reports the outcome of processing back to its caller
+type resultReporter interface {
+	reportResult(err error)
+}
+
+//sendResult sends the outcome of a request on the error channel without blocking the TableSet
+//The outcome is dropped if the channel has no room for it, so callers must use a buffered channel
+func sendResult(errorChan chan<- error, err error) {
+	if errorChan == nil {
+		return
+	}
+
+	select {
+	case errorChan <- err:
+	default:
+		logrus.WithError(err).Warn("Outcome of table request dropped, the error channel is full")
+	}
+}
+
+//TableStats holds the outcome of the requests processed by the TableSet
+type TableStats struct {
+	Processed uint64
+	Failed    uint64
+
+	//The amount of events which were not applied because they were superseded by another event about the same entity,
+	//this is expected when events arrive out of order so they don't count as failed
+	Stale uint64
+
+	//The amount of failed requests per request type
+	FailedByType map[string]uint64
+
+	//The last error and when it occurred, empty if no request has failed
+	LastError     string
+	LastErrorTime time.Time
+}
+
 //TableSet is a set containing all tables
 //The TableSet has it's own goroutine which can be used to query data from the tables
 type TableSet struct {
-	nodeTable NodeTable
-	Channel   chan TableRequest
+	nodeTable         NodeTable
+	reportTable       ReportTable
+	confirmationTable ConfirmationTable
+	delistTable       DelistTable
+	neighborTable     NeighborTable
+	versionTable      VersionTable
+	Channel           chan TableRequest
+
+	//stats is only accessed from the goroutine of the TableSet
+	stats TableStats
+
+	//snapshot is the last snapshot taken, it is reused as long as the tables are not written
+	snapshot *TableSnapshot
+
+	//changes collects the changes the current request makes to the tables, they are published to the subscriptions
+	changes       *changeSet
+	subscriptions map[*TableSubscription]struct{}
+
+	//NodeVerifier is used to confirm the claims of node announcements before they are accepted
+	NodeVerifier NodeVerifier
+
+	//ReportTTL determines when reports expire, it must not be changed once the TableSet is running
+	ReportTTL ReportTTLPolicy
+
+	//MaintenanceInterval determines how often expired entities are removed from the tables,
+	//it must not be changed once the TableSet is running
+	MaintenanceInterval time.Duration
+
+	//TombstoneTTL determines how long the version of a deleted or expired entity is kept,
+	//it must be longer than events can take to reach this node. It must not be changed once the TableSet is running
+	TombstoneTTL time.Duration
+}
+
+//DefaultMaintenanceInterval is used if the MaintenanceInterval of the TableSet is not set
+const DefaultMaintenanceInterval = time.Second
+
+//DefaultDelistExpiry is the time a reporter has to decide on a delist request before it expires
+const DefaultDelistExpiry = 30 * 24 * time.Hour
+
+//NewTableSet creates a new table set with empty tables
+func NewTableSet(channelBufferSize int) *TableSet {
+	changes := &changeSet{}
+
+	return &TableSet{
+		nodeTable: NodeTable{
+			Entities:      make(map[uuid.UUID]Node),
+			byASN:         make(nodeIndex),
+			byIP:          make(nodeIndex),
+			byFingerprint: make(nodeIndex),
+			equivocators:  make(map[uuid.UUID]struct{}),
+			changes:       changes,
+		},
+		reportTable: ReportTable{
+			Entities: make(map[uuid.UUID]Report),
+			changes:  changes,
+		},
+		confirmationTable: ConfirmationTable{
+			Entities: make(map[uuid.UUID]ReportConfirmation),
+			byReport: make(map[uuid.UUID]map[uuid.UUID]struct{}),
+			trust:    make(map[uuid.UUID]float64),
+			changes:  changes,
+		},
+		delistTable: DelistTable{
+			Entities:    make(map[uuid.UUID]DelistRequest),
+			ExpireAfter: DefaultDelistExpiry,
+			changes:     changes,
+		},
+		neighborTable: NeighborTable{
+			Entities:  make(map[NeighborEdge]Neighbor),
+			announced: make(map[neighborAnnouncement]time.Time),
+			adjacency: make(map[uuid.UUID]map[uuid.UUID]struct{}),
+			changes:   changes,
+		},
+		versionTable: VersionTable{
+			versions: make(map[string]entityVersion),
+		},
+		Channel: make(chan TableRequest, channelBufferSize),
+		stats: TableStats{
+			FailedByType: make(map[string]uint64),
+		},
+		changes:       changes,
+		subscriptions: make(map[*TableSubscription]struct{}),
+	}
 }
 
 //Run starts a goroutine which is used to interact with the tables
+//A failing request is logged and reported back to the caller, Run only returns when the tables are corrupt
 func (set *TableSet) Run(ctx context.Context) error {
+	interval := set.MaintenanceInterval
+	if interval <= 0 {
+		interval = DefaultMaintenanceInterval
+	}
+
+	maintenance := time.NewTicker(interval)
+	defer maintenance.Stop()
+
 	for {
 		select {
 		case req := <-set.Channel:
-			err := req.Process(set)
-			if err != nil {
+			err := set.process(req)
+			if errors.Cause(err) == ErrTableCorrupt {
 				return err
 			}
+		case now := <-maintenance.C:
+			set.maintain(now)
 		case <-ctx.Done():
 			return nil
 		}
 	}
 }
 
+//process processes a single request and records its outcome
+func (set *TableSet) process(req TableRequest) (err error) {
+	requestType := fmt.Sprintf("%T", req)
+
+	defer func() {
+		//A panic may have left a table half updated, so the tables can no longer be trusted
+		if r := recover(); r != nil {
+			err = errors.Wrapf(ErrTableCorrupt, "Panic while processing '%s': %v", requestType, r)
+		}
+
+		//A failed request may still have changed some of the tables
+		if errors.Cause(err) != ErrTableCorrupt {
+			set.publish()
+		}
+
+		set.stats.Processed++
+
+		if isStale(err) {
+			set.stats.Stale++
+
+			logrus.WithError(err).WithField("request", requestType).Debug("Event superseded by another event about the same entity")
+		} else if err != nil {
+			set.stats.Failed++
+			set.stats.FailedByType[requestType]++
+			set.stats.LastError = err.Error()
+			set.stats.LastErrorTime = time.Now()
+
+			logrus.WithError(err).WithField("request", requestType).Error("Error while processing table request")
+		}
+
+		if reporter, ok := req.(resultReporter); ok {
+			reporter.reportResult(err)
+		}
+	}()
+
+	return req.Process(set)
+}
+
+//maintain removes the entities which have expired, requests may see a expired entity until the next maintenance
+func (set *TableSet) maintain(now time.Time) {
+	set.expireReports(now)
+	set.delistTable.expire(now)
+	set.publish()
+
+	ttl := set.TombstoneTTL
+	if ttl <= 0 {
+		ttl = DefaultTombstoneTTL
+	}
+
+	set.versionTable.prune(now, ttl)
+}
+
+//GetTableStatsRequest can be used to request the outcome of the requests processed by the TableSet
+type GetTableStatsRequest struct {
+	ResponseChan chan<- TableStats
+}
+
+//Process processes the request and sends a copy of the stats on the ResponseChan
+func (req *GetTableStatsRequest) Process(tables *TableSet) error {
+	stats := tables.stats
+	stats.FailedByType = make(map[string]uint64, len(tables.stats.FailedByType))
+	for requestType, failed := range tables.stats.FailedByType {
+		stats.FailedByType[requestType] = failed
+	}
+
+	req.ResponseChan <- stats
+
+	return nil
+}
+
 //EventUpdate creates a new update table request and queues it
+//The TableSet is a validation observer, the stream waits for the request to be queued before the next event is validated
 func (set *TableSet) EventUpdate(event Event) {
 	set.Channel <- &UpdateTableRequest{
 		Event: event,
 	}
 }
 
+func (set *TableSet) validation() {}
+
+//UpdateTableRequest applies a event to the tables
 type UpdateTableRequest struct {
 	Event Event
+
+	//ErrorChan receives nil once the event is applied or the error which prevented it, it is optional.
+	//The channel must be buffered, the TableSet doesn't wait for the caller to receive the outcome
+	ErrorChan chan<- error
 }
 
+func (req *UpdateTableRequest) reportResult(err error) {
+	sendResult(req.ErrorChan, err)
+}
+
+//Process processes the request and applies the event to the table of its entity
+//Events which are not newer than the last event applied to the same entity are rejected with ErrStaleEvent,
+//events about a deleted entity are rejected with ErrDeletedEntity unless the entity is revivable and the event is newer
 func (req *UpdateTableRequest) Process(tables *TableSet) error {
 
 	switch event := req.Event.(type) {
 	case *GenericEvent:
 		eventType := event.UpdateType
+
+		key, version := eventEntityKey(event), eventVersion(event)
+
+		var owner uuid.UUID
+		if key != "" {
+			var err error
+			owner, err = tables.versionTable.checkOwner(key, event)
+			if err != nil {
+				return err
+			}
+
+			err = tables.versionTable.check(key, version, eventType)
+			if err != nil {
+				return err
+			}
+		}
+
 		switch tableEntity := event.GetTableEntity().(type) {
 		case *abusemesh.TableEvent_Node:
-			err := tables.nodeTable.handleTableEvent(eventType, tableEntity)
+			err := tables.nodeTable.handleTableEvent(event, tableEntity)
+			if err != nil {
+				return err
+			}
+		case *abusemesh.TableEvent_Report:
+			err := tables.reportTable.handleTableEvent(eventType, tableEntity, time.Unix(event.GetTimestamp(), 0), tables.ReportTTL)
+			if err != nil {
+				return err
+			}
+		case *abusemesh.TableEvent_ReportConfirmation:
+			err := tables.confirmationTable.handleTableEvent(eventType, tableEntity, time.Unix(event.GetTimestamp(), 0))
+			if err != nil {
+				return err
+			}
+		case *abusemesh.TableEvent_DelistRequests:
+			err := tables.delistTable.handleDelistRequestEvent(eventType, tableEntity, time.Unix(event.GetTimestamp(), 0))
+			if err != nil {
+				return err
+			}
+		case *abusemesh.TableEvent_DelistAcceptance:
+			err := tables.delistTable.handleDelistAcceptanceEvent(eventType, tableEntity, time.Unix(event.GetTimestamp(), 0), &tables.reportTable)
+			if err != nil {
+				return err
+			}
+		case *abusemesh.TableEvent_Neighbor:
+			err := tables.neighborTable.handleTableEvent(eventType, tableEntity, time.Unix(event.GetTimestamp(), 0))
 			if err != nil {
 				return err
 			}
 		default:
 			return errors.Errorf("Unknown event type '%T'", event)
 		}
+
+		if key != "" {
+			tables.versionTable.record(key, version, eventType, owner, time.Now())
+		}
 	default:
 		return errors.Errorf("Unknown event type '%T'", event)
 	}
@@ -0,0 +1,149 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//A TableSnapshot is a immutable and consistent view of all tables at one point in time
+//Readers can take their time iterating a snapshot without blocking the goroutine of the TableSet.
+//The maps are shared with other readers and, until the next write, with the tables themselves, so they must never be modified.
+type TableSnapshot struct {
+	Nodes          map[uuid.UUID]Node
+	Reports        map[uuid.UUID]Report
+	Confirmations  map[uuid.UUID]ReportConfirmation
+	DelistRequests map[uuid.UUID]DelistRequest
+	Neighbors      map[NeighborEdge]Neighbor
+
+	//The time at which the snapshot was taken
+	Taken time.Time
+}
+
+//The tables implement copy-on-write: taking a snapshot marks the maps of the tables as shared,
+//the first write after that copies the map of the written table so the snapshot keeps seeing the old state.
+//Taking a snapshot is cheap, the cost of the copy is paid at most once per snapshot by the writer.
+
+func (table *NodeTable) own() {
+	if !table.shared {
+		return
+	}
+
+	entities := make(map[uuid.UUID]Node, len(table.Entities))
+	for id, node := range table.Entities {
+		entities[id] = node
+	}
+
+	table.Entities = entities
+	table.shared = false
+}
+
+func (table *ReportTable) own() {
+	if !table.shared {
+		return
+	}
+
+	entities := make(map[uuid.UUID]Report, len(table.Entities))
+	for id, report := range table.Entities {
+		entities[id] = report
+	}
+
+	table.Entities = entities
+	table.shared = false
+}
+
+func (table *ConfirmationTable) own() {
+	if !table.shared {
+		return
+	}
+
+	entities := make(map[uuid.UUID]ReportConfirmation, len(table.Entities))
+	for id, confirmation := range table.Entities {
+		entities[id] = confirmation
+	}
+
+	table.Entities = entities
+	table.shared = false
+}
+
+func (table *DelistTable) own() {
+	if !table.shared {
+		return
+	}
+
+	entities := make(map[uuid.UUID]DelistRequest, len(table.Entities))
+	for id, request := range table.Entities {
+		entities[id] = request
+	}
+
+	table.Entities = entities
+	table.shared = false
+}
+
+func (table *NeighborTable) own() {
+	if !table.shared {
+		return
+	}
+
+	entities := make(map[NeighborEdge]Neighbor, len(table.Entities))
+	for edge, neighbor := range table.Entities {
+		entities[edge] = neighbor
+	}
+
+	table.Entities = entities
+	table.shared = false
+}
+
+//takeSnapshot returns a snapshot of the current state of the tables, must be called from the goroutine of the TableSet
+//If nothing was written since the last snapshot the same snapshot is returned
+func (set *TableSet) takeSnapshot() *TableSnapshot {
+	if set.snapshot != nil &&
+		set.nodeTable.shared &&
+		set.reportTable.shared &&
+		set.confirmationTable.shared &&
+		set.delistTable.shared &&
+		set.neighborTable.shared {
+		return set.snapshot
+	}
+
+	set.snapshot = &TableSnapshot{
+		Nodes:          set.nodeTable.Entities,
+		Reports:        set.reportTable.Entities,
+		Confirmations:  set.confirmationTable.Entities,
+		DelistRequests: set.delistTable.Entities,
+		Neighbors:      set.neighborTable.Entities,
+		Taken:          time.Now(),
+	}
+
+	set.nodeTable.shared = true
+	set.reportTable.shared = true
+	set.confirmationTable.shared = true
+	set.delistTable.shared = true
+	set.neighborTable.shared = true
+
+	return set.snapshot
+}
+
+//GetTableSnapshotRequest can be used to request a snapshot of all tables
+type GetTableSnapshotRequest struct {
+	ResponseChan chan<- *TableSnapshot
+}
+
+//Process processes the request and sends the snapshot on the ResponseChan
+func (req *GetTableSnapshotRequest) Process(tables *TableSet) error {
+	req.ResponseChan <- tables.takeSnapshot()
+
+	return nil
+}
+
+//Snapshot returns a snapshot of the current state of all tables
+//NOTE: must not be called from the goroutine of the TableSet since it waits for the request to be processed
+func (set *TableSet) Snapshot() *TableSnapshot {
+	responseChan := make(chan *TableSnapshot, 1)
+
+	set.Channel <- &GetTableSnapshotRequest{
+		ResponseChan: responseChan,
+	}
+
+	return <-responseChan
+}
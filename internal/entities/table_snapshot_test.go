@@ -0,0 +1,91 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/google/uuid"
+)
+
+//A snapshot keeps showing the state of the tables at the time it was taken, writes after it copy the tables
+func Test_TableSnapshot_Isolation(t *testing.T) {
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	node := newTestNode(t, "node-a")
+	edited, added := uuid.New(), uuid.New()
+
+	//Every step applies its events and takes a snapshot, which must hold the reports with the given addresses
+	tests := []struct {
+		name    string
+		events  []*GenericEvent
+		reports map[uuid.UUID]string
+	}{
+		{
+			name: "initial",
+			events: []*GenericEvent{
+				node.announce(t),
+				node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, edited, "198.51.100.1"),
+			},
+			reports: map[uuid.UUID]string{edited: "198.51.100.1"},
+		},
+		{
+			//Nothing was written since the last snapshot, so it is returned again
+			name:    "unchanged",
+			reports: map[uuid.UUID]string{edited: "198.51.100.1"},
+		},
+		{
+			name: "edit and add",
+			events: []*GenericEvent{
+				node.report(t, abusemesh.TableEventType_TABLE_UPDATE_EDIT, edited, "198.51.100.2"),
+				node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, added, "198.51.100.3"),
+			},
+			reports: map[uuid.UUID]string{edited: "198.51.100.2", added: "198.51.100.3"},
+		},
+		{
+			//The tables are shared again with the previous snapshot, a delete must not change it
+			name: "delete",
+			events: []*GenericEvent{
+				node.report(t, abusemesh.TableEventType_TABLE_UPDATE_DELETE, added, "198.51.100.3"),
+			},
+			reports: map[uuid.UUID]string{edited: "198.51.100.2"},
+		},
+	}
+
+	var snapshots []*TableSnapshot
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, event := range tt.events {
+				err := applyEvent(tableSet, event)
+				if err != nil {
+					t.Fatalf("Error while applying event: %s", err)
+				}
+			}
+
+			snapshot := tableSet.Snapshot()
+			if i > 0 && (snapshot == snapshots[i-1]) != (len(tt.events) == 0) {
+				t.Errorf("Expected a new snapshot only after writes, %d events were written", len(tt.events))
+			}
+			snapshots = append(snapshots, snapshot)
+
+			//The snapshots taken in the earlier steps must not have changed
+			for j, snapshot := range snapshots {
+				if len(snapshot.Nodes) != 1 {
+					t.Errorf("%s: expected 1 node, got %d", tests[j].name, len(snapshot.Nodes))
+				}
+				if len(snapshot.Reports) != len(tests[j].reports) {
+					t.Errorf("%s: expected %d reports, got %d", tests[j].name, len(tests[j].reports), len(snapshot.Reports))
+				}
+				for reportID, address := range tests[j].reports {
+					report, found := snapshot.Reports[reportID]
+					if !found {
+						t.Errorf("%s: expected report %s", tests[j].name, reportID)
+					} else if report.Prefix.IP.String() != address {
+						t.Errorf("%s: expected report %s for %s, got %s", tests[j].name, reportID, address, report.Prefix.String())
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package entities
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/google/uuid"
+)
+
+//A caller which doesn't receive the outcome of its request must not block the TableSet
+func Test_TableSet_UnreadErrorChan(t *testing.T) {
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	tableSet.Channel <- &UpdateTableRequest{
+		Event:     &GenericEvent{},
+		ErrorChan: make(chan error),
+	}
+
+	statsChan := make(chan TableStats, 1)
+	tableSet.Channel <- &GetTableStatsRequest{ResponseChan: statsChan}
+
+	select {
+	case stats := <-statsChan:
+		if stats.Failed != 1 {
+			t.Errorf("Expected 1 failed request, got %d", stats.Failed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("TableSet is blocked by the unread error channel")
+	}
+}
+
+//Expired reports are removed by the maintenance of the TableSet, without any request being made
+func Test_TableSet_MaintenanceExpiresReports(t *testing.T) {
+	tableSet := NewTableSet(100)
+	tableSet.NodeVerifier = acceptingNodeVerifier{}
+	tableSet.ReportTTL = ReportTTLPolicy{Default: time.Second}
+	tableSet.MaintenanceInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tableSet.Run(ctx)
+
+	node := newTestNode(t, "node-a")
+	reportID := uuid.New()
+
+	for _, event := range []*GenericEvent{
+		node.announce(t),
+		node.report(t, abusemesh.TableEventType_TABLE_UPDATE_NEW, reportID, "198.51.100.1"),
+	} {
+		err := applyEvent(tableSet, event)
+		if err != nil {
+			t.Fatalf("Error while applying event: %s", err)
+		}
+	}
+
+	subscription := tableSet.Subscribe(ChangeFilter{Tables: []TableName{TableReports}}, 10)
+	defer tableSet.Unsubscribe(subscription)
+
+	select {
+	case change := <-subscription.Changes:
+		if change.Key != reportID.String() || change.Type != ChangeDeleted {
+			t.Errorf("Expected the removal of report %s, got %+v", reportID, change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Report did not expire")
+	}
+
+	if tableSet.getReport(reportID) != nil {
+		t.Error("Expected the expired report to be removed from the table")
+	}
+}
@@ -0,0 +1,220 @@
+package entities
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/utils/conv"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var (
+	//ErrStaleEvent signals that a event is not newer than the version of its entity which has already been applied
+	ErrStaleEvent = errors.New("The event is older than the current version of the entity")
+
+	//ErrDeletedEntity signals that the entity of a event has been deleted, a deleted entity can't be changed
+	ErrDeletedEntity = errors.New("The entity of the event has been deleted")
+
+	//ErrFutureEvent signals that a event is signed with a timestamp which is more than MaxClockSkew in the future
+	ErrFutureEvent = errors.New("The event is signed in the future")
+)
+
+//MaxClockSkew is how far the timestamp of a event may be ahead of the local clock
+const MaxClockSkew = 5 * time.Minute
+
+//DefaultTombstoneTTL is used if the TombstoneTTL of the TableSet is not set
+const DefaultTombstoneTTL = 30 * 24 * time.Hour
+
+//A EntityVersion orders the events about the same entity
+//Events are ordered on their signed timestamp, events with the same timestamp are ordered on their event id.
+//Since both are part of the signed event every node orders the events in the same way, regardless of the order
+//in which they arrive, so all nodes derive the same state: the entity is what the newest event says it is (last writer wins),
+//unless the entity was deleted, a deletion is final regardless of its version.
+//Entities which are keyed by what they are about instead of a id of their own can come back, for those a deletion is
+//just another version and a newer event creates the entity again
+type EntityVersion struct {
+	Timestamp int64
+	EventID   uuid.UUID
+}
+
+//After returns true if the version is newer than the other version
+func (version EntityVersion) After(other EntityVersion) bool {
+	if version.Timestamp != other.Timestamp {
+		return version.Timestamp > other.Timestamp
+	}
+
+	return bytes.Compare(version.EventID[:], other.EventID[:]) > 0
+}
+
+//eventVersion returns the version a entity gets when the event is applied
+func eventVersion(event *GenericEvent) EntityVersion {
+	return EntityVersion{
+		Timestamp: event.GetTimestamp(),
+		EventID:   event.GetID(),
+	}
+}
+
+//A entityVersion is the version of the last event applied to a entity
+type entityVersion struct {
+	EntityVersion
+
+	//True if the last event deleted the entity, the version is kept as tombstone so the entity can't come back
+	deleted bool
+
+	//The node which owns the entity, only events made by this node are applied.
+	//uuid.Nil for nodes, their ownership is checked by the NodeTable against the key of the node
+	owner uuid.UUID
+
+	//The time at which the entity was deleted or expired, zero if the entity exists.
+	//The version of a removed entity is kept for the TombstoneTTL of the TableSet
+	removedAt time.Time
+}
+
+//A VersionTable holds the version of every entity, including recently deleted and expired entities
+//The entities are identified by the same key which is used to compact the event stream
+type VersionTable struct {
+	versions map[string]entityVersion
+}
+
+//check returns ErrDeletedEntity if the entity of the event is deleted
+//or ErrStaleEvent if the event is not newer than the current version of its entity.
+//A deletion is final: it is applied even if a newer event has been applied to the entity and
+//no event is applied after it, so all nodes end up with a deleted entity regardless of the order of the events.
+//A revivable entity only follows the versions, a newer event of any type is applied even if the entity is deleted
+func (table *VersionTable) check(key string, version EntityVersion, updateType abusemesh.TableEventType) error {
+	current, found := table.versions[key]
+	if !found {
+		return nil
+	}
+
+	if revivable(key) {
+		if version.After(current.EntityVersion) {
+			return nil
+		}
+
+		if current.deleted {
+			return errors.Wrapf(ErrDeletedEntity, "Entity '%s' was deleted by a newer event", key)
+		}
+
+		return errors.Wrapf(ErrStaleEvent, "Entity '%s' was changed by a newer event", key)
+	}
+
+	if current.deleted {
+		return errors.Wrapf(ErrDeletedEntity, "Entity '%s'", key)
+	}
+
+	if updateType == abusemesh.TableEventType_TABLE_UPDATE_DELETE || version.After(current.EntityVersion) {
+		return nil
+	}
+
+	return errors.Wrapf(ErrStaleEvent, "Entity '%s' was changed by a newer event", key)
+}
+
+//checkOwner returns the node which made the event, or a error if the entity of the event is owned by a other node
+//The event is validated against the tables before it is applied, but those may not have been up to date,
+//so the owner is checked again against the owner recorded with the version of the entity.
+//The owner is uuid.Nil if the ownership of the entity is checked by its table instead
+func (table *VersionTable) checkOwner(key string, event *GenericEvent) (uuid.UUID, error) {
+	var ownerID *abusemesh.UUID
+	var notOwner error
+
+	switch e := event.GetTableEntity().(type) {
+	case *abusemesh.TableEvent_Report:
+		ownerID, notOwner = e.Report.GetReporter(), ErrNotReportOwner
+	case *abusemesh.TableEvent_ReportConfirmation:
+		ownerID, notOwner = e.ReportConfirmation.GetConfirmer(), ErrNotConfirmer
+	case *abusemesh.TableEvent_DelistRequests:
+		//A deletion is the rejection of the request, it is made by the reporter and checked by the DelistTable
+		if event.UpdateType == abusemesh.TableEventType_TABLE_UPDATE_DELETE {
+			return uuid.Nil, nil
+		}
+		ownerID, notOwner = e.DelistRequests.GetRequester(), ErrNotRequester
+	case *abusemesh.TableEvent_DelistAcceptance:
+		ownerID, notOwner = e.DelistAcceptance.GetAcceptor(), ErrNotReporter
+	case *abusemesh.TableEvent_Neighbor:
+		ownerID, notOwner = e.Neighbor.GetNode(), ErrNotNeighborOwner
+	default:
+		return uuid.Nil, nil
+	}
+
+	owner, err := conv.AuuidToGuuid(ownerID)
+	if err != nil {
+		return uuid.Nil, errors.Wrap(err, "Owner ID invalid")
+	}
+
+	current, found := table.versions[key]
+	if found && current.owner != uuid.Nil && current.owner != owner {
+		return uuid.Nil, errors.Wrapf(notOwner, "Entity '%s' is owned by '%s'", key, current.owner)
+	}
+
+	return owner, nil
+}
+
+//record sets the version and owner of the entity after the event has been applied
+//The owner of a entity never changes, a owner of uuid.Nil keeps the current owner
+func (table *VersionTable) record(key string, version EntityVersion, updateType abusemesh.TableEventType, owner uuid.UUID, now time.Time) {
+	current := entityVersion{EntityVersion: version, owner: owner}
+	if previous, found := table.versions[key]; found && previous.owner != uuid.Nil {
+		current.owner = previous.owner
+	}
+
+	if updateType == abusemesh.TableEventType_TABLE_UPDATE_DELETE {
+		current.deleted = true
+		current.removedAt = now
+	}
+
+	table.versions[key] = current
+}
+
+//expire marks the entity as removed without deleting it, a newer event can list the entity again
+func (table *VersionTable) expire(key string, now time.Time) {
+	current, found := table.versions[key]
+	if !found || !current.removedAt.IsZero() {
+		return
+	}
+
+	current.removedAt = now
+	table.versions[key] = current
+}
+
+//prune forgets the versions of entities which were removed longer than the ttl ago
+//A event about such a entity which arrives afterwards is applied as if the entity never existed
+func (table *VersionTable) prune(now time.Time, ttl time.Duration) int {
+	pruned := 0
+
+	for key, current := range table.versions {
+		if !current.removedAt.IsZero() && now.Sub(current.removedAt) > ttl {
+			delete(table.versions, key)
+			pruned++
+		}
+	}
+
+	return pruned
+}
+
+//revivable returns true if the entity can be created again after it was deleted
+//Neighborships are keyed by the pair of nodes, a peering which goes down and comes back up is the same entity again
+func revivable(key string) bool {
+	return strings.HasPrefix(key, "neighbor/")
+}
+
+//isStale returns true if the error signals that the event was superseded by another event about the same entity
+//Superseded events are expected when events arrive out of order, they are not a failure
+func isStale(err error) bool {
+	cause := errors.Cause(err)
+	return cause == ErrStaleEvent || cause == ErrDeletedEntity
+}
+
+//checkClockSkew returns ErrFutureEvent if the event is signed with a timestamp too far in the future
+//Such a event would be newer than every event its author will sign in the coming time, freezing the entity
+func checkClockSkew(event *GenericEvent, now time.Time) error {
+	signedAt := time.Unix(event.GetTimestamp(), 0)
+	if signedAt.After(now.Add(MaxClockSkew)) {
+		return errors.Wrapf(ErrFutureEvent, "Event is signed at %s", signedAt.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
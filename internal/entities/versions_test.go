@@ -0,0 +1,298 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var (
+	updateNew    = abusemesh.TableEventType_TABLE_UPDATE_NEW
+	updateEdit   = abusemesh.TableEventType_TABLE_UPDATE_EDIT
+	updateDelete = abusemesh.TableEventType_TABLE_UPDATE_DELETE
+)
+
+//A versionedUpdate is a event about a entity as seen by the VersionTable
+type versionedUpdate struct {
+	version    EntityVersion
+	updateType abusemesh.TableEventType
+}
+
+//applyUpdates applies the updates to the entity with the key in the given order
+//and returns the last applied update, or nil if the entity is deleted
+func applyUpdates(key string, updates []versionedUpdate) *versionedUpdate {
+	table := VersionTable{versions: make(map[string]entityVersion)}
+
+	var current *versionedUpdate
+	for i := range updates {
+		update := updates[i]
+		if table.check(key, update.version, update.updateType) != nil {
+			continue
+		}
+
+		table.record(key, update.version, update.updateType, uuid.Nil, time.Now())
+		current = &update
+		if update.updateType == updateDelete {
+			current = nil
+		}
+	}
+
+	return current
+}
+
+func Test_VersionTable_LastWriterWins(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+	if !(EntityVersion{EventID: second}).After(EntityVersion{EventID: first}) {
+		first, second = second, first
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		updates  []versionedUpdate
+		expected *EntityVersion
+	}{
+		{
+			name: "newer timestamp wins",
+			updates: []versionedUpdate{
+				{EntityVersion{Timestamp: 1, EventID: second}, updateNew},
+				{EntityVersion{Timestamp: 2, EventID: first}, updateEdit},
+			},
+			expected: &EntityVersion{Timestamp: 2, EventID: first},
+		},
+		{
+			name: "same timestamp is ordered on event id",
+			updates: []versionedUpdate{
+				{EntityVersion{Timestamp: 1, EventID: first}, updateNew},
+				{EntityVersion{Timestamp: 1, EventID: second}, updateEdit},
+			},
+			expected: &EntityVersion{Timestamp: 1, EventID: second},
+		},
+		{
+			name: "delete is final",
+			updates: []versionedUpdate{
+				{EntityVersion{Timestamp: 1, EventID: first}, updateNew},
+				{EntityVersion{Timestamp: 2, EventID: first}, updateDelete},
+				{EntityVersion{Timestamp: 3, EventID: first}, updateEdit},
+				{EntityVersion{Timestamp: 4, EventID: second}, updateNew},
+			},
+			expected: nil,
+		},
+		{
+			name: "delete wins over newer edit",
+			updates: []versionedUpdate{
+				{EntityVersion{Timestamp: 1, EventID: first}, updateNew},
+				{EntityVersion{Timestamp: 3, EventID: first}, updateEdit},
+				{EntityVersion{Timestamp: 2, EventID: first}, updateDelete},
+			},
+			expected: nil,
+		},
+		{
+			name: "withdrawn neighborship is announced again",
+			key:  "neighbor/1/2",
+			updates: []versionedUpdate{
+				{EntityVersion{Timestamp: 1, EventID: first}, updateNew},
+				{EntityVersion{Timestamp: 2, EventID: first}, updateDelete},
+				{EntityVersion{Timestamp: 3, EventID: first}, updateNew},
+			},
+			expected: &EntityVersion{Timestamp: 3, EventID: first},
+		},
+		{
+			name: "neighborship flaps",
+			key:  "neighbor/1/2",
+			updates: []versionedUpdate{
+				{EntityVersion{Timestamp: 1, EventID: first}, updateNew},
+				{EntityVersion{Timestamp: 2, EventID: first}, updateDelete},
+				{EntityVersion{Timestamp: 3, EventID: first}, updateNew},
+				{EntityVersion{Timestamp: 4, EventID: first}, updateDelete},
+			},
+			expected: nil,
+		},
+		{
+			name: "older withdrawal of neighborship is stale",
+			key:  "neighbor/1/2",
+			updates: []versionedUpdate{
+				{EntityVersion{Timestamp: 1, EventID: first}, updateNew},
+				{EntityVersion{Timestamp: 3, EventID: first}, updateEdit},
+				{EntityVersion{Timestamp: 2, EventID: first}, updateDelete},
+			},
+			expected: &EntityVersion{Timestamp: 3, EventID: first},
+		},
+	}
+
+	for _, test := range tests {
+		key := test.key
+		if key == "" {
+			key = "report/1"
+		}
+
+		//Every order of the events must lead to the same state
+		for _, order := range permutations(len(test.updates)) {
+			updates := make([]versionedUpdate, len(order))
+			for i, index := range order {
+				updates[i] = test.updates[index]
+			}
+
+			current := applyUpdates(key, updates)
+
+			switch {
+			case test.expected == nil && current != nil:
+				t.Errorf("%s: order %v: expected the entity to be deleted, got version %+v", test.name, order, current.version)
+			case test.expected != nil && current == nil:
+				t.Errorf("%s: order %v: expected version %+v, got a deleted entity", test.name, order, *test.expected)
+			case test.expected != nil && current.version != *test.expected:
+				t.Errorf("%s: order %v: expected version %+v, got %+v", test.name, order, *test.expected, current.version)
+			}
+		}
+	}
+}
+
+//permutations returns all orders of the numbers 0 up to n
+func permutations(n int) [][]int {
+	if n == 0 {
+		return [][]int{{}}
+	}
+
+	var result [][]int
+	for _, order := range permutations(n - 1) {
+		for i := 0; i <= len(order); i++ {
+			permutation := append(append(append([]int{}, order[:i]...), n-1), order[i:]...)
+			result = append(result, permutation)
+		}
+	}
+
+	return result
+}
+
+func Test_VersionTable_Stale(t *testing.T) {
+	table := VersionTable{versions: make(map[string]entityVersion)}
+	table.record("report/1", EntityVersion{Timestamp: 2}, updateNew, uuid.Nil, time.Now())
+
+	err := table.check("report/1", EntityVersion{Timestamp: 1}, updateEdit)
+	if errors.Cause(err) != ErrStaleEvent {
+		t.Errorf("Expected ErrStaleEvent, got %v", err)
+	}
+
+	table.record("report/1", EntityVersion{Timestamp: 3}, updateDelete, uuid.Nil, time.Now())
+
+	err = table.check("report/1", EntityVersion{Timestamp: 4}, updateEdit)
+	if errors.Cause(err) != ErrDeletedEntity {
+		t.Errorf("Expected ErrDeletedEntity, got %v", err)
+	}
+
+	if !isStale(err) {
+		t.Error("Expected a event about a deleted entity to be stale")
+	}
+}
+
+func Test_VersionTable_Prune(t *testing.T) {
+	now := time.Now()
+
+	table := VersionTable{versions: make(map[string]entityVersion)}
+	table.record("report/listed", EntityVersion{Timestamp: 1}, updateNew, uuid.Nil, now.Add(-time.Hour))
+	table.record("report/deleted", EntityVersion{Timestamp: 1}, updateDelete, uuid.Nil, now.Add(-time.Hour))
+	table.record("report/recently-deleted", EntityVersion{Timestamp: 1}, updateDelete, uuid.Nil, now)
+	table.record("report/expired", EntityVersion{Timestamp: 1}, updateNew, uuid.Nil, now.Add(-time.Hour))
+	table.expire("report/expired", now.Add(-time.Hour))
+
+	//A expired entity can be listed again by a newer event, but not by a older one
+	if err := table.check("report/expired", EntityVersion{Timestamp: 0}, updateEdit); errors.Cause(err) != ErrStaleEvent {
+		t.Errorf("Expected ErrStaleEvent for older event about expired entity, got %v", err)
+	}
+	if err := table.check("report/expired", EntityVersion{Timestamp: 2}, updateEdit); err != nil {
+		t.Errorf("Expected newer event about expired entity to be accepted, got %v", err)
+	}
+
+	pruned := table.prune(now, time.Minute)
+	if pruned != 2 {
+		t.Errorf("Expected 2 pruned versions, got %d", pruned)
+	}
+
+	for _, key := range []string{"report/listed", "report/recently-deleted"} {
+		if _, found := table.versions[key]; !found {
+			t.Errorf("Expected version of '%s' to be kept", key)
+		}
+	}
+}
+
+func Test_GenericEvent_ClockSkew(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		signedAt time.Time
+		valid    bool
+	}{
+		{now.Add(-24 * time.Hour), true},
+		{now, true},
+		{now.Add(MaxClockSkew - time.Second), true},
+		{now.Add(MaxClockSkew + time.Second), false},
+		{time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, test := range tests {
+		event := &GenericEvent{TableEvent: abusemesh.TableEvent{Timestamp: test.signedAt.Unix()}}
+
+		err := checkClockSkew(event, now)
+		if test.valid && err != nil {
+			t.Errorf("Expected event signed at %s to be accepted, got %s", test.signedAt, err)
+		}
+		if !test.valid && errors.Cause(err) != ErrFutureEvent {
+			t.Errorf("Expected ErrFutureEvent for event signed at %s, got %v", test.signedAt, err)
+		}
+	}
+}
+
+//A event which was validated against tables which were not up to date must not change a entity of a other node
+func Test_UpdateTableRequest_Owner(t *testing.T) {
+	tableSet, stopTableSet := runTestTableSet()
+	defer stopTableSet()
+
+	owner, other := newTestNode(t, "node-a"), newTestNode(t, "node-b")
+	reportID := uuid.New()
+
+	for _, event := range []*GenericEvent{
+		owner.announce(t),
+		other.announce(t),
+		owner.report(t, updateNew, reportID, "198.51.100.1"),
+	} {
+		err := applyEvent(tableSet, event)
+		if err != nil {
+			t.Fatalf("Error while applying event: %s", err)
+		}
+	}
+
+	err := processEvent(tableSet, other.report(t, updateEdit, reportID, "198.51.100.2"))
+	if errors.Cause(err) != ErrNotReportOwner {
+		t.Errorf("Expected ErrNotReportOwner for edit by other node, got %v", err)
+	}
+
+	err = processEvent(tableSet, other.report(t, updateDelete, reportID, "198.51.100.1"))
+	if errors.Cause(err) != ErrNotReportOwner {
+		t.Errorf("Expected ErrNotReportOwner for deletion by other node, got %v", err)
+	}
+
+	//The other node announces its own key under the id of the owner
+	impostor := testNode{id: owner.id, key: other.key, author: other.author}
+	nodeEvent, err := impostor.author.NodeEvent(updateEdit, impostor.message(t))
+	if err != nil {
+		t.Fatalf("Error while signing node event: %s", err)
+	}
+
+	err = processEvent(tableSet, nodeEvent)
+	if errors.Cause(err) != ErrNotNodeOwner {
+		t.Errorf("Expected ErrNotNodeOwner for node edit signed by other node, got %v", err)
+	}
+
+	report := tableSet.getReport(reportID)
+	if report == nil || report.Reporter != owner.id || report.Prefix.IP.String() != "198.51.100.1" {
+		t.Errorf("Expected report to be unchanged, got %+v", report)
+	}
+
+	err = processEvent(tableSet, owner.report(t, updateEdit, reportID, "198.51.100.2"))
+	if err != nil {
+		t.Errorf("Expected edit by owner to be applied, got %s", err)
+	}
+}
@@ -0,0 +1,5 @@
+/*Package local implements storage.StorageBackend as a embedded key value store in a local data directory.
+Data is written to append-only segment files, a index of all keys and the location of their values is kept in memory.
+Overwritten and deleted values are reclaimed by compacting the segments.
+*/
+package local
@@ -0,0 +1,107 @@
+package local
+
+import (
+	"sort"
+	"strings"
+)
+
+//maxChunkKeys is the amount of keys after which a chunk of the key index is split
+const maxChunkKeys = 512
+
+//A keyIndex holds the keys of the storage in ascending order, so the keys with a prefix are found without scanning all keys
+//The keys are split in sorted chunks of at most maxChunkKeys keys, inserting or removing a key only moves the keys of one chunk
+type keyIndex struct {
+	chunks [][]string
+}
+
+//chunk returns the first chunk which may hold the key, len(chunks) if the key is larger than all keys
+func (index *keyIndex) chunk(key string) int {
+	return sort.Search(len(index.chunks), func(i int) bool {
+		chunk := index.chunks[i]
+		return chunk[len(chunk)-1] >= key
+	})
+}
+
+//insert adds the key, inserting a existing key does nothing
+func (index *keyIndex) insert(key string) {
+	if len(index.chunks) == 0 {
+		index.chunks = [][]string{{key}}
+		return
+	}
+
+	i := index.chunk(key)
+	if i == len(index.chunks) {
+		i--
+	}
+
+	chunk := index.chunks[i]
+	j := sort.SearchStrings(chunk, key)
+	if j < len(chunk) && chunk[j] == key {
+		return
+	}
+
+	chunk = append(chunk, "")
+	copy(chunk[j+1:], chunk[j:])
+	chunk[j] = key
+
+	if len(chunk) <= maxChunkKeys {
+		index.chunks[i] = chunk
+		return
+	}
+
+	//The halves get their own arrays, so growing one doesn't overwrite the other
+	half := len(chunk) / 2
+	left := append([]string(nil), chunk[:half]...)
+	right := append([]string(nil), chunk[half:]...)
+
+	index.chunks = append(index.chunks, nil)
+	copy(index.chunks[i+2:], index.chunks[i+1:])
+	index.chunks[i], index.chunks[i+1] = left, right
+}
+
+//remove removes the key, removing a key which doesn't exist does nothing
+func (index *keyIndex) remove(key string) {
+	i := index.chunk(key)
+	if i == len(index.chunks) {
+		return
+	}
+
+	chunk := index.chunks[i]
+	j := sort.SearchStrings(chunk, key)
+	if j == len(chunk) || chunk[j] != key {
+		return
+	}
+
+	chunk = append(chunk[:j], chunk[j+1:]...)
+	if len(chunk) > 0 {
+		index.chunks[i] = chunk
+		return
+	}
+
+	index.chunks = append(index.chunks[:i], index.chunks[i+1:]...)
+}
+
+//ascend calls the callback for every key starting with the prefix in ascending order
+func (index *keyIndex) ascend(prefix string, callback func(key string)) {
+	for i := index.chunk(prefix); i < len(index.chunks); i++ {
+		chunk := index.chunks[i]
+
+		for _, key := range chunk[sort.SearchStrings(chunk, prefix):] {
+			if !strings.HasPrefix(key, prefix) {
+				return
+			}
+
+			callback(key)
+		}
+	}
+}
+
+//clone returns a copy of the index which isn't changed by writes to the index
+func (index *keyIndex) clone() keyIndex {
+	chunks := make([][]string, len(index.chunks))
+	for i, chunk := range index.chunks {
+		chunks[i] = append([]string(nil), chunk...)
+	}
+
+	return keyIndex{chunks: chunks}
+}
@@ -0,0 +1,267 @@
+package local
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	opPut    byte = 1
+	opDelete byte = 2
+
+	//frameHeaderSize is the size of the length and checksum in front of every frame
+	frameHeaderSize = 8
+
+	//maxFrameSize is the upper limit of a single frame, larger lengths indicate a corrupt segment
+	maxFrameSize = 256 * 1024 * 1024
+
+	segmentExtension = ".seg"
+)
+
+//checksumTable is the CRC table used to checksum the frames in a segment
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+//A segment is a append-only data file
+//Every committed batch is written as a single frame, which consists of a 4 byte big endian payload length,
+//a 4 byte CRC-32C checksum of the payload and the payload. The payload is a sequence of operations,
+//every operation is a op code followed by the uvarint length and bytes of the key and, for puts, the value.
+//Since a frame is only valid if the checksum matches, a batch is either applied completely or not at all.
+type segment struct {
+	id   uint64
+	file *os.File
+	size int64
+
+	//The amount of snapshots and iterations which are reading from this segment,
+	//it is changed atomically since references are taken while holding the read lock
+	refs int64
+
+	//A obsolete segment has been compacted and will be removed once it is no longer referenced
+	obsolete bool
+}
+
+//A location points to a value in a segment
+type location struct {
+	segment uint64
+	offset  int64
+	length  uint32
+}
+
+//A operation is a single write in a frame
+type operation struct {
+	op    byte
+	key   []byte
+	value []byte
+}
+
+func segmentPath(dir string, id uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%016x%s", id, segmentExtension))
+}
+
+//openSegment opens or creates the segment with the given id
+func openSegment(dir string, id uint64) (*segment, error) {
+	file, err := os.OpenFile(segmentPath(dir, id), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while opening segment")
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "Error while opening segment")
+	}
+
+	return &segment{
+		id:   id,
+		file: file,
+		size: info.Size(),
+	}, nil
+}
+
+//encodeFrame encodes the operations into a frame and returns the offset of every value within the frame
+func encodeFrame(operations []operation) ([]byte, []int64) {
+	payload := &bytes.Buffer{}
+	valueOffsets := make([]int64, len(operations))
+	varintBuf := make([]byte, binary.MaxVarintLen64)
+
+	for i, operation := range operations {
+		payload.WriteByte(operation.op)
+
+		n := binary.PutUvarint(varintBuf, uint64(len(operation.key)))
+		payload.Write(varintBuf[:n])
+		payload.Write(operation.key)
+
+		if operation.op == opPut {
+			n = binary.PutUvarint(varintBuf, uint64(len(operation.value)))
+			payload.Write(varintBuf[:n])
+
+			valueOffsets[i] = frameHeaderSize + int64(payload.Len())
+			payload.Write(operation.value)
+		}
+	}
+
+	frame := make([]byte, frameHeaderSize+payload.Len())
+	binary.BigEndian.PutUint32(frame[0:4], uint32(payload.Len()))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.Checksum(payload.Bytes(), checksumTable))
+	copy(frame[frameHeaderSize:], payload.Bytes())
+
+	return frame, valueOffsets
+}
+
+//decodePayload decodes the operations in the payload of a frame
+//The offset of every value within the frame is returned alongside the operations
+func decodePayload(payload []byte) ([]operation, []int64, error) {
+	var operations []operation
+	var valueOffsets []int64
+
+	reader := bytes.NewReader(payload)
+
+	readBytes := func() ([]byte, int64, error) {
+		length, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if length > uint64(reader.Len()) {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+
+		offset := frameHeaderSize + int64(len(payload)-reader.Len())
+		data := make([]byte, length)
+		_, err = io.ReadFull(reader, data)
+
+		return data, offset, err
+	}
+
+	for reader.Len() > 0 {
+		op, _ := reader.ReadByte()
+		if op != opPut && op != opDelete {
+			return nil, nil, errors.Errorf("Unknown op code '%d'", op)
+		}
+
+		key, _, err := readBytes()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Error while decoding key")
+		}
+
+		operation := operation{op: op, key: key}
+		var valueOffset int64
+
+		if op == opPut {
+			operation.value, valueOffset, err = readBytes()
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "Error while decoding value")
+			}
+		}
+
+		operations = append(operations, operation)
+		valueOffsets = append(valueOffsets, valueOffset)
+	}
+
+	return operations, valueOffsets, nil
+}
+
+//replay reads all frames of the segment and calls the callback for every operation with the location of its value
+//If truncate is true a damaged tail is removed, otherwise damage is returned as error
+func (segment *segment) replay(truncate bool, callback func(operation, location)) error {
+	var offset int64
+	header := make([]byte, frameHeaderSize)
+
+	var err error
+	for offset < segment.size {
+		_, err = segment.file.ReadAt(header, offset)
+		if err != nil {
+			break
+		}
+
+		length := binary.BigEndian.Uint32(header[0:4])
+		if length > maxFrameSize {
+			err = errors.Errorf("Frame length '%d' exceeds the maximum", length)
+			break
+		}
+
+		payload := make([]byte, length)
+		_, err = segment.file.ReadAt(payload, offset+frameHeaderSize)
+		if err != nil {
+			break
+		}
+
+		if crc32.Checksum(payload, checksumTable) != binary.BigEndian.Uint32(header[4:8]) {
+			err = errors.New("Frame checksum mismatch")
+			break
+		}
+
+		var operations []operation
+		var valueOffsets []int64
+		operations, valueOffsets, err = decodePayload(payload)
+		if err != nil {
+			break
+		}
+
+		for i, operation := range operations {
+			callback(operation, location{
+				segment: segment.id,
+				offset:  offset + valueOffsets[i],
+				length:  uint32(len(operation.value)),
+			})
+		}
+
+		offset += frameHeaderSize + int64(length)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	if !truncate {
+		return errors.Wrapf(err, "Segment '%d' is damaged at offset '%d'", segment.id, offset)
+	}
+
+	err = segment.file.Truncate(offset)
+	if err != nil {
+		return errors.Wrap(err, "Error while truncating segment")
+	}
+
+	segment.size = offset
+
+	return nil
+}
+
+//write appends a frame to the segment and returns the offset at which it was written
+func (segment *segment) write(frame []byte, sync bool) (int64, error) {
+	offset := segment.size
+
+	_, err := segment.file.WriteAt(frame, offset)
+	if err != nil {
+		return 0, errors.Wrap(err, "Error while writing to segment")
+	}
+
+	if sync {
+		err = segment.file.Sync()
+		if err != nil {
+			return 0, errors.Wrap(err, "Error while syncing segment")
+		}
+	}
+
+	segment.size += int64(len(frame))
+
+	return offset, nil
+}
+
+//read reads the value at the location
+func (segment *segment) read(loc location) ([]byte, error) {
+	value := make([]byte, loc.length)
+
+	_, err := segment.file.ReadAt(value, loc.offset)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while reading from segment")
+	}
+
+	return value, nil
+}
@@ -0,0 +1,613 @@
+package local
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go/internal/storage"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+//DefaultMaxSegmentSize is the size after which a new segment file is started
+const DefaultMaxSegmentSize = 64 * 1024 * 1024
+
+//StorageBackend implements storage.StorageBackend using segment files in a local data directory
+type StorageBackend struct {
+	//The data directory containing the segments
+	dir string
+
+	//If true every commit is synced to disk before it returns
+	syncWrites bool
+
+	//The size after which the active segment is rotated
+	maxSegmentSize int64
+
+	//The location of the value of every key
+	index map[string]location
+
+	//The keys of index in ascending order
+	keys keyIndex
+
+	//All open segments indexed on their id
+	segments map[uint64]*segment
+
+	//The segment new writes are appended to
+	active *segment
+
+	//The amount of bytes in the segments which belong to overwritten or deleted values
+	garbage int64
+
+	closed bool
+
+	//A lock for all of the above, reads of segment files by snapshots happen without holding the lock
+	lock sync.RWMutex
+}
+
+//NewStorageBackend opens or creates a storage backend in the given data directory
+func NewStorageBackend(dir string, syncWrites bool) (*StorageBackend, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while creating data directory")
+	}
+
+	backend := &StorageBackend{
+		dir:            dir,
+		syncWrites:     syncWrites,
+		maxSegmentSize: DefaultMaxSegmentSize,
+		index:          make(map[string]location),
+		segments:       make(map[uint64]*segment),
+	}
+
+	ids, err := backend.segmentIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, id := range ids {
+		seg, err := openSegment(dir, id)
+		if err != nil {
+			backend.closeSegments()
+			return nil, err
+		}
+		backend.segments[id] = seg
+
+		//Only the last segment can have a damaged tail as the result of a interrupted write
+		err = seg.replay(i == len(ids)-1, backend.apply)
+		if err != nil {
+			backend.closeSegments()
+			return nil, err
+		}
+	}
+
+	if len(ids) > 0 {
+		backend.active = backend.segments[ids[len(ids)-1]]
+	} else {
+		err = backend.rotate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"dir":      dir,
+		"keys":     len(backend.index),
+		"segments": len(backend.segments),
+	}).Info("Local storage opened")
+
+	return backend, nil
+}
+
+//segmentIDs returns the ids of all segments in the data directory in ascending order
+func (backend *StorageBackend) segmentIDs() ([]uint64, error) {
+	files, err := ioutil.ReadDir(backend.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while reading data directory")
+	}
+
+	var ids []uint64
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), segmentExtension) {
+			continue
+		}
+
+		id, err := strconv.ParseUint(strings.TrimSuffix(file.Name(), segmentExtension), 16, 64)
+		if err != nil {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids, nil
+}
+
+//apply updates the index with a operation, the lock must be held by the caller
+func (backend *StorageBackend) apply(operation operation, loc location) {
+	key := string(operation.key)
+
+	if old, found := backend.index[key]; found {
+		backend.garbage += int64(old.length)
+	}
+
+	switch operation.op {
+	case opPut:
+		if _, found := backend.index[key]; !found {
+			backend.keys.insert(key)
+		}
+		backend.index[key] = loc
+	case opDelete:
+		if _, found := backend.index[key]; found {
+			backend.keys.remove(key)
+		}
+		delete(backend.index, key)
+	}
+}
+
+//rotate starts a new active segment, the lock must be held by the caller
+func (backend *StorageBackend) rotate() error {
+	var id uint64
+	for existingID := range backend.segments {
+		if existingID >= id {
+			id = existingID + 1
+		}
+	}
+
+	seg, err := openSegment(backend.dir, id)
+	if err != nil {
+		return err
+	}
+
+	//The new segment is only durable once its directory entry is, otherwise a crash could lose the whole file
+	err = syncDir(backend.dir)
+	if err != nil {
+		seg.file.Close()
+		return err
+	}
+
+	backend.segments[id] = seg
+	backend.active = seg
+
+	return nil
+}
+
+//syncDir syncs the directory so the files created in it survive a crash
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrap(err, "Error while opening data directory")
+	}
+	defer file.Close()
+
+	return errors.Wrap(file.Sync(), "Error while syncing data directory")
+}
+
+//commit writes the operations as a single frame and updates the index
+func (backend *StorageBackend) commit(operations []operation) error {
+	if len(operations) == 0 {
+		return nil
+	}
+
+	frame, valueOffsets := encodeFrame(operations)
+
+	backend.lock.Lock()
+	defer backend.lock.Unlock()
+
+	if backend.closed {
+		return storage.ErrClosed
+	}
+
+	offset, err := backend.active.write(frame, backend.syncWrites)
+	if err != nil {
+		return err
+	}
+
+	for i, operation := range operations {
+		backend.apply(operation, location{
+			segment: backend.active.id,
+			offset:  offset + valueOffsets[i],
+			length:  uint32(len(operation.value)),
+		})
+	}
+
+	if backend.active.size >= backend.maxSegmentSize {
+		return backend.rotate()
+	}
+
+	return nil
+}
+
+//acquire references all segments so they are not removed while being read, the read lock must be held by the caller
+func (backend *StorageBackend) acquire() map[uint64]*segment {
+	segments := make(map[uint64]*segment, len(backend.segments))
+	for id, seg := range backend.segments {
+		atomic.AddInt64(&seg.refs, 1)
+		segments[id] = seg
+	}
+
+	return segments
+}
+
+//release drops the references taken by acquire and removes obsolete segments which are no longer used
+func (backend *StorageBackend) release(segments map[uint64]*segment) {
+	backend.lock.Lock()
+	defer backend.lock.Unlock()
+
+	for _, seg := range segments {
+		atomic.AddInt64(&seg.refs, -1)
+	}
+
+	backend.removeObsolete()
+}
+
+//removeObsolete removes compacted segments which are no longer referenced, the lock must be held by the caller
+//Segments are removed oldest first and removal stops at the first segment which is still in use,
+//this way a crash never leaves a older put in place while the delete which followed it is gone
+func (backend *StorageBackend) removeObsolete() {
+	ids := make([]uint64, 0, len(backend.segments))
+	for id := range backend.segments {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		seg := backend.segments[id]
+		if !seg.obsolete || atomic.LoadInt64(&seg.refs) > 0 {
+			return
+		}
+
+		seg.file.Close()
+
+		err := os.Remove(segmentPath(backend.dir, id))
+		if err != nil {
+			logrus.WithError(err).WithField("segment", id).Error("Error while removing compacted segment")
+			return
+		}
+
+		delete(backend.segments, id)
+	}
+}
+
+func (backend *StorageBackend) closeSegments() {
+	for _, seg := range backend.segments {
+		seg.file.Close()
+	}
+}
+
+//Get returns the value stored under the key or storage.ErrNotFound
+//The value is read while holding the read lock, this keeps the segment from being removed without referencing it
+func (backend *StorageBackend) Get(key []byte) ([]byte, error) {
+	backend.lock.RLock()
+	defer backend.lock.RUnlock()
+
+	if backend.closed {
+		return nil, storage.ErrClosed
+	}
+
+	loc, found := backend.index[string(key)]
+	if !found {
+		return nil, storage.ErrNotFound
+	}
+
+	return backend.segments[loc.segment].read(loc)
+}
+
+//Iterate calls the callback for every key starting with the prefix in ascending key order
+//Writes made during the iteration are not visible to it. Only the locations of the matching keys are collected
+//while holding the read lock, the values are read one by one from the referenced segments
+func (backend *StorageBackend) Iterate(prefix []byte, callback storage.IterateFunc) error {
+	backend.lock.RLock()
+
+	if backend.closed {
+		backend.lock.RUnlock()
+		return storage.ErrClosed
+	}
+
+	var keys []string
+	var locations []location
+	backend.keys.ascend(string(prefix), func(key string) {
+		keys = append(keys, key)
+		locations = append(locations, backend.index[key])
+	})
+
+	segments := backend.acquire()
+	backend.lock.RUnlock()
+
+	defer backend.release(segments)
+
+	return iterate(keys, locations, segments, callback)
+}
+
+//iterate reads the values at the locations and calls the callback for every key
+func iterate(keys []string, locations []location, segments map[uint64]*segment, callback storage.IterateFunc) error {
+	for i, key := range keys {
+		value, err := segments[locations[i].segment].read(locations[i])
+		if err != nil {
+			return err
+		}
+
+		err = callback([]byte(key), value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Put stores the value under the key, replacing any existing value
+func (backend *StorageBackend) Put(key, value []byte) error {
+	return backend.commit([]operation{{op: opPut, key: key, value: value}})
+}
+
+//Delete removes the key
+func (backend *StorageBackend) Delete(key []byte) error {
+	return backend.commit([]operation{{op: opDelete, key: key}})
+}
+
+//NewBatch creates a batch of writes which can be committed atomically
+func (backend *StorageBackend) NewBatch() storage.Batch {
+	return &batch{backend: backend}
+}
+
+//Snapshot takes a consistent read-only view of the storage
+//The snapshot must be released, compacted segments are kept on disk until all snapshots using them are released
+func (backend *StorageBackend) Snapshot() (storage.Snapshot, error) {
+	backend.lock.RLock()
+	defer backend.lock.RUnlock()
+
+	if backend.closed {
+		return nil, storage.ErrClosed
+	}
+
+	index := make(map[string]location, len(backend.index))
+	for key, loc := range backend.index {
+		index[key] = loc
+	}
+
+	return &snapshot{
+		backend:  backend,
+		index:    index,
+		keys:     backend.keys.clone(),
+		segments: backend.acquire(),
+	}, nil
+}
+
+//Compact rewrites all live values to a new segment and removes the old segments
+//Segments which are still in use by a snapshot are removed when the snapshot is released
+func (backend *StorageBackend) Compact() error {
+	backend.lock.Lock()
+	defer backend.lock.Unlock()
+
+	if backend.closed {
+		return storage.ErrClosed
+	}
+
+	old := make([]*segment, 0, len(backend.segments))
+	for _, seg := range backend.segments {
+		old = append(old, seg)
+	}
+
+	err := backend.rotate()
+	if err != nil {
+		return err
+	}
+	compacted := backend.active
+
+	var keys []string
+	backend.keys.ascend("", func(key string) {
+		keys = append(keys, key)
+	})
+
+	//Copy the values in frames of limited size so a single frame never grows too large
+	const compactFrameSize = 4 * 1024 * 1024
+	var operations []operation
+	var pending int
+
+	flush := func() error {
+		if len(operations) == 0 {
+			return nil
+		}
+
+		frame, valueOffsets := encodeFrame(operations)
+		offset, err := compacted.write(frame, false)
+		if err != nil {
+			return err
+		}
+
+		for i, operation := range operations {
+			backend.index[string(operation.key)] = location{
+				segment: compacted.id,
+				offset:  offset + valueOffsets[i],
+				length:  uint32(len(operation.value)),
+			}
+		}
+
+		operations = operations[:0]
+		pending = 0
+
+		return nil
+	}
+
+	for _, key := range keys {
+		loc := backend.index[key]
+		value, err := backend.segments[loc.segment].read(loc)
+		if err != nil {
+			return err
+		}
+
+		operations = append(operations, operation{op: opPut, key: []byte(key), value: value})
+		pending += len(key) + len(value)
+
+		if pending >= compactFrameSize {
+			err = flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = flush()
+	if err != nil {
+		return err
+	}
+
+	//The compacted segment must be durable before the old segments are removed
+	err = compacted.file.Sync()
+	if err != nil {
+		return errors.Wrap(err, "Error while syncing compacted segment")
+	}
+
+	for _, seg := range old {
+		seg.obsolete = true
+	}
+	backend.garbage = 0
+
+	backend.removeObsolete()
+
+	//Continue writing in a fresh segment so the compacted segment stays untouched
+	return backend.rotate()
+}
+
+//Garbage returns the amount of bytes occupied by overwritten or deleted values which Compact would reclaim
+func (backend *StorageBackend) Garbage() int64 {
+	backend.lock.RLock()
+	defer backend.lock.RUnlock()
+
+	return backend.garbage
+}
+
+//RunCompaction compacts the storage every interval if at least minGarbage bytes can be reclaimed
+//It returns when the context is cancelled or the storage is closed
+func (backend *StorageBackend) RunCompaction(ctx context.Context, interval time.Duration, minGarbage int64) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			garbage := backend.Garbage()
+			if garbage < minGarbage {
+				continue
+			}
+
+			err := backend.Compact()
+			if err == storage.ErrClosed {
+				return nil
+			}
+			if err != nil {
+				logrus.WithError(err).WithField("dir", backend.dir).Error("Error while compacting local storage")
+				continue
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"dir":       backend.dir,
+				"reclaimed": garbage,
+			}).Info("Local storage compacted")
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+//Close flushes all data and closes the storage
+func (backend *StorageBackend) Close() error {
+	backend.lock.Lock()
+	defer backend.lock.Unlock()
+
+	if backend.closed {
+		return nil
+	}
+	backend.closed = true
+
+	err := backend.active.file.Sync()
+
+	backend.closeSegments()
+
+	return err
+}
+
+//A batch collects operations until it is committed
+type batch struct {
+	backend    *StorageBackend
+	operations []operation
+}
+
+func (batch *batch) Put(key, value []byte) {
+	batch.operations = append(batch.operations, operation{
+		op:    opPut,
+		key:   append([]byte(nil), key...),
+		value: append([]byte(nil), value...),
+	})
+}
+
+func (batch *batch) Delete(key []byte) {
+	batch.operations = append(batch.operations, operation{
+		op:  opDelete,
+		key: append([]byte(nil), key...),
+	})
+}
+
+func (batch *batch) Commit() error {
+	err := batch.backend.commit(batch.operations)
+	batch.operations = nil
+
+	return err
+}
+
+//A snapshot holds a copy of the index and references to the segments it points to
+type snapshot struct {
+	backend  *StorageBackend
+	index    map[string]location
+	keys     keyIndex
+	segments map[uint64]*segment
+
+	released bool
+}
+
+func (snapshot *snapshot) Get(key []byte) ([]byte, error) {
+	if snapshot.released {
+		return nil, storage.ErrClosed
+	}
+
+	loc, found := snapshot.index[string(key)]
+	if !found {
+		return nil, storage.ErrNotFound
+	}
+
+	return snapshot.segments[loc.segment].read(loc)
+}
+
+func (snapshot *snapshot) Iterate(prefix []byte, callback storage.IterateFunc) error {
+	if snapshot.released {
+		return storage.ErrClosed
+	}
+
+	var keys []string
+	var locations []location
+	snapshot.keys.ascend(string(prefix), func(key string) {
+		keys = append(keys, key)
+		locations = append(locations, snapshot.index[key])
+	})
+
+	return iterate(keys, locations, snapshot.segments, callback)
+}
+
+func (snapshot *snapshot) Release() {
+	if snapshot.released {
+		return
+	}
+	snapshot.released = true
+
+	snapshot.backend.release(snapshot.segments)
+}
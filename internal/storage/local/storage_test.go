@@ -0,0 +1,254 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abuse-mesh/abuse-mesh-go/internal/storage"
+)
+
+func openTestBackend(t *testing.T, dir string) *StorageBackend {
+	backend, err := NewStorageBackend(dir, false)
+	if err != nil {
+		t.Fatalf("Error while opening backend: %s", err)
+	}
+
+	return backend
+}
+
+func collect(t *testing.T, reader storage.Reader, prefix string) string {
+	var pairs []string
+	err := reader.Iterate([]byte(prefix), func(key, value []byte) error {
+		pairs = append(pairs, string(key)+"="+string(value))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error while iterating: %s", err)
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func Test_StorageBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "abusemesh-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	backend := openTestBackend(t, dir)
+
+	backend.Put([]byte("node/b"), []byte("2"))
+	backend.Put([]byte("node/a"), []byte("1"))
+	backend.Put([]byte("report/a"), []byte("3"))
+	backend.Put([]byte("node/a"), []byte("4"))
+	backend.Delete([]byte("report/a"))
+
+	if _, err := backend.Get([]byte("report/a")); err != storage.ErrNotFound {
+		t.Errorf("Expected ErrNotFound for deleted key, got '%v'", err)
+	}
+
+	if got := collect(t, backend, "node/"); got != "node/a=4,node/b=2" {
+		t.Errorf("Unexpected iteration result '%s'", got)
+	}
+
+	snapshot, err := backend.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	batch := backend.NewBatch()
+	batch.Put([]byte("node/c"), []byte("5"))
+	batch.Delete([]byte("node/b"))
+	err = batch.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := collect(t, snapshot, ""); got != "node/a=4,node/b=2" {
+		t.Errorf("Snapshot sees later writes '%s'", got)
+	}
+
+	err = backend.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//The snapshot must still be readable after the segments it uses have been compacted
+	if got := collect(t, snapshot, ""); got != "node/a=4,node/b=2" {
+		t.Errorf("Snapshot unreadable after compaction '%s'", got)
+	}
+	snapshot.Release()
+
+	backend.Put([]byte("node/d"), []byte("6"))
+	backend.Close()
+
+	backend = openTestBackend(t, dir)
+	defer backend.Close()
+
+	if got := collect(t, backend, ""); got != "node/a=4,node/c=5,node/d=6" {
+		t.Errorf("Unexpected data after reopen '%s'", got)
+	}
+}
+
+func Test_StorageBackend_DamagedTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "abusemesh-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	backend := openTestBackend(t, dir)
+	backend.Put([]byte("a"), []byte("1"))
+
+	batch := backend.NewBatch()
+	batch.Put([]byte("b"), []byte("2"))
+	batch.Put([]byte("c"), []byte("3"))
+	batch.Commit()
+	backend.Close()
+
+	//Cut the last frame in half to simulate a interrupted write, the batch must be lost as a whole
+	path := segmentPath(dir, 0)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Truncate(path, info.Size()-3)
+
+	backend = openTestBackend(t, dir)
+	defer backend.Close()
+
+	if got := collect(t, backend, ""); got != "a=1" {
+		t.Errorf("Unexpected data after truncation '%s'", got)
+	}
+
+	backend.Put([]byte("d"), []byte("4"))
+	if got := collect(t, backend, ""); got != "a=1,d=4" {
+		t.Errorf("Unexpected data after write following truncation '%s'", got)
+	}
+}
+
+//Compaction is scheduled once enough garbage has accumulated, while reads continue
+func Test_StorageBackend_RunCompaction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "abusemesh-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	backend := openTestBackend(t, dir)
+	defer backend.Close()
+
+	for i := 0; i < 10; i++ {
+		backend.Put([]byte("a"), []byte("1234567890"))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go backend.RunCompaction(ctx, 10*time.Millisecond, 50)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for backend.Garbage() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Storage was not compacted, %d bytes of garbage left", backend.Garbage())
+		}
+
+		if value, err := backend.Get([]byte("a")); err != nil || string(value) != "1234567890" {
+			t.Fatalf("Unexpected value '%s' during compaction, error '%v'", value, err)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if got := collect(t, backend, ""); got != "a=1234567890" {
+		t.Errorf("Unexpected data after compaction '%s'", got)
+	}
+}
+
+//Prefix iteration returns exactly the keys with the prefix in ascending order, also once the key index is split in chunks
+func Test_StorageBackend_Iterate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "abusemesh-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	backend := openTestBackend(t, dir)
+	defer backend.Close()
+
+	//The keys are written in a order which differs from the key order
+	const keyCount = 4 * maxChunkKeys
+	for i := 0; i < keyCount; i++ {
+		n := (i * 7919) % keyCount
+		for _, prefix := range []string{"a/", "b/"} {
+			err := backend.Put([]byte(fmt.Sprintf("%s%05d", prefix, n)), []byte(strconv.Itoa(n)))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	//Every other key of b/ is removed again
+	for n := 0; n < keyCount; n += 2 {
+		err := backend.Delete([]byte(fmt.Sprintf("b/%05d", n)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshot, err := backend.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Release()
+
+	err = backend.Put([]byte("b/00000"), []byte("0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//keys returns the keys with the prefix and the numbers from first up to last in steps of step
+	keys := func(prefix string, first, last, step int) []string {
+		var keys []string
+		for n := first; n <= last; n += step {
+			keys = append(keys, fmt.Sprintf("%s%05d", prefix, n))
+		}
+
+		return keys
+	}
+
+	tests := []struct {
+		name     string
+		reader   storage.Reader
+		prefix   string
+		expected []string
+	}{
+		{name: "all keys of a", reader: backend, prefix: "a/", expected: keys("a/", 0, keyCount-1, 1)},
+		{name: "range of a", reader: backend, prefix: "a/001", expected: keys("a/", 100, 199, 1)},
+		{name: "deleted keys of b", reader: snapshot, prefix: "b/", expected: keys("b/", 1, keyCount-1, 2)},
+		{name: "rewritten key of b", reader: backend, prefix: "b/0000", expected: append(keys("b/", 0, 0, 1), keys("b/", 1, 9, 2)...)},
+		{name: "missing prefix", reader: backend, prefix: "c/"},
+	}
+
+	for _, test := range tests {
+		var iterated []string
+		err := test.reader.Iterate([]byte(test.prefix), func(key, value []byte) error {
+			iterated = append(iterated, string(key))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: error while iterating: %s", test.name, err)
+		}
+
+		if !reflect.DeepEqual(iterated, test.expected) {
+			t.Errorf("%s: expected %d keys, got %d: %v", test.name, len(test.expected), len(iterated), iterated)
+		}
+	}
+}
@@ -1,4 +1,65 @@
 package storage
 
+import (
+	"github.com/pkg/errors"
+)
+
+var (
+	//ErrNotFound signals that no value is stored under the requested key
+	ErrNotFound = errors.New("Key not found")
+
+	//ErrClosed signals that the storage backend has been closed
+	ErrClosed = errors.New("Storage backend is closed")
+)
+
+//IterateFunc is called for every key value pair during iteration, returning a error stops the iteration
+//The key and value are only valid during the call and must be copied if they are retained
+type IterateFunc func(key, value []byte) error
+
+//A Reader can read values from the storage
+type Reader interface {
+	//Get returns the value stored under the key or ErrNotFound
+	Get(key []byte) ([]byte, error)
+
+	//Iterate calls the callback for every key starting with the prefix in ascending key order
+	//A nil prefix iterates over all keys
+	Iterate(prefix []byte, callback IterateFunc) error
+}
+
+//A Batch is a set of writes which are applied atomically, either all or none of them are persisted
+type Batch interface {
+	Put(key, value []byte)
+	Delete(key []byte)
+
+	//Commit applies all writes in the batch to the storage
+	Commit() error
+}
+
+//A Snapshot is a consistent read-only view of the storage at the moment it was taken
+//Writes made after the snapshot was taken are not visible through the snapshot
+type Snapshot interface {
+	Reader
+
+	//Release frees the resources held by the snapshot, the snapshot can't be used afterwards
+	Release()
+}
+
+//StorageBackend is a ordered key value store which is used to persist the data of the node
 type StorageBackend interface {
+	Reader
+
+	//Put stores the value under the key, replacing any existing value
+	Put(key, value []byte) error
+
+	//Delete removes the key, deleting a key which doesn't exist is not a error
+	Delete(key []byte) error
+
+	//NewBatch creates a batch of writes which can be committed atomically
+	NewBatch() Batch
+
+	//Snapshot takes a consistent read-only view of the storage
+	Snapshot() (Snapshot, error)
+
+	//Close flushes all data and closes the storage
+	Close() error
 }
@@ -11,10 +11,28 @@ It has these top-level messages:
 	GetNodeRequest
 	GetClientsRequest
 	GetServersRequest
+	TraceEventRequest
+	ListQuarantineRequest
+	GetQuarantinedEventRequest
+	RevalidateQuarantinedEventRequest
+	PurgeQuarantineRequest
+	ListEquivocationsRequest
+	GetReportsByPrefixRequest
+	CreateReportRequest
 	GetClientsResponse
 	GetServersResponse
+	TraceEventResponse
+	ListQuarantineResponse
+	RevalidateQuarantinedEventResponse
+	PurgeQuarantineResponse
+	ListEquivocationsResponse
+	GetReportsByPrefixResponse
+	CreateReportResponse
 	Client
 	Server
+	EventHop
+	QuarantinedEvent
+	Equivocation
 */
 package adminapi
 
@@ -92,6 +110,34 @@ func (x ServerSessionState) String() string {
 }
 func (ServerSessionState) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
 
+// Determines which prefixes match a prefix query
+type PrefixMatch int32
+
+const (
+	// Only the queried prefix itself
+	PrefixMatch_PrefixMatchExact PrefixMatch = 0
+	// The queried prefix and all less specific prefixes which contain it
+	PrefixMatch_PrefixMatchCovering PrefixMatch = 1
+	// The queried prefix and all more specific prefixes within it
+	PrefixMatch_PrefixMatchCoveredBy PrefixMatch = 2
+)
+
+var PrefixMatch_name = map[int32]string{
+	0: "PrefixMatchExact",
+	1: "PrefixMatchCovering",
+	2: "PrefixMatchCoveredBy",
+}
+var PrefixMatch_value = map[string]int32{
+	"PrefixMatchExact":     0,
+	"PrefixMatchCovering":  1,
+	"PrefixMatchCoveredBy": 2,
+}
+
+func (x PrefixMatch) String() string {
+	return proto.EnumName(PrefixMatch_name, int32(x))
+}
+func (PrefixMatch) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
 type GetNodeRequest struct {
 }
 
@@ -116,6 +162,158 @@ func (m *GetServersRequest) String() string            { return proto.CompactTex
 func (*GetServersRequest) ProtoMessage()               {}
 func (*GetServersRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
 
+type TraceEventRequest struct {
+	// The id of the event to trace
+	EventId *abusemesh.UUID `protobuf:"bytes,1,opt,name=event_id,json=eventId" json:"event_id,omitempty"`
+}
+
+func (m *TraceEventRequest) Reset()                    { *m = TraceEventRequest{} }
+func (m *TraceEventRequest) String() string            { return proto.CompactTextString(m) }
+func (*TraceEventRequest) ProtoMessage()               {}
+func (*TraceEventRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *TraceEventRequest) GetEventId() *abusemesh.UUID {
+	if m != nil {
+		return m.EventId
+	}
+	return nil
+}
+
+type ListQuarantineRequest struct {
+}
+
+func (m *ListQuarantineRequest) Reset()                    { *m = ListQuarantineRequest{} }
+func (m *ListQuarantineRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListQuarantineRequest) ProtoMessage()               {}
+func (*ListQuarantineRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+type GetQuarantinedEventRequest struct {
+	EventId *abusemesh.UUID `protobuf:"bytes,1,opt,name=event_id,json=eventId" json:"event_id,omitempty"`
+}
+
+func (m *GetQuarantinedEventRequest) Reset()                    { *m = GetQuarantinedEventRequest{} }
+func (m *GetQuarantinedEventRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetQuarantinedEventRequest) ProtoMessage()               {}
+func (*GetQuarantinedEventRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *GetQuarantinedEventRequest) GetEventId() *abusemesh.UUID {
+	if m != nil {
+		return m.EventId
+	}
+	return nil
+}
+
+type RevalidateQuarantinedEventRequest struct {
+	EventId *abusemesh.UUID `protobuf:"bytes,1,opt,name=event_id,json=eventId" json:"event_id,omitempty"`
+}
+
+func (m *RevalidateQuarantinedEventRequest) Reset()         { *m = RevalidateQuarantinedEventRequest{} }
+func (m *RevalidateQuarantinedEventRequest) String() string { return proto.CompactTextString(m) }
+func (*RevalidateQuarantinedEventRequest) ProtoMessage()    {}
+func (*RevalidateQuarantinedEventRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor0, []int{6}
+}
+
+func (m *RevalidateQuarantinedEventRequest) GetEventId() *abusemesh.UUID {
+	if m != nil {
+		return m.EventId
+	}
+	return nil
+}
+
+type PurgeQuarantineRequest struct {
+	// The ids of the events to remove, all events are removed if empty
+	EventIds []*abusemesh.UUID `protobuf:"bytes,1,rep,name=event_ids,json=eventIds" json:"event_ids,omitempty"`
+}
+
+func (m *PurgeQuarantineRequest) Reset()                    { *m = PurgeQuarantineRequest{} }
+func (m *PurgeQuarantineRequest) String() string            { return proto.CompactTextString(m) }
+func (*PurgeQuarantineRequest) ProtoMessage()               {}
+func (*PurgeQuarantineRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *PurgeQuarantineRequest) GetEventIds() []*abusemesh.UUID {
+	if m != nil {
+		return m.EventIds
+	}
+	return nil
+}
+
+type ListEquivocationsRequest struct {
+}
+
+func (m *ListEquivocationsRequest) Reset()                    { *m = ListEquivocationsRequest{} }
+func (m *ListEquivocationsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListEquivocationsRequest) ProtoMessage()               {}
+func (*ListEquivocationsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+type GetReportsByPrefixRequest struct {
+	// The queried network in CIDR notation, a single IP address is queried as a /32 or /128 prefix
+	Prefix string `protobuf:"bytes,1,opt,name=prefix" json:"prefix,omitempty"`
+	// Determines if reports about the prefix itself, less specific or more specific prefixes are returned
+	Match PrefixMatch `protobuf:"varint,2,opt,name=match,enum=adminapi.PrefixMatch" json:"match,omitempty"`
+}
+
+func (m *GetReportsByPrefixRequest) Reset()                    { *m = GetReportsByPrefixRequest{} }
+func (m *GetReportsByPrefixRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetReportsByPrefixRequest) ProtoMessage()               {}
+func (*GetReportsByPrefixRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *GetReportsByPrefixRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *GetReportsByPrefixRequest) GetMatch() PrefixMatch {
+	if m != nil {
+		return m.Match
+	}
+	return PrefixMatch_PrefixMatchExact
+}
+
+type CreateReportRequest struct {
+	// The reported IP address or network in CIDR notation
+	Prefix      string `protobuf:"bytes,1,opt,name=prefix" json:"prefix,omitempty"`
+	Category    string `protobuf:"bytes,2,opt,name=category" json:"category,omitempty"`
+	Description string `protobuf:"bytes,3,opt,name=description" json:"description,omitempty"`
+	// The time in seconds the report should stay listed, 0 leaves it to the ttl policy of every node
+	Ttl int64 `protobuf:"varint,4,opt,name=ttl" json:"ttl,omitempty"`
+}
+
+func (m *CreateReportRequest) Reset()                    { *m = CreateReportRequest{} }
+func (m *CreateReportRequest) String() string            { return proto.CompactTextString(m) }
+func (*CreateReportRequest) ProtoMessage()               {}
+func (*CreateReportRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *CreateReportRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *CreateReportRequest) GetCategory() string {
+	if m != nil {
+		return m.Category
+	}
+	return ""
+}
+
+func (m *CreateReportRequest) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *CreateReportRequest) GetTtl() int64 {
+	if m != nil {
+		return m.Ttl
+	}
+	return 0
+}
+
 type GetClientsResponse struct {
 	Client []*Client `protobuf:"bytes,1,rep,name=client" json:"client,omitempty"`
 }
@@ -123,7 +321,7 @@ type GetClientsResponse struct {
 func (m *GetClientsResponse) Reset()                    { *m = GetClientsResponse{} }
 func (m *GetClientsResponse) String() string            { return proto.CompactTextString(m) }
 func (*GetClientsResponse) ProtoMessage()               {}
-func (*GetClientsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*GetClientsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
 
 func (m *GetClientsResponse) GetClient() []*Client {
 	if m != nil {
@@ -139,7 +337,7 @@ type GetServersResponse struct {
 func (m *GetServersResponse) Reset()                    { *m = GetServersResponse{} }
 func (m *GetServersResponse) String() string            { return proto.CompactTextString(m) }
 func (*GetServersResponse) ProtoMessage()               {}
-func (*GetServersResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*GetServersResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
 
 func (m *GetServersResponse) GetClient() []*Client {
 	if m != nil {
@@ -148,6 +346,153 @@ func (m *GetServersResponse) GetClient() []*Client {
 	return nil
 }
 
+type TraceEventResponse struct {
+	EventId *abusemesh.UUID `protobuf:"bytes,1,opt,name=event_id,json=eventId" json:"event_id,omitempty"`
+	// The hops through which the event reached this node in the order in which they arrived
+	// No hops are returned if the event is unknown
+	Hops []*EventHop `protobuf:"bytes,2,rep,name=hops" json:"hops,omitempty"`
+}
+
+func (m *TraceEventResponse) Reset()                    { *m = TraceEventResponse{} }
+func (m *TraceEventResponse) String() string            { return proto.CompactTextString(m) }
+func (*TraceEventResponse) ProtoMessage()               {}
+func (*TraceEventResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *TraceEventResponse) GetEventId() *abusemesh.UUID {
+	if m != nil {
+		return m.EventId
+	}
+	return nil
+}
+
+func (m *TraceEventResponse) GetHops() []*EventHop {
+	if m != nil {
+		return m.Hops
+	}
+	return nil
+}
+
+type ListQuarantineResponse struct {
+	// The quarantined events, the most recently refused event last
+	Events []*QuarantinedEvent `protobuf:"bytes,1,rep,name=events" json:"events,omitempty"`
+}
+
+func (m *ListQuarantineResponse) Reset()                    { *m = ListQuarantineResponse{} }
+func (m *ListQuarantineResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListQuarantineResponse) ProtoMessage()               {}
+func (*ListQuarantineResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *ListQuarantineResponse) GetEvents() []*QuarantinedEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+type RevalidateQuarantinedEventResponse struct {
+	// True if the event is valid now, it is removed from the quarantine and written to the event stream
+	Accepted bool `protobuf:"varint,1,opt,name=accepted" json:"accepted,omitempty"`
+	// The reason the event is still invalid
+	Reason string `protobuf:"bytes,2,opt,name=reason" json:"reason,omitempty"`
+}
+
+func (m *RevalidateQuarantinedEventResponse) Reset()         { *m = RevalidateQuarantinedEventResponse{} }
+func (m *RevalidateQuarantinedEventResponse) String() string { return proto.CompactTextString(m) }
+func (*RevalidateQuarantinedEventResponse) ProtoMessage()    {}
+func (*RevalidateQuarantinedEventResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor0, []int{15}
+}
+
+func (m *RevalidateQuarantinedEventResponse) GetAccepted() bool {
+	if m != nil {
+		return m.Accepted
+	}
+	return false
+}
+
+func (m *RevalidateQuarantinedEventResponse) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+type PurgeQuarantineResponse struct {
+	// The amount of events which were removed
+	Purged uint64 `protobuf:"varint,1,opt,name=purged" json:"purged,omitempty"`
+}
+
+func (m *PurgeQuarantineResponse) Reset()                    { *m = PurgeQuarantineResponse{} }
+func (m *PurgeQuarantineResponse) String() string            { return proto.CompactTextString(m) }
+func (*PurgeQuarantineResponse) ProtoMessage()               {}
+func (*PurgeQuarantineResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *PurgeQuarantineResponse) GetPurged() uint64 {
+	if m != nil {
+		return m.Purged
+	}
+	return 0
+}
+
+type ListEquivocationsResponse struct {
+	// The detected equivocations, the most recently detected last
+	Equivocations []*Equivocation `protobuf:"bytes,1,rep,name=equivocations" json:"equivocations,omitempty"`
+}
+
+func (m *ListEquivocationsResponse) Reset()                    { *m = ListEquivocationsResponse{} }
+func (m *ListEquivocationsResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListEquivocationsResponse) ProtoMessage()               {}
+func (*ListEquivocationsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *ListEquivocationsResponse) GetEquivocations() []*Equivocation {
+	if m != nil {
+		return m.Equivocations
+	}
+	return nil
+}
+
+type GetReportsByPrefixResponse struct {
+	// The matching reports, reports about the same prefix are ordered by UUID
+	Reports []*abusemesh.Report `protobuf:"bytes,1,rep,name=reports" json:"reports,omitempty"`
+}
+
+func (m *GetReportsByPrefixResponse) Reset()                    { *m = GetReportsByPrefixResponse{} }
+func (m *GetReportsByPrefixResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetReportsByPrefixResponse) ProtoMessage()               {}
+func (*GetReportsByPrefixResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *GetReportsByPrefixResponse) GetReports() []*abusemesh.Report {
+	if m != nil {
+		return m.Reports
+	}
+	return nil
+}
+
+type CreateReportResponse struct {
+	// The id of the event which lists the report, it can be traced or looked up in the quarantine if it was refused
+	EventId *abusemesh.UUID   `protobuf:"bytes,1,opt,name=event_id,json=eventId" json:"event_id,omitempty"`
+	Report  *abusemesh.Report `protobuf:"bytes,2,opt,name=report" json:"report,omitempty"`
+}
+
+func (m *CreateReportResponse) Reset()                    { *m = CreateReportResponse{} }
+func (m *CreateReportResponse) String() string            { return proto.CompactTextString(m) }
+func (*CreateReportResponse) ProtoMessage()               {}
+func (*CreateReportResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *CreateReportResponse) GetEventId() *abusemesh.UUID {
+	if m != nil {
+		return m.EventId
+	}
+	return nil
+}
+
+func (m *CreateReportResponse) GetReport() *abusemesh.Report {
+	if m != nil {
+		return m.Report
+	}
+	return nil
+}
+
 type Client struct {
 	// The id of the client node
 	NodeId *abusemesh.UUID `protobuf:"bytes,1,opt,name=node_id,json=nodeId" json:"node_id,omitempty"`
@@ -165,7 +510,7 @@ type Client struct {
 func (m *Client) Reset()                    { *m = Client{} }
 func (m *Client) String() string            { return proto.CompactTextString(m) }
 func (*Client) ProtoMessage()               {}
-func (*Client) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*Client) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
 
 func (m *Client) GetNodeId() *abusemesh.UUID {
 	if m != nil {
@@ -219,7 +564,7 @@ type Server struct {
 func (m *Server) Reset()                    { *m = Server{} }
 func (m *Server) String() string            { return proto.CompactTextString(m) }
 func (*Server) ProtoMessage()               {}
-func (*Server) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (*Server) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
 
 func (m *Server) GetNodeId() *abusemesh.UUID {
 	if m != nil {
@@ -256,16 +601,196 @@ func (m *Server) GetEventCount() uint64 {
 	return 0
 }
 
+// The arrival of a event at this node
+type EventHop struct {
+	// The id of the neighbor which sent the event, empty if the event was written on this node
+	NeighborId *abusemesh.UUID `protobuf:"bytes,1,opt,name=neighbor_id,json=neighborId" json:"neighbor_id,omitempty"`
+	// The time at which the event arrived as unix timestamp in nanoseconds
+	ReceivedAt int64 `protobuf:"varint,2,opt,name=received_at,json=receivedAt" json:"received_at,omitempty"`
+	// True if this copy of the event was accepted, the other hops delivered duplicates
+	Accepted bool `protobuf:"varint,3,opt,name=accepted" json:"accepted,omitempty"`
+}
+
+func (m *EventHop) Reset()                    { *m = EventHop{} }
+func (m *EventHop) String() string            { return proto.CompactTextString(m) }
+func (*EventHop) ProtoMessage()               {}
+func (*EventHop) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *EventHop) GetNeighborId() *abusemesh.UUID {
+	if m != nil {
+		return m.NeighborId
+	}
+	return nil
+}
+
+func (m *EventHop) GetReceivedAt() int64 {
+	if m != nil {
+		return m.ReceivedAt
+	}
+	return 0
+}
+
+func (m *EventHop) GetAccepted() bool {
+	if m != nil {
+		return m.Accepted
+	}
+	return false
+}
+
+// A event which was refused because it is invalid
+type QuarantinedEvent struct {
+	// The id of the event, empty if the event has no valid id
+	EventId *abusemesh.UUID       `protobuf:"bytes,1,opt,name=event_id,json=eventId" json:"event_id,omitempty"`
+	Event   *abusemesh.TableEvent `protobuf:"bytes,2,opt,name=event" json:"event,omitempty"`
+	// The validation error of the last time the event was refused
+	Reason string `protobuf:"bytes,3,opt,name=reason" json:"reason,omitempty"`
+	// The id of the neighbor which sent the event, empty if the event was written on this node
+	SourceId *abusemesh.UUID `protobuf:"bytes,4,opt,name=source_id,json=sourceId" json:"source_id,omitempty"`
+	// The time at which the event arrived as unix timestamp in nanoseconds
+	ReceivedAt int64 `protobuf:"varint,5,opt,name=received_at,json=receivedAt" json:"received_at,omitempty"`
+	// The time at which the event was last refused as unix timestamp in nanoseconds
+	QuarantinedAt int64 `protobuf:"varint,6,opt,name=quarantined_at,json=quarantinedAt" json:"quarantined_at,omitempty"`
+	// The amount of times the event was refused
+	Rejections uint64 `protobuf:"varint,7,opt,name=rejections" json:"rejections,omitempty"`
+}
+
+func (m *QuarantinedEvent) Reset()                    { *m = QuarantinedEvent{} }
+func (m *QuarantinedEvent) String() string            { return proto.CompactTextString(m) }
+func (*QuarantinedEvent) ProtoMessage()               {}
+func (*QuarantinedEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+
+func (m *QuarantinedEvent) GetEventId() *abusemesh.UUID {
+	if m != nil {
+		return m.EventId
+	}
+	return nil
+}
+
+func (m *QuarantinedEvent) GetEvent() *abusemesh.TableEvent {
+	if m != nil {
+		return m.Event
+	}
+	return nil
+}
+
+func (m *QuarantinedEvent) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *QuarantinedEvent) GetSourceId() *abusemesh.UUID {
+	if m != nil {
+		return m.SourceId
+	}
+	return nil
+}
+
+func (m *QuarantinedEvent) GetReceivedAt() int64 {
+	if m != nil {
+		return m.ReceivedAt
+	}
+	return 0
+}
+
+func (m *QuarantinedEvent) GetQuarantinedAt() int64 {
+	if m != nil {
+		return m.QuarantinedAt
+	}
+	return 0
+}
+
+func (m *QuarantinedEvent) GetRejections() uint64 {
+	if m != nil {
+		return m.Rejections
+	}
+	return 0
+}
+
+// Proof that a node signed two conflicting events about the same entity
+type Equivocation struct {
+	// The id of the node which signed both events
+	AuthorId *abusemesh.UUID `protobuf:"bytes,1,opt,name=author_id,json=authorId" json:"author_id,omitempty"`
+	// The key of the entity both events are about
+	Entity string `protobuf:"bytes,2,opt,name=entity" json:"entity,omitempty"`
+	// The event which was seen first, including its signature
+	FirstEvent *abusemesh.TableEvent `protobuf:"bytes,3,opt,name=first_event,json=firstEvent" json:"first_event,omitempty"`
+	// The event which conflicts with the first event, including its signature
+	SecondEvent *abusemesh.TableEvent `protobuf:"bytes,4,opt,name=second_event,json=secondEvent" json:"second_event,omitempty"`
+	// The time at which the conflict was detected as unix timestamp in nanoseconds
+	DetectedAt int64 `protobuf:"varint,5,opt,name=detected_at,json=detectedAt" json:"detected_at,omitempty"`
+}
+
+func (m *Equivocation) Reset()                    { *m = Equivocation{} }
+func (m *Equivocation) String() string            { return proto.CompactTextString(m) }
+func (*Equivocation) ProtoMessage()               {}
+func (*Equivocation) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+func (m *Equivocation) GetAuthorId() *abusemesh.UUID {
+	if m != nil {
+		return m.AuthorId
+	}
+	return nil
+}
+
+func (m *Equivocation) GetEntity() string {
+	if m != nil {
+		return m.Entity
+	}
+	return ""
+}
+
+func (m *Equivocation) GetFirstEvent() *abusemesh.TableEvent {
+	if m != nil {
+		return m.FirstEvent
+	}
+	return nil
+}
+
+func (m *Equivocation) GetSecondEvent() *abusemesh.TableEvent {
+	if m != nil {
+		return m.SecondEvent
+	}
+	return nil
+}
+
+func (m *Equivocation) GetDetectedAt() int64 {
+	if m != nil {
+		return m.DetectedAt
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*GetNodeRequest)(nil), "adminapi.GetNodeRequest")
 	proto.RegisterType((*GetClientsRequest)(nil), "adminapi.GetClientsRequest")
 	proto.RegisterType((*GetServersRequest)(nil), "adminapi.GetServersRequest")
+	proto.RegisterType((*TraceEventRequest)(nil), "adminapi.TraceEventRequest")
+	proto.RegisterType((*ListQuarantineRequest)(nil), "adminapi.ListQuarantineRequest")
+	proto.RegisterType((*GetQuarantinedEventRequest)(nil), "adminapi.GetQuarantinedEventRequest")
+	proto.RegisterType((*RevalidateQuarantinedEventRequest)(nil), "adminapi.RevalidateQuarantinedEventRequest")
+	proto.RegisterType((*PurgeQuarantineRequest)(nil), "adminapi.PurgeQuarantineRequest")
+	proto.RegisterType((*ListEquivocationsRequest)(nil), "adminapi.ListEquivocationsRequest")
+	proto.RegisterType((*GetReportsByPrefixRequest)(nil), "adminapi.GetReportsByPrefixRequest")
+	proto.RegisterType((*CreateReportRequest)(nil), "adminapi.CreateReportRequest")
 	proto.RegisterType((*GetClientsResponse)(nil), "adminapi.GetClientsResponse")
 	proto.RegisterType((*GetServersResponse)(nil), "adminapi.GetServersResponse")
+	proto.RegisterType((*TraceEventResponse)(nil), "adminapi.TraceEventResponse")
+	proto.RegisterType((*ListQuarantineResponse)(nil), "adminapi.ListQuarantineResponse")
+	proto.RegisterType((*RevalidateQuarantinedEventResponse)(nil), "adminapi.RevalidateQuarantinedEventResponse")
+	proto.RegisterType((*PurgeQuarantineResponse)(nil), "adminapi.PurgeQuarantineResponse")
+	proto.RegisterType((*ListEquivocationsResponse)(nil), "adminapi.ListEquivocationsResponse")
+	proto.RegisterType((*GetReportsByPrefixResponse)(nil), "adminapi.GetReportsByPrefixResponse")
+	proto.RegisterType((*CreateReportResponse)(nil), "adminapi.CreateReportResponse")
 	proto.RegisterType((*Client)(nil), "adminapi.Client")
 	proto.RegisterType((*Server)(nil), "adminapi.Server")
+	proto.RegisterType((*EventHop)(nil), "adminapi.EventHop")
+	proto.RegisterType((*QuarantinedEvent)(nil), "adminapi.QuarantinedEvent")
+	proto.RegisterType((*Equivocation)(nil), "adminapi.Equivocation")
 	proto.RegisterEnum("adminapi.ClientSessionState", ClientSessionState_name, ClientSessionState_value)
 	proto.RegisterEnum("adminapi.ServerSessionState", ServerSessionState_name, ServerSessionState_value)
+	proto.RegisterEnum("adminapi.PrefixMatch", PrefixMatch_name, PrefixMatch_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -285,6 +810,23 @@ type AdmininterfaceClient interface {
 	GetClients(ctx context.Context, in *GetClientsRequest, opts ...grpc.CallOption) (*GetClientsResponse, error)
 	// Returns all servers of this node
 	GetServers(ctx context.Context, in *GetServersRequest, opts ...grpc.CallOption) (*GetServersResponse, error)
+	// Returns from which neighbors and when a event reached this node
+	// A event can be traced through the mesh by tracing it on the neighbor it was accepted from
+	TraceEvent(ctx context.Context, in *TraceEventRequest, opts ...grpc.CallOption) (*TraceEventResponse, error)
+	// Returns the events which were refused because they are invalid
+	ListQuarantine(ctx context.Context, in *ListQuarantineRequest, opts ...grpc.CallOption) (*ListQuarantineResponse, error)
+	// Returns a single quarantined event
+	GetQuarantinedEvent(ctx context.Context, in *GetQuarantinedEventRequest, opts ...grpc.CallOption) (*QuarantinedEvent, error)
+	// Validates a quarantined event again, if it is valid now it is written to the event stream
+	RevalidateQuarantinedEvent(ctx context.Context, in *RevalidateQuarantinedEventRequest, opts ...grpc.CallOption) (*RevalidateQuarantinedEventResponse, error)
+	// Removes events from the quarantine
+	PurgeQuarantine(ctx context.Context, in *PurgeQuarantineRequest, opts ...grpc.CallOption) (*PurgeQuarantineResponse, error)
+	// Returns the proofs of nodes which signed conflicting events
+	ListEquivocations(ctx context.Context, in *ListEquivocationsRequest, opts ...grpc.CallOption) (*ListEquivocationsResponse, error)
+	// Returns the reports about a IP address or network
+	GetReportsByPrefix(ctx context.Context, in *GetReportsByPrefixRequest, opts ...grpc.CallOption) (*GetReportsByPrefixResponse, error)
+	// Signs a new report of this node and submits it to the event stream
+	CreateReport(ctx context.Context, in *CreateReportRequest, opts ...grpc.CallOption) (*CreateReportResponse, error)
 }
 
 type admininterfaceClient struct {
@@ -322,6 +864,78 @@ func (c *admininterfaceClient) GetServers(ctx context.Context, in *GetServersReq
 	return out, nil
 }
 
+func (c *admininterfaceClient) TraceEvent(ctx context.Context, in *TraceEventRequest, opts ...grpc.CallOption) (*TraceEventResponse, error) {
+	out := new(TraceEventResponse)
+	err := grpc.Invoke(ctx, "/adminapi.admininterface/TraceEvent", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *admininterfaceClient) ListQuarantine(ctx context.Context, in *ListQuarantineRequest, opts ...grpc.CallOption) (*ListQuarantineResponse, error) {
+	out := new(ListQuarantineResponse)
+	err := grpc.Invoke(ctx, "/adminapi.admininterface/ListQuarantine", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *admininterfaceClient) GetQuarantinedEvent(ctx context.Context, in *GetQuarantinedEventRequest, opts ...grpc.CallOption) (*QuarantinedEvent, error) {
+	out := new(QuarantinedEvent)
+	err := grpc.Invoke(ctx, "/adminapi.admininterface/GetQuarantinedEvent", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *admininterfaceClient) RevalidateQuarantinedEvent(ctx context.Context, in *RevalidateQuarantinedEventRequest, opts ...grpc.CallOption) (*RevalidateQuarantinedEventResponse, error) {
+	out := new(RevalidateQuarantinedEventResponse)
+	err := grpc.Invoke(ctx, "/adminapi.admininterface/RevalidateQuarantinedEvent", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *admininterfaceClient) PurgeQuarantine(ctx context.Context, in *PurgeQuarantineRequest, opts ...grpc.CallOption) (*PurgeQuarantineResponse, error) {
+	out := new(PurgeQuarantineResponse)
+	err := grpc.Invoke(ctx, "/adminapi.admininterface/PurgeQuarantine", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *admininterfaceClient) ListEquivocations(ctx context.Context, in *ListEquivocationsRequest, opts ...grpc.CallOption) (*ListEquivocationsResponse, error) {
+	out := new(ListEquivocationsResponse)
+	err := grpc.Invoke(ctx, "/adminapi.admininterface/ListEquivocations", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *admininterfaceClient) GetReportsByPrefix(ctx context.Context, in *GetReportsByPrefixRequest, opts ...grpc.CallOption) (*GetReportsByPrefixResponse, error) {
+	out := new(GetReportsByPrefixResponse)
+	err := grpc.Invoke(ctx, "/adminapi.admininterface/GetReportsByPrefix", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *admininterfaceClient) CreateReport(ctx context.Context, in *CreateReportRequest, opts ...grpc.CallOption) (*CreateReportResponse, error) {
+	out := new(CreateReportResponse)
+	err := grpc.Invoke(ctx, "/adminapi.admininterface/CreateReport", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Admininterface service
 
 type AdmininterfaceServer interface {
@@ -331,6 +945,23 @@ type AdmininterfaceServer interface {
 	GetClients(context.Context, *GetClientsRequest) (*GetClientsResponse, error)
 	// Returns all servers of this node
 	GetServers(context.Context, *GetServersRequest) (*GetServersResponse, error)
+	// Returns from which neighbors and when a event reached this node
+	// A event can be traced through the mesh by tracing it on the neighbor it was accepted from
+	TraceEvent(context.Context, *TraceEventRequest) (*TraceEventResponse, error)
+	// Returns the events which were refused because they are invalid
+	ListQuarantine(context.Context, *ListQuarantineRequest) (*ListQuarantineResponse, error)
+	// Returns a single quarantined event
+	GetQuarantinedEvent(context.Context, *GetQuarantinedEventRequest) (*QuarantinedEvent, error)
+	// Validates a quarantined event again, if it is valid now it is written to the event stream
+	RevalidateQuarantinedEvent(context.Context, *RevalidateQuarantinedEventRequest) (*RevalidateQuarantinedEventResponse, error)
+	// Removes events from the quarantine
+	PurgeQuarantine(context.Context, *PurgeQuarantineRequest) (*PurgeQuarantineResponse, error)
+	// Returns the proofs of nodes which signed conflicting events
+	ListEquivocations(context.Context, *ListEquivocationsRequest) (*ListEquivocationsResponse, error)
+	// Returns the reports about a IP address or network
+	GetReportsByPrefix(context.Context, *GetReportsByPrefixRequest) (*GetReportsByPrefixResponse, error)
+	// Signs a new report of this node and submits it to the event stream
+	CreateReport(context.Context, *CreateReportRequest) (*CreateReportResponse, error)
 }
 
 func RegisterAdmininterfaceServer(s *grpc.Server, srv AdmininterfaceServer) {
@@ -391,6 +1022,150 @@ func _Admininterface_GetServers_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Admininterface_TraceEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TraceEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdmininterfaceServer).TraceEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminapi.admininterface/TraceEvent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdmininterfaceServer).TraceEvent(ctx, req.(*TraceEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admininterface_ListQuarantine_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListQuarantineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdmininterfaceServer).ListQuarantine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminapi.admininterface/ListQuarantine",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdmininterfaceServer).ListQuarantine(ctx, req.(*ListQuarantineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admininterface_GetQuarantinedEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetQuarantinedEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdmininterfaceServer).GetQuarantinedEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminapi.admininterface/GetQuarantinedEvent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdmininterfaceServer).GetQuarantinedEvent(ctx, req.(*GetQuarantinedEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admininterface_RevalidateQuarantinedEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevalidateQuarantinedEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdmininterfaceServer).RevalidateQuarantinedEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminapi.admininterface/RevalidateQuarantinedEvent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdmininterfaceServer).RevalidateQuarantinedEvent(ctx, req.(*RevalidateQuarantinedEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admininterface_PurgeQuarantine_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeQuarantineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdmininterfaceServer).PurgeQuarantine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminapi.admininterface/PurgeQuarantine",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdmininterfaceServer).PurgeQuarantine(ctx, req.(*PurgeQuarantineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admininterface_ListEquivocations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListEquivocationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdmininterfaceServer).ListEquivocations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminapi.admininterface/ListEquivocations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdmininterfaceServer).ListEquivocations(ctx, req.(*ListEquivocationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admininterface_GetReportsByPrefix_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetReportsByPrefixRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdmininterfaceServer).GetReportsByPrefix(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminapi.admininterface/GetReportsByPrefix",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdmininterfaceServer).GetReportsByPrefix(ctx, req.(*GetReportsByPrefixRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admininterface_CreateReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdmininterfaceServer).CreateReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/adminapi.admininterface/CreateReport",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdmininterfaceServer).CreateReport(ctx, req.(*CreateReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Admininterface_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "adminapi.admininterface",
 	HandlerType: (*AdmininterfaceServer)(nil),
@@ -407,6 +1182,38 @@ var _Admininterface_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetServers",
 			Handler:    _Admininterface_GetServers_Handler,
 		},
+		{
+			MethodName: "TraceEvent",
+			Handler:    _Admininterface_TraceEvent_Handler,
+		},
+		{
+			MethodName: "ListQuarantine",
+			Handler:    _Admininterface_ListQuarantine_Handler,
+		},
+		{
+			MethodName: "GetQuarantinedEvent",
+			Handler:    _Admininterface_GetQuarantinedEvent_Handler,
+		},
+		{
+			MethodName: "RevalidateQuarantinedEvent",
+			Handler:    _Admininterface_RevalidateQuarantinedEvent_Handler,
+		},
+		{
+			MethodName: "PurgeQuarantine",
+			Handler:    _Admininterface_PurgeQuarantine_Handler,
+		},
+		{
+			MethodName: "ListEquivocations",
+			Handler:    _Admininterface_ListEquivocations_Handler,
+		},
+		{
+			MethodName: "GetReportsByPrefix",
+			Handler:    _Admininterface_GetReportsByPrefix_Handler,
+		},
+		{
+			MethodName: "CreateReport",
+			Handler:    _Admininterface_CreateReport_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/adminapi/adminapi.proto",
@@ -415,35 +1222,82 @@ var _Admininterface_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("internal/adminapi/adminapi.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 469 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x94, 0x5f, 0x8b, 0xd3, 0x4c,
-	0x14, 0xc6, 0xdf, 0x69, 0x77, 0xbb, 0xfb, 0x9e, 0x6a, 0xcd, 0x8e, 0x88, 0xa1, 0x5b, 0x30, 0xac,
-	0x37, 0x61, 0x61, 0xb3, 0x10, 0xbd, 0x16, 0x96, 0x2a, 0x25, 0x37, 0x5e, 0xa4, 0xec, 0x75, 0x49,
-	0x33, 0xc7, 0x76, 0x20, 0x99, 0xa9, 0x99, 0x49, 0x3f, 0x81, 0x1f, 0xd2, 0x0b, 0x3f, 0x8c, 0x64,
-	0x26, 0xb6, 0x99, 0x74, 0x41, 0x14, 0xbc, 0x9b, 0xfe, 0xce, 0x73, 0xfe, 0x96, 0x27, 0x10, 0x70,
-	0xa1, 0xb1, 0x12, 0x59, 0x71, 0x9f, 0xb1, 0x92, 0x8b, 0x6c, 0xc7, 0x0f, 0x8f, 0x68, 0x57, 0x49,
-	0x2d, 0xe9, 0xe5, 0xaf, 0xdf, 0xd3, 0x87, 0x0d, 0xd7, 0xdb, 0x7a, 0x1d, 0xe5, 0xb2, 0xbc, 0xcf,
-	0xd6, 0xb5, 0xc2, 0xbb, 0x12, 0xd5, 0xb6, 0xf3, 0xbc, 0x33, 0x19, 0xb9, 0x2c, 0xba, 0x2c, 0x97,
-	0x65, 0x29, 0x85, 0x2d, 0x76, 0xe3, 0xc1, 0x64, 0x81, 0xfa, 0xb3, 0x64, 0x98, 0xe2, 0xd7, 0x1a,
-	0x95, 0xbe, 0x79, 0x09, 0x57, 0x0b, 0xd4, 0xf3, 0x82, 0xa3, 0xd0, 0xca, 0x85, 0x4b, 0xac, 0xf6,
-	0x58, 0x1d, 0xe0, 0x07, 0xa0, 0x5d, 0xa5, 0xda, 0x49, 0xa1, 0x90, 0x86, 0x30, 0xca, 0x0d, 0xf2,
-	0x49, 0x30, 0x0c, 0xc7, 0xb1, 0x17, 0x1d, 0xe6, 0xb7, 0xd2, 0xb4, 0x8d, 0xb7, 0xf9, 0x87, 0xa2,
-	0x7f, 0x9c, 0xff, 0x83, 0xc0, 0xc8, 0x22, 0x1a, 0xc2, 0x85, 0x90, 0x0c, 0x57, 0x9c, 0xf9, 0x24,
-	0x20, 0xe1, 0x38, 0x7e, 0x11, 0x99, 0x8d, 0x9b, 0x85, 0xa3, 0xc7, 0xc7, 0xe4, 0x63, 0x3a, 0x6a,
-	0xe2, 0x09, 0xa3, 0x11, 0x80, 0x42, 0xa5, 0xb8, 0x14, 0x8d, 0x78, 0xf0, 0xb4, 0xf8, 0xff, 0x56,
-	0x92, 0x30, 0xfa, 0x16, 0x9e, 0x2b, 0x33, 0xe1, 0x2a, 0xcb, 0x35, 0xdf, 0xa3, 0x3f, 0x0c, 0x48,
-	0x78, 0x99, 0x3e, 0xb3, 0xf0, 0xc1, 0x30, 0x1a, 0xc3, 0xb9, 0xd2, 0x99, 0x46, 0xff, 0x2c, 0x20,
-	0xe1, 0x24, 0x9e, 0xf5, 0x47, 0x5e, 0xda, 0x72, 0xcb, 0x46, 0x93, 0x5a, 0x29, 0x7d, 0x03, 0x63,
-	0xdc, 0xa3, 0xd0, 0xab, 0x5c, 0xd6, 0x42, 0xfb, 0xe7, 0x01, 0x09, 0xcf, 0x52, 0x30, 0x68, 0xde,
-	0x10, 0xb3, 0x9e, 0x3d, 0xce, 0xbf, 0x5d, 0xcf, 0x5e, 0xb3, 0xb7, 0x9e, 0x85, 0xbf, 0x5d, 0xcf,
-	0xce, 0xf7, 0x37, 0xeb, 0xdd, 0x6e, 0x80, 0x9e, 0x1e, 0x87, 0xbe, 0x82, 0x2b, 0x87, 0x26, 0xac,
-	0x40, 0xef, 0x3f, 0x3a, 0x03, 0xdf, 0xc1, 0x9f, 0x94, 0xce, 0xd6, 0x05, 0x57, 0x5b, 0x64, 0x1e,
-	0x39, 0x89, 0x26, 0x8d, 0x85, 0xaa, 0x7a, 0xa7, 0x91, 0x79, 0x83, 0xdb, 0x6f, 0x04, 0xe8, 0xe9,
-	0x9c, 0x4d, 0x27, 0x87, 0x1e, 0x3b, 0x39, 0xd8, 0xed, 0x74, 0x0d, 0xaf, 0x9d, 0xe8, 0x5c, 0x0a,
-	0x81, 0xb9, 0xe6, 0x62, 0xe3, 0x0d, 0x4e, 0x52, 0xbb, 0x63, 0x0c, 0xe3, 0xef, 0x04, 0x26, 0xe6,
-	0x6e, 0xc6, 0xe0, 0x5f, 0xb2, 0x1c, 0xe9, 0x7b, 0xb8, 0x68, 0xcd, 0x47, 0xfd, 0xe3, 0x4d, 0x5d,
-	0x3f, 0x4e, 0xbb, 0xff, 0x9e, 0x91, 0x2e, 0x00, 0x8e, 0xb6, 0xa3, 0xd7, 0x4e, 0xa2, 0x6b, 0xdb,
-	0xe9, 0xec, 0xe9, 0x60, 0xeb, 0x34, 0x5b, 0xa8, 0xf5, 0x5f, 0xaf, 0x90, 0x6b, 0xf5, 0x5e, 0xa1,
-	0x9e, 0x65, 0xd7, 0x23, 0xf3, 0x2d, 0x79, 0xf7, 0x33, 0x00, 0x00, 0xff, 0xff, 0x66, 0x6f, 0x97,
-	0x9e, 0xbc, 0x04, 0x00, 0x00,
+	// 1217 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x57, 0x6f, 0x73, 0xdb, 0xc4,
+	0x13, 0xae, 0xed, 0xc4, 0x76, 0xd6, 0x89, 0xeb, 0x5c, 0xfe, 0xb9, 0x6a, 0x7e, 0x8d, 0xab, 0xf4,
+	0xc7, 0x84, 0xa4, 0x75, 0xc1, 0x30, 0x0c, 0x2f, 0x18, 0x98, 0x34, 0x84, 0xd4, 0x33, 0x2d, 0x14,
+	0xa5, 0x2d, 0x30, 0xc3, 0x4c, 0x90, 0xa5, 0x8d, 0x2d, 0xc6, 0xd6, 0x39, 0xba, 0xb3, 0x69, 0x5e,
+	0xf0, 0x8e, 0x2f, 0xc1, 0x97, 0xe0, 0x13, 0xf1, 0x9e, 0xaf, 0xc1, 0xdc, 0x1f, 0x5b, 0x27, 0x4b,
+	0x71, 0x48, 0x06, 0xde, 0xe9, 0x76, 0x9f, 0x7d, 0x56, 0x7b, 0xb7, 0x7b, 0x7a, 0x04, 0x8d, 0x20,
+	0xe4, 0x18, 0x85, 0x6e, 0xff, 0xa9, 0xeb, 0x0f, 0x82, 0xd0, 0x1d, 0x06, 0xd3, 0x87, 0xe6, 0x30,
+	0xa2, 0x9c, 0x92, 0xf2, 0x64, 0x6d, 0x1d, 0x76, 0x03, 0xde, 0x1b, 0x75, 0x9a, 0x1e, 0x1d, 0x3c,
+	0x75, 0x3b, 0x23, 0x86, 0x4f, 0x06, 0xc8, 0x7a, 0xc6, 0xe3, 0x13, 0x19, 0xe1, 0xd1, 0xbe, 0x69,
+	0xf3, 0xe8, 0x60, 0x40, 0x43, 0x45, 0x66, 0xd7, 0xa0, 0x7a, 0x82, 0xfc, 0x6b, 0xea, 0xa3, 0x83,
+	0x17, 0x23, 0x64, 0xdc, 0x5e, 0x83, 0xd5, 0x13, 0xe4, 0x47, 0xfd, 0x00, 0x43, 0xce, 0x92, 0xc6,
+	0x53, 0x8c, 0xc6, 0x18, 0x4d, 0x8d, 0x5f, 0xc0, 0xea, 0xeb, 0xc8, 0xf5, 0xf0, 0x78, 0x8c, 0x21,
+	0xd7, 0x46, 0xb2, 0x0f, 0x65, 0x14, 0xeb, 0xb3, 0xc0, 0xaf, 0xe7, 0x1a, 0xb9, 0xbd, 0x4a, 0xeb,
+	0x6e, 0x53, 0x26, 0x17, 0xb9, 0x9b, 0x6f, 0xde, 0xb4, 0xbf, 0x74, 0x4a, 0x12, 0xd0, 0xf6, 0xed,
+	0x2d, 0xd8, 0x78, 0x11, 0x30, 0xfe, 0xed, 0xc8, 0x8d, 0xdc, 0x90, 0x07, 0xe1, 0xf4, 0x1d, 0x9e,
+	0x83, 0x75, 0x82, 0x86, 0xdd, 0xbf, 0x75, 0x8a, 0x6f, 0xe0, 0xa1, 0x83, 0x63, 0xb7, 0x1f, 0xf8,
+	0x2e, 0xc7, 0x7f, 0x83, 0xf0, 0x2b, 0xd8, 0x7c, 0x35, 0x8a, 0xba, 0x98, 0x7a, 0x69, 0xf2, 0x18,
+	0x96, 0x26, 0x2c, 0xac, 0x9e, 0x6b, 0x14, 0xb2, 0x68, 0xca, 0x9a, 0x86, 0xd9, 0x16, 0xd4, 0x45,
+	0xed, 0xc7, 0x17, 0xa3, 0x60, 0x4c, 0x3d, 0x97, 0x07, 0x34, 0x9c, 0x6e, 0xec, 0x4f, 0x70, 0xef,
+	0x04, 0xb9, 0x83, 0x43, 0x1a, 0x71, 0xf6, 0xec, 0xf2, 0x55, 0x84, 0xe7, 0xc1, 0xbb, 0x49, 0x9a,
+	0x4d, 0x28, 0x0e, 0xa5, 0x41, 0xbe, 0xea, 0x92, 0xa3, 0x57, 0xe4, 0x00, 0x16, 0x07, 0x2e, 0xf7,
+	0x7a, 0xf5, 0x7c, 0x23, 0xb7, 0x57, 0x6d, 0x6d, 0x34, 0xa7, 0x6d, 0xa3, 0xe2, 0x5f, 0x0a, 0xa7,
+	0xa3, 0x30, 0xf6, 0xaf, 0xb0, 0x76, 0x14, 0xa1, 0xcb, 0x51, 0x25, 0xb9, 0x8e, 0xdb, 0x82, 0xb2,
+	0xe7, 0x72, 0xec, 0xd2, 0xe8, 0x52, 0xd2, 0x2f, 0x39, 0xd3, 0x35, 0x69, 0x40, 0xc5, 0x47, 0xe6,
+	0x45, 0xc1, 0x50, 0xd4, 0x50, 0x2f, 0x48, 0xb7, 0x69, 0x22, 0x35, 0x28, 0x70, 0xde, 0xaf, 0x2f,
+	0x34, 0x72, 0x7b, 0x05, 0x47, 0x3c, 0xda, 0x9f, 0x03, 0x31, 0x7b, 0x8c, 0x0d, 0x69, 0xc8, 0x90,
+	0xec, 0x41, 0xd1, 0x93, 0x26, 0xbd, 0x7b, 0xb5, 0xb8, 0x04, 0x05, 0x75, 0xb4, 0x5f, 0xc7, 0x4f,
+	0xdb, 0xf1, 0xc6, 0xf1, 0x3d, 0x20, 0x66, 0xe7, 0xea, 0xf8, 0x1b, 0xb4, 0x01, 0x79, 0x0f, 0x16,
+	0x7a, 0x74, 0xc8, 0xea, 0x79, 0x99, 0x89, 0xc4, 0x99, 0x24, 0xe5, 0x73, 0x3a, 0x74, 0xa4, 0xdf,
+	0x7e, 0x01, 0x9b, 0xb3, 0x2d, 0xae, 0xb3, 0xb5, 0xa0, 0x28, 0xc9, 0x26, 0xbd, 0x62, 0xc5, 0x1c,
+	0xa9, 0x3e, 0xd5, 0x48, 0xfb, 0x7b, 0xb0, 0xe7, 0x75, 0xb3, 0x66, 0xb6, 0xa0, 0xec, 0x7a, 0x1e,
+	0x0e, 0x39, 0xaa, 0x3a, 0xca, 0xce, 0x74, 0x2d, 0x4e, 0x38, 0x42, 0x97, 0xd1, 0x50, 0x9f, 0xa3,
+	0x5e, 0xd9, 0x1f, 0xc2, 0x56, 0xaa, 0xad, 0x35, 0x9d, 0x68, 0x0a, 0xe1, 0x52, 0x64, 0x0b, 0x8e,
+	0x5e, 0xd9, 0x3f, 0xc0, 0xbd, 0x8c, 0x0e, 0xd6, 0x41, 0x9f, 0xc1, 0x0a, 0x9a, 0x0e, 0x5d, 0xe4,
+	0xa6, 0xb1, 0x51, 0x86, 0xdb, 0x49, 0x82, 0xed, 0xb6, 0x9c, 0xff, 0xd4, 0x00, 0x68, 0xee, 0x03,
+	0x28, 0x45, 0xca, 0xa5, 0x59, 0x57, 0x8d, 0x63, 0xd2, 0x0d, 0x3d, 0x41, 0xd8, 0x03, 0x58, 0x4f,
+	0x76, 0xfa, 0x2d, 0x0e, 0xfb, 0x7d, 0xb1, 0x69, 0x22, 0x5a, 0x6e, 0x5a, 0x66, 0x3e, 0x0d, 0xb0,
+	0xff, 0xcc, 0x41, 0x51, 0x35, 0x1b, 0xd9, 0x83, 0x52, 0x48, 0x7d, 0x9c, 0x93, 0xa0, 0x28, 0xfc,
+	0x6d, 0x9f, 0x34, 0x01, 0x18, 0x32, 0x16, 0xd0, 0x50, 0x80, 0xf3, 0xd9, 0xe0, 0x25, 0x0d, 0x69,
+	0xfb, 0x64, 0x17, 0x56, 0x98, 0xec, 0xfd, 0x33, 0xd7, 0xe3, 0xc1, 0x18, 0xe5, 0xd0, 0x95, 0x9d,
+	0x65, 0x65, 0x3c, 0x94, 0x36, 0xd2, 0x82, 0x45, 0xc6, 0x5d, 0x8e, 0x72, 0xee, 0xaa, 0xad, 0xed,
+	0xd9, 0x61, 0x38, 0x55, 0x74, 0xa7, 0x02, 0xe3, 0x28, 0x28, 0xd9, 0x81, 0x8a, 0xda, 0x14, 0x8f,
+	0x8e, 0x42, 0x5e, 0x5f, 0x94, 0xe7, 0x0d, 0xd2, 0x74, 0x24, 0x2c, 0xb2, 0x3c, 0x35, 0x76, 0xff,
+	0x6d, 0x79, 0x6a, 0x4e, 0x67, 0xca, 0x53, 0xc6, 0x6b, 0xcb, 0x53, 0xef, 0x77, 0xab, 0xf2, 0x2e,
+	0xa1, 0x3c, 0x99, 0x5f, 0xf2, 0x01, 0x54, 0x42, 0x0c, 0xba, 0xbd, 0x0e, 0x8d, 0xe6, 0xd4, 0x08,
+	0x13, 0x4c, 0xdb, 0x17, 0xf4, 0x11, 0x7a, 0x18, 0x8c, 0xd1, 0x3f, 0x73, 0x55, 0xaf, 0x14, 0x1c,
+	0x98, 0x98, 0x0e, 0x79, 0x62, 0x30, 0x0b, 0xc9, 0xc1, 0xb4, 0x7f, 0xcf, 0x43, 0x6d, 0x76, 0xa2,
+	0x6f, 0xd4, 0xa4, 0x07, 0xb0, 0x28, 0x1f, 0xf5, 0x06, 0x6f, 0x18, 0xc0, 0xd7, 0x6e, 0xa7, 0xaf,
+	0xef, 0x3a, 0x85, 0x31, 0xae, 0x81, 0x82, 0x79, 0x0d, 0x88, 0x6f, 0x18, 0xa3, 0xa3, 0xc8, 0x93,
+	0xc7, 0xba, 0x90, 0x9d, 0xb1, 0xac, 0x10, 0xe9, 0x82, 0x17, 0x53, 0x05, 0xff, 0x1f, 0xaa, 0x17,
+	0x71, 0x4d, 0x02, 0x53, 0x94, 0x98, 0x15, 0xc3, 0x7a, 0xc8, 0xc9, 0x03, 0x80, 0x08, 0x7f, 0x46,
+	0x4f, 0xdd, 0x14, 0x25, 0x75, 0x2c, 0xb1, 0xc5, 0xfe, 0x2b, 0x07, 0xcb, 0xe6, 0x75, 0x21, 0x5e,
+	0xd3, 0x1d, 0xf1, 0xde, 0xdc, 0x93, 0x29, 0x2b, 0x44, 0x5b, 0xde, 0x79, 0x18, 0xf2, 0x80, 0x4f,
+	0xbe, 0x5d, 0x7a, 0x45, 0x3e, 0x81, 0xca, 0x79, 0x10, 0x31, 0x7e, 0xa6, 0xf6, 0xad, 0x30, 0x6f,
+	0xdf, 0x40, 0x22, 0xd5, 0xa9, 0x7c, 0x0a, 0xcb, 0x0c, 0x3d, 0x1a, 0xfa, 0x3a, 0x70, 0x61, 0x5e,
+	0x60, 0x45, 0x41, 0x55, 0xe4, 0x8e, 0xf8, 0x56, 0x72, 0xf4, 0x78, 0x62, 0xc3, 0x26, 0xa6, 0x43,
+	0xbe, 0xdf, 0x05, 0x92, 0x9e, 0x4e, 0xb2, 0x01, 0xab, 0x09, 0x6b, 0xdb, 0xef, 0x63, 0xed, 0x0e,
+	0xd9, 0x86, 0x7a, 0xc2, 0x7c, 0xcc, 0xb8, 0xdb, 0xe9, 0x07, 0xac, 0x87, 0x7e, 0x2d, 0x97, 0xf2,
+	0xb6, 0x43, 0x8e, 0x51, 0x34, 0x12, 0xcd, 0x56, 0xcb, 0xef, 0xff, 0x96, 0x03, 0x92, 0x1e, 0x14,
+	0x91, 0x29, 0x61, 0x8d, 0x33, 0x25, 0xcc, 0xc9, 0x4c, 0xf7, 0x61, 0x2b, 0xe1, 0x3d, 0xa2, 0x61,
+	0x28, 0x8e, 0x2e, 0xec, 0xd6, 0xf2, 0xa9, 0x50, 0xf3, 0x35, 0x0a, 0xfb, 0x6f, 0xa1, 0x62, 0xa8,
+	0x13, 0xb2, 0x0e, 0x35, 0x63, 0x79, 0xfc, 0xce, 0xf5, 0x78, 0xed, 0x0e, 0xd9, 0x82, 0x35, 0xc3,
+	0x7a, 0x44, 0xc7, 0x18, 0x09, 0xee, 0x1c, 0xa9, 0xc3, 0xfa, 0xac, 0x03, 0xfd, 0x67, 0x97, 0xb5,
+	0x7c, 0xeb, 0x8f, 0x12, 0x54, 0xe5, 0x85, 0x20, 0xd5, 0xf4, 0xb9, 0xeb, 0x21, 0xf9, 0x18, 0x4a,
+	0x5a, 0xe9, 0x92, 0x7a, 0x7c, 0x59, 0x24, 0xc5, 0xaf, 0x65, 0x76, 0x91, 0x84, 0x9e, 0x00, 0xc4,
+	0x4a, 0x85, 0xdc, 0x4f, 0x04, 0x26, 0x35, 0xb2, 0xb5, 0x9d, 0xed, 0xd4, 0xdf, 0x1b, 0x45, 0xa4,
+	0x25, 0xcb, 0x0c, 0x51, 0x52, 0x57, 0x5b, 0xdb, 0xd9, 0xce, 0x98, 0x28, 0xd6, 0x2e, 0x26, 0x51,
+	0x4a, 0x8b, 0x5b, 0xdb, 0xd9, 0x4e, 0x4d, 0x74, 0x0a, 0xd5, 0xa4, 0x34, 0x21, 0x3b, 0x31, 0x3e,
+	0x53, 0x97, 0x5b, 0x8d, 0xab, 0x01, 0x9a, 0xf4, 0x3b, 0x58, 0xcb, 0x50, 0xee, 0xe4, 0x51, 0xa2,
+	0xa4, 0x2b, 0x74, 0xb8, 0x35, 0x47, 0x02, 0x91, 0x5f, 0xc0, 0xba, 0x5a, 0xfa, 0x90, 0x83, 0x38,
+	0xf2, 0x5a, 0xb9, 0x6f, 0x3d, 0xfe, 0x67, 0x60, 0x5d, 0xd1, 0x5b, 0xb8, 0x3b, 0xa3, 0x8c, 0x88,
+	0xb1, 0x0d, 0xd9, 0xff, 0x02, 0xd6, 0xc3, 0x39, 0x08, 0xcd, 0xfb, 0x23, 0xac, 0xa6, 0xe4, 0x13,
+	0xb1, 0x93, 0x1b, 0x9c, 0xf5, 0x77, 0x60, 0xed, 0xce, 0xc5, 0x68, 0xf6, 0x33, 0xa9, 0x90, 0x67,
+	0x14, 0x14, 0xd9, 0x4d, 0x1c, 0x43, 0xf6, 0x0f, 0x86, 0xf5, 0x68, 0x3e, 0x48, 0x27, 0x78, 0x09,
+	0xcb, 0xa6, 0xae, 0x22, 0xff, 0x33, 0xf4, 0x45, 0xfa, 0xcf, 0xc2, 0x7a, 0x70, 0x95, 0x5b, 0xd1,
+	0x75, 0x8a, 0xf2, 0x77, 0xf4, 0xa3, 0xbf, 0x07, 0x00, 0x80, 0x32, 0x6b, 0xa5, 0xff, 0x0e, 0x00,
+	0x00,
 }
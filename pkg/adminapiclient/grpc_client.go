@@ -45,3 +45,59 @@ func (client *AdminClient) GetNode(request *adminapi.GetNodeRequest) (*abusemesh
 	defer cancel()
 	return client.grpcClient.GetNode(ctx, request)
 }
+
+//TraceEvent requests the server to send back from which neighbors and when a event reached it
+func (client *AdminClient) TraceEvent(request *adminapi.TraceEventRequest) (*adminapi.TraceEventResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), client.unaryRequestTimeout)
+	defer cancel()
+	return client.grpcClient.TraceEvent(ctx, request)
+}
+
+//ListQuarantine requests the server to send back the events it refused because they are invalid
+func (client *AdminClient) ListQuarantine(request *adminapi.ListQuarantineRequest) (*adminapi.ListQuarantineResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), client.unaryRequestTimeout)
+	defer cancel()
+	return client.grpcClient.ListQuarantine(ctx, request)
+}
+
+//GetQuarantinedEvent requests the server to send back a single quarantined event
+func (client *AdminClient) GetQuarantinedEvent(request *adminapi.GetQuarantinedEventRequest) (*adminapi.QuarantinedEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), client.unaryRequestTimeout)
+	defer cancel()
+	return client.grpcClient.GetQuarantinedEvent(ctx, request)
+}
+
+//RevalidateQuarantinedEvent requests the server to validate a quarantined event again
+func (client *AdminClient) RevalidateQuarantinedEvent(request *adminapi.RevalidateQuarantinedEventRequest) (*adminapi.RevalidateQuarantinedEventResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), client.unaryRequestTimeout)
+	defer cancel()
+	return client.grpcClient.RevalidateQuarantinedEvent(ctx, request)
+}
+
+//PurgeQuarantine requests the server to remove events from the quarantine
+func (client *AdminClient) PurgeQuarantine(request *adminapi.PurgeQuarantineRequest) (*adminapi.PurgeQuarantineResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), client.unaryRequestTimeout)
+	defer cancel()
+	return client.grpcClient.PurgeQuarantine(ctx, request)
+}
+
+//ListEquivocations requests the server to send back the proofs of nodes which signed conflicting events
+func (client *AdminClient) ListEquivocations(request *adminapi.ListEquivocationsRequest) (*adminapi.ListEquivocationsResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), client.unaryRequestTimeout)
+	defer cancel()
+	return client.grpcClient.ListEquivocations(ctx, request)
+}
+
+//GetReportsByPrefix requests the server to send back the reports about a IP address or network
+func (client *AdminClient) GetReportsByPrefix(request *adminapi.GetReportsByPrefixRequest) (*adminapi.GetReportsByPrefixResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), client.unaryRequestTimeout)
+	defer cancel()
+	return client.grpcClient.GetReportsByPrefix(ctx, request)
+}
+
+//CreateReport requests the server to sign a new report and submit it to the event stream
+func (client *AdminClient) CreateReport(request *adminapi.CreateReportRequest) (*adminapi.CreateReportResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), client.unaryRequestTimeout)
+	defer cancel()
+	return client.grpcClient.CreateReport(ctx, request)
+}
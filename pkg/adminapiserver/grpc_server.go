@@ -4,15 +4,20 @@ import (
 	"bytes"
 	"context"
 	"net"
+	"time"
 
 	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
 	"github.com/abuse-mesh/abuse-mesh-go/internal/config"
 	"github.com/abuse-mesh/abuse-mesh-go/internal/entities"
 	"github.com/abuse-mesh/abuse-mesh-go/internal/pgp"
+	"github.com/abuse-mesh/abuse-mesh-go/internal/utils/conv"
 	"github.com/abuse-mesh/abuse-mesh-go/pkg/adminapi"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type abuseMeshAdminApi struct {
@@ -20,6 +25,7 @@ type abuseMeshAdminApi struct {
 	pgpProvider pgp.PGPProvider
 	eventStream entities.EventStream
 	tables      *entities.TableSet
+	author      *entities.EventAuthor
 }
 
 // Returns the Node data of the current node
@@ -93,12 +99,241 @@ func (api *abuseMeshAdminApi) GetServers(context.Context, *adminapi.GetServersRe
 	panic("not implemented")
 }
 
+// Returns from which neighbors and when a event reached this node
+func (api *abuseMeshAdminApi) TraceEvent(ctx context.Context, req *adminapi.TraceEventRequest) (*adminapi.TraceEventResponse, error) {
+	eventID, err := conv.AuuidToGuuid(req.GetEventId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Event ID invalid")
+	}
+
+	provenance, err := api.eventStream.GetProvenance(eventID)
+	if err != nil {
+		log.WithError(err).Error("Error while reading event provenance")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	response := &adminapi.TraceEventResponse{
+		EventId: req.GetEventId(),
+	}
+
+	for _, hop := range provenance.Hops {
+		protobufHop := &adminapi.EventHop{
+			ReceivedAt: hop.ReceivedAt.UnixNano(),
+			Accepted:   hop.Accepted,
+		}
+
+		if hop.Neighbor != uuid.Nil {
+			protobufHop.NeighborId = &abusemesh.UUID{
+				Uuid: hop.Neighbor.String(),
+			}
+		}
+
+		response.Hops = append(response.Hops, protobufHop)
+	}
+
+	return response, nil
+}
+
+//quarantinedEventToProtobuf converts a quarantined event to its admin API message
+func quarantinedEventToProtobuf(quarantined entities.QuarantinedEvent) *adminapi.QuarantinedEvent {
+	message := &adminapi.QuarantinedEvent{
+		ReceivedAt:    quarantined.ReceivedAt.UnixNano(),
+		QuarantinedAt: quarantined.QuarantinedAt.UnixNano(),
+		Rejections:    quarantined.Rejections,
+	}
+
+	if quarantined.EventID != uuid.Nil {
+		message.EventId = &abusemesh.UUID{
+			Uuid: quarantined.EventID.String(),
+		}
+	}
+
+	if quarantined.Source != uuid.Nil {
+		message.SourceId = &abusemesh.UUID{
+			Uuid: quarantined.Source.String(),
+		}
+	}
+
+	if quarantined.Reason != nil {
+		message.Reason = quarantined.Reason.Error()
+	}
+
+	if genericEvent, ok := quarantined.Event.(*entities.GenericEvent); ok {
+		message.Event = &genericEvent.TableEvent
+	}
+
+	return message
+}
+
+// Returns the events which were refused because they are invalid
+func (api *abuseMeshAdminApi) ListQuarantine(context.Context, *adminapi.ListQuarantineRequest) (*adminapi.ListQuarantineResponse, error) {
+	response := &adminapi.ListQuarantineResponse{}
+
+	for _, quarantined := range api.eventStream.GetQuarantinedEvents() {
+		response.Events = append(response.Events, quarantinedEventToProtobuf(quarantined))
+	}
+
+	return response, nil
+}
+
+// Returns a single quarantined event
+func (api *abuseMeshAdminApi) GetQuarantinedEvent(ctx context.Context, req *adminapi.GetQuarantinedEventRequest) (*adminapi.QuarantinedEvent, error) {
+	eventID, err := conv.AuuidToGuuid(req.GetEventId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Event ID invalid")
+	}
+
+	quarantined, found := api.eventStream.GetQuarantinedEvent(eventID)
+	if !found {
+		return nil, status.Error(codes.NotFound, entities.ErrNotQuarantined.Error())
+	}
+
+	return quarantinedEventToProtobuf(quarantined), nil
+}
+
+// Validates a quarantined event again, if it is valid now it is written to the event stream
+func (api *abuseMeshAdminApi) RevalidateQuarantinedEvent(ctx context.Context, req *adminapi.RevalidateQuarantinedEventRequest) (*adminapi.RevalidateQuarantinedEventResponse, error) {
+	eventID, err := conv.AuuidToGuuid(req.GetEventId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Event ID invalid")
+	}
+
+	accepted, reason := api.eventStream.RevalidateQuarantinedEvent(eventID)
+	if reason == entities.ErrNotQuarantined {
+		return nil, status.Error(codes.NotFound, reason.Error())
+	}
+
+	response := &adminapi.RevalidateQuarantinedEventResponse{
+		Accepted: accepted,
+	}
+
+	if reason != nil {
+		response.Reason = reason.Error()
+	}
+
+	return response, nil
+}
+
+// Removes events from the quarantine
+func (api *abuseMeshAdminApi) PurgeQuarantine(ctx context.Context, req *adminapi.PurgeQuarantineRequest) (*adminapi.PurgeQuarantineResponse, error) {
+	eventIDs := make([]uuid.UUID, 0, len(req.GetEventIds()))
+	for _, protobufID := range req.GetEventIds() {
+		eventID, err := conv.AuuidToGuuid(protobufID)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "Event ID invalid")
+		}
+
+		eventIDs = append(eventIDs, eventID)
+	}
+
+	return &adminapi.PurgeQuarantineResponse{
+		Purged: uint64(api.eventStream.PurgeQuarantine(eventIDs...)),
+	}, nil
+}
+
+// Returns the proofs of nodes which signed conflicting events
+func (api *abuseMeshAdminApi) ListEquivocations(context.Context, *adminapi.ListEquivocationsRequest) (*adminapi.ListEquivocationsResponse, error) {
+	response := &adminapi.ListEquivocationsResponse{}
+
+	for _, proof := range api.eventStream.GetEquivocations() {
+		response.Equivocations = append(response.Equivocations, &adminapi.Equivocation{
+			AuthorId: &abusemesh.UUID{
+				Uuid: proof.Author.String(),
+			},
+			Entity:      proof.Entity,
+			FirstEvent:  &proof.First.TableEvent,
+			SecondEvent: &proof.Second.TableEvent,
+			DetectedAt:  proof.DetectedAt.UnixNano(),
+		})
+	}
+
+	return response, nil
+}
+
+//prefixMatches maps the prefix match of the admin API to the one of the prefix index
+var prefixMatches = map[adminapi.PrefixMatch]entities.PrefixMatch{
+	adminapi.PrefixMatch_PrefixMatchExact:     entities.PrefixMatchExact,
+	adminapi.PrefixMatch_PrefixMatchCovering:  entities.PrefixMatchCovering,
+	adminapi.PrefixMatch_PrefixMatchCoveredBy: entities.PrefixMatchCoveredBy,
+}
+
+// Returns the reports about a IP address or network
+func (api *abuseMeshAdminApi) GetReportsByPrefix(ctx context.Context, req *adminapi.GetReportsByPrefixRequest) (*adminapi.GetReportsByPrefixResponse, error) {
+	prefix, err := entities.ParsePrefix(req.GetPrefix())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	match, found := prefixMatches[req.GetMatch()]
+	if !found {
+		return nil, status.Error(codes.InvalidArgument, "Unknown prefix match")
+	}
+
+	responseChan := make(chan []entities.Report, 1)
+	api.tables.Channel <- &entities.GetReportsByPrefixRequest{
+		ResponseChan: responseChan,
+		Prefix:       prefix,
+		Match:        match,
+	}
+
+	response := &adminapi.GetReportsByPrefixResponse{}
+	for _, report := range <-responseChan {
+		response.Reports = append(response.Reports, report.ToProtobuf())
+	}
+
+	return response, nil
+}
+
+// Signs a new report of this node and submits it to the event stream
+func (api *abuseMeshAdminApi) CreateReport(ctx context.Context, req *adminapi.CreateReportRequest) (*adminapi.CreateReportResponse, error) {
+	prefix, err := entities.ParsePrefix(req.GetPrefix())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if req.GetTtl() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "TTL can't be negative")
+	}
+
+	reporter, err := uuid.Parse(api.config.Node.UUID)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, "UUID of this node is invalid")
+	}
+
+	report := entities.Report{
+		UUID:        uuid.New(),
+		Reporter:    reporter,
+		Prefix:      prefix,
+		Category:    req.GetCategory(),
+		Description: req.GetDescription(),
+		TTL:         time.Duration(req.GetTtl()) * time.Second,
+	}
+
+	event, err := api.author.ReportEvent(abusemesh.TableEventType_TABLE_UPDATE_NEW, report.ToProtobuf())
+	if err != nil {
+		log.WithError(err).Error("Error while signing report event")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	//The event is validated by the event stream like any other event, if it is refused it ends up in the quarantine
+	err = api.author.Submit(ctx, event)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	return &adminapi.CreateReportResponse{
+		EventId: event.GetEventId(),
+		Report:  event.GetReport(),
+	}, nil
+}
+
 //NewAbuseMeshServer creates a new instance of a AbuseMeshServer
 func NewAbuseMeshAdminAPI(
 	config *config.AbuseMeshConfig,
 	pgpProvider pgp.PGPProvider,
 	tableSet *entities.TableSet,
 	eventStream entities.EventStream,
+	author *entities.EventAuthor,
 ) *grpc.Server {
 
 	//Configure the Admin API GRPC server
@@ -113,6 +348,7 @@ func NewAbuseMeshAdminAPI(
 		pgpProvider: pgpProvider,
 		tables:      tableSet,
 		eventStream: eventStream,
+		author:      author,
 	}
 
 	//Register the AbuseMeshServer at the GRPC server
@@ -9,6 +9,9 @@ import (
 	"google.golang.org/grpc"
 )
 
+//DefaultPort is the TCP port on which nodes serve the AbuseMesh protocol unless configured otherwise
+const DefaultPort = 180
+
 type AbuseMeshClient struct {
 	grpcClient     abusemesh.AbuseMeshClient
 	grpcConnection *grpc.ClientConn
@@ -24,11 +27,21 @@ func NewAbuseMeshClient() *AbuseMeshClient {
 	opts = append(opts, grpc.WithInsecure())
 
 	//TODO make url a parameter
-	conn, err := grpc.Dial("localhost:1180", opts...)
+	client, err := DialAbuseMeshClient("localhost:1180", opts...)
 	if err != nil {
 		log.Fatalf("fail to dial: %v", err)
 	}
-	//defer conn.Close()
+
+	return client
+}
+
+//DialAbuseMeshClient creates a client for the node listening on the given address
+//Unlike NewAbuseMeshClient it returns dial errors so it can be used for connections to arbitrary nodes
+func DialAbuseMeshClient(address string, opts ...grpc.DialOption) (*AbuseMeshClient, error) {
+	conn, err := grpc.Dial(address, opts...)
+	if err != nil {
+		return nil, err
+	}
 
 	client := abusemesh.NewAbuseMeshClient(conn)
 
@@ -36,7 +49,7 @@ func NewAbuseMeshClient() *AbuseMeshClient {
 		unaryRequestTimeout: 10 * time.Second,
 		grpcClient:          client,
 		grpcConnection:      conn,
-	}
+	}, nil
 }
 
 func (client *AbuseMeshClient) Close() error {
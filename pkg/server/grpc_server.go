@@ -4,14 +4,20 @@ import (
 	"bytes"
 	"context"
 	"net"
+	"strconv"
 
 	"github.com/abuse-mesh/abuse-mesh-go-stubs/abusemesh"
 	"github.com/abuse-mesh/abuse-mesh-go/internal/config"
 	"github.com/abuse-mesh/abuse-mesh-go/internal/entities"
 	"github.com/abuse-mesh/abuse-mesh-go/internal/pgp"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 type abuseMeshServer struct {
@@ -58,7 +64,7 @@ func (server abuseMeshServer) GetNode(context.Context, *abusemesh.GetNodeRequest
 
 	var buf bytes.Buffer
 
-	err := server.pgpProvider.GetPublicKey().Serialize(&buf)
+	err := server.pgpProvider.GetEntity().Serialize(&buf)
 	if err != nil {
 		log.WithError(err).Error("Error while serializing public key")
 		return nil, errors.WithStack(err)
@@ -95,46 +101,171 @@ func (abuseMeshServer) NegotiateNeighborship(context.Context, *abusemesh.Negotia
 type eventStreamObserver struct {
 	//The closure to be called
 	eventUpdate func(entities.Event)
+
+	//The closure to be called when the event stream disconnects the observer
+	disconnected func(error)
 }
 
 func (observer *eventStreamObserver) EventUpdate(event entities.Event) {
 	observer.eventUpdate(event)
 }
 
+func (observer *eventStreamObserver) Disconnected(reason error) {
+	observer.disconnected(reason)
+}
+
+//clientNode returns the node which is connected to us, it is identified by the IP address it connects from
+//Returns false if no node or multiple nodes claim the address
+func (server abuseMeshServer) clientNode(ctx context.Context) (uuid.UUID, bool) {
+	clientPeer, ok := peer.FromContext(ctx)
+	if !ok {
+		return uuid.UUID{}, false
+	}
+
+	host, _, err := net.SplitHostPort(clientPeer.Addr.String())
+	if err != nil {
+		return uuid.UUID{}, false
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return uuid.UUID{}, false
+	}
+
+	responseChan := make(chan []entities.Node, 1)
+	server.tables.Channel <- &entities.GetNodesByIPRequest{
+		ResponseChan: responseChan,
+		IPAddress:    ip,
+	}
+
+	nodes := <-responseChan
+	if len(nodes) != 1 {
+		return uuid.UUID{}, false
+	}
+
+	return nodes[0].UUID, true
+}
+
 // Opens a stream on which all table events of a node are published
+// The stream starts at the offset in the request, so a client which reconnects only receives the events it missed
+// If the offset has been compacted the client bootstraps from the snapshot. A snapshot contains less events than the offset
+// it covers, so the response headers tell the client the offset after the snapshot and how many snapshot events precede it
+// Events which we received from the client itself are not sent back to it, a marker with only the event id is sent in their place
+// so the client can count every offset
 func (server abuseMeshServer) TableEventStream(req *abusemesh.TableEventStreamRequest, stream abusemesh.AbuseMesh_TableEventStreamServer) error {
 	ctx := stream.Context()
 
-	eventChan := make(chan entities.Event, 1000) //NOTE the buffer size was chosen arbitrarily and may need to be tweaked
+	clientID, clientKnown := server.clientNode(ctx)
+
+	//The event stream queues events for every observer, so no buffer is needed here
+	eventChan := make(chan entities.Event)
+	disconnectChan := make(chan error, 1)
 
 	//Create a observer
 	observer := eventStreamObserver{
 		//On every event update call this function
 		eventUpdate: func(event entities.Event) {
-			eventChan <- event
+			select {
+			case eventChan <- event:
+			case <-ctx.Done():
+			}
+		},
+		//If we can't keep up the stream is closed, the client will resume from the last offset it received
+		disconnected: func(reason error) {
+			disconnectChan <- reason
 		},
 	}
 
-	//Attack to the event stream
-	server.eventStream.Attach(&observer)
+	//Attach to the event stream, all events from the requested offset which we already have are returned
+	//and all events after them will be sent to the observer
+	backlog, err := server.eventStream.AttachFrom(req.GetOffset(), &observer)
+	if err != nil {
+		if errors.Cause(err) == entities.ErrOffsetOutOfRange {
+			return status.Error(codes.OutOfRange, err.Error())
+		}
+
+		return err
+	}
 	//Make sure we always detach
 	defer server.eventStream.Detach(&observer)
 
+	//Tell the client where the events after the snapshot start, the headers are sent before the first event
+	err = stream.SendHeader(metadata.Pairs(
+		EventOffsetHeader, strconv.FormatUint(backlog.Offset, 10),
+		SnapshotEventsHeader, strconv.Itoa(len(backlog.Snapshot)),
+	))
+	if err != nil {
+		log.WithError(err).Error("Error while sending table event stream headers")
+		return err
+	}
+
+	send := func(event entities.Event) error {
+		//Check if we are dealing with a generic event
+		genericEvent, ok := event.(*entities.GenericEvent)
+		if !ok {
+			log.Errorf("Unknown event type '%T'", event)
+			return status.Error(codes.Internal, "Unknown event type")
+		}
+
+		err := stream.Send(&genericEvent.TableEvent)
+		if err != nil {
+			log.WithError(err).Error("Error while sending table event")
+		}
+
+		return err
+	}
+
+	sendEvent := func(event entities.Event) error {
+		//Don't advertise the event back to the neighbor we received it from
+		if clientKnown {
+			provenance, err := server.eventStream.GetProvenance(event.GetID())
+			if err != nil {
+				log.WithError(err).Warn("Unable to check provenance of event, sending it anyway")
+			} else if provenance.ReceivedFrom(clientID) {
+				err := stream.Send(suppressedEvent(event))
+				if err != nil {
+					log.WithError(err).Error("Error while sending suppressed table event")
+				}
+
+				return err
+			}
+		}
+
+		return send(event)
+	}
+
+	//The client counts the snapshot events to know when the events after the snapshot start, so none are suppressed
+	for _, event := range backlog.Snapshot {
+		err = send(event)
+		if err != nil {
+			return err
+		}
+	}
+
+	//Then send the events the client has missed
+	for _, event := range backlog.Events {
+		err = sendEvent(event)
+		if err != nil {
+			return err
+		}
+	}
+
 	//Loop forever
 	for {
 		select {
 		//Get a update from the event stream
 		case event := <-eventChan:
-			//Check if we are dealing with a generic event
-			if genericEvent, ok := event.(*entities.GenericEvent); ok {
-				err := stream.Send(&genericEvent.TableEvent)
-				if err != nil {
-					log.WithError(err).Error("Error while sending table event")
-				}
-			} else {
-				log.Error("Unknown event type")
+			//Every event has to be sent or the client will miss it, so on error we close the stream
+			//and the client will resume from the last offset it received
+			err = sendEvent(event)
+			if err != nil {
+				return err
 			}
 
+		//The event stream has disconnected us because the client is too slow
+		case reason := <-disconnectChan:
+			return status.Error(codes.ResourceExhausted, reason.Error())
+
 		//Get a stop signal from the client
 		case <-ctx.Done():
 			log.Info("Table event stream with '' was closed by client")
@@ -143,6 +274,19 @@ func (server abuseMeshServer) TableEventStream(req *abusemesh.TableEventStreamRe
 	}
 }
 
+//suppressedEvent returns the marker which is sent in place of a event the client already has
+//The marker only holds the event id, it has no entity and no signature
+func suppressedEvent(event entities.Event) *abusemesh.TableEvent {
+	return &abusemesh.TableEvent{
+		EventId: &abusemesh.UUID{Uuid: event.GetID().String()},
+	}
+}
+
+//isSuppressedEvent returns true if the event is a marker of a event which was not sent because we already have it
+func isSuppressedEvent(event *abusemesh.TableEvent) bool {
+	return event.GetTableEntity() == nil && len(event.GetSignature()) == 0
+}
+
 //NewAbuseMeshServer creates a new instance of a AbuseMeshServer
 func NewAbuseMeshServer(
 	config *config.AbuseMeshConfig,
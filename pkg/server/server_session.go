@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	stdErrors "errors"
+	"strconv"
 	"sync"
 	"time"
 
@@ -13,6 +14,16 @@ import (
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	//EventOffsetHeader is the header of a table event stream which holds the offset of the first event after the snapshot
+	EventOffsetHeader = "abusemesh-offset"
+
+	//SnapshotEventsHeader is the header of a table event stream which holds the amount of snapshot events sent before the offset
+	SnapshotEventsHeader = "abusemesh-snapshot-events"
 )
 
 var (
@@ -50,6 +61,10 @@ type serverSession struct {
 	eventStreamWriteChan chan<- entities.Event
 	//The event counter
 	eventCounter uint64
+	//The amount of snapshot events which we still have to receive before the events after the snapshot start
+	snapshotEvents uint64
+	//The offset of the first event after the snapshot, the event counter is set to it once all snapshot events are received
+	snapshotOffset uint64
 	//The context of the session
 	context context.Context
 }
@@ -127,6 +142,9 @@ func (session *serverSession) Run() error {
 				Offset:    session.eventCounter,
 				SessionId: &abusemesh.UUID{Uuid: session.id.String()},
 			})
+			if err == nil {
+				err = session.readStreamHeader()
+			}
 
 			if err != nil {
 				eventStreamClientCancel()
@@ -170,14 +188,39 @@ func (session *serverSession) Run() error {
 				//Maybe change it to a warning and error if we can't recover in the interupted state?
 				log.WithError(err).Error("Event stream was closed")
 
+				//The server doesn't have the events up to our offset, for example because it lost its in memory events.
+				//Resume from the start, events we already have will be refused as duplicates
+				if grpcStatus, ok := status.FromError(err); ok && grpcStatus.Code() == codes.OutOfRange {
+					log.WithField("offset", session.eventCounter).Warn("Offset out of range, resyncing event stream from the start")
+					session.eventCounter = 0
+				}
+
 				session.state = serverStateInterupted
 				continue
 			}
 
-			//TODO add missing event check
+			//The stream records that the event came from this server, so it isn't advertised back to it
+			//A marker of a event we sent to the server ourselves only has to be counted
+			if !isSuppressedEvent(event) {
+				session.eventStreamWriteChan <- &entities.ReceivedEvent{
+					Event:      &entities.GenericEvent{TableEvent: *event},
+					From:       session.server.UUID,
+					ReceivedAt: time.Now(),
+				}
+			}
 
-			session.eventStreamWriteChan <- &entities.GenericEvent{TableEvent: *event}
+			//Until all snapshot events are received we have to resume from the offset we requested,
+			//after the snapshot the counter continues from the offset the snapshot covers
+			if session.snapshotEvents > 0 {
+				session.snapshotEvents--
+				if session.snapshotEvents == 0 {
+					session.eventCounter = session.snapshotOffset
+				}
+				continue
+			}
 
+			//The server sends events in offset order starting at the offset we requested,
+			//so the counter is the offset from which we need to resume
 			session.eventCounter++
 
 		case serverStateInterupted:
@@ -186,6 +229,9 @@ func (session *serverSession) Run() error {
 				Offset:    session.eventCounter,
 				SessionId: &abusemesh.UUID{Uuid: session.id.String()},
 			})
+			if err == nil {
+				err = session.readStreamHeader()
+			}
 
 			if err != nil {
 				eventStreamClientCancel()
@@ -212,10 +258,55 @@ func (session *serverSession) Run() error {
 				time.Sleep(1 * time.Second)
 				continue
 			}
+
+			//reset the failed reconnect counter
+			failedReconnectAttempts = 0
+			session.state = serverStateEstablished
 		}
 	}
 }
 
+//readStreamHeader reads the headers of a newly opened event stream
+//If the offset we requested has been compacted the server first sends the events of a snapshot, the headers
+//tell us how many and at which offset the events after the snapshot start. Servers without these headers send no snapshot count
+//and we keep counting from the offset we requested
+func (session *serverSession) readStreamHeader() error {
+	header, err := session.eventStreamClient.Header()
+	if err != nil {
+		return errors.Wrap(err, "Error while reading event stream headers")
+	}
+
+	session.snapshotEvents = 0
+
+	offsets := header.Get(EventOffsetHeader)
+	if len(offsets) == 0 {
+		return nil
+	}
+
+	offset, err := strconv.ParseUint(offsets[0], 10, 64)
+	if err != nil {
+		return errors.Wrap(err, "Protocol error: invalid event stream offset")
+	}
+
+	var snapshotEvents uint64
+	if counts := header.Get(SnapshotEventsHeader); len(counts) > 0 {
+		snapshotEvents, err = strconv.ParseUint(counts[0], 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "Protocol error: invalid snapshot event count")
+		}
+	}
+
+	if snapshotEvents == 0 {
+		session.eventCounter = offset
+		return nil
+	}
+
+	session.snapshotEvents = snapshotEvents
+	session.snapshotOffset = offset
+
+	return nil
+}
+
 type serverSessionStorage struct {
 	sessions map[uuid.UUID]*serverSession
 	lock     sync.RWMutex